@@ -12,10 +12,13 @@ import (
 
 	"github.com/Athla/vr-software-challenge/config"
 	"github.com/Athla/vr-software-challenge/internal/api/server"
-	"github.com/Athla/vr-software-challenge/internal/domain/models"
+	domain_errors "github.com/Athla/vr-software-challenge/internal/domain/errors"
 	"github.com/Athla/vr-software-challenge/internal/infrastructure/database"
 	"github.com/Athla/vr-software-challenge/internal/infrastructure/messagery"
+	"github.com/Athla/vr-software-challenge/internal/infrastructure/treasury"
+	"github.com/Athla/vr-software-challenge/internal/processing"
 	"github.com/Athla/vr-software-challenge/internal/repository"
+	"github.com/Athla/vr-software-challenge/internal/service"
 	"github.com/Athla/vr-software-challenge/migrations"
 	"github.com/charmbracelet/log"
 	_ "github.com/joho/godotenv/autoload"
@@ -56,27 +59,109 @@ func main() {
 
 	txRepo := repository.NewTransactionRepository(db)
 
-	producer, err := messagery.NewProducer(cfg.Kafka.Brokers, cfg.Kafka.Topic)
+	var broker messagery.Broker = messagery.NewKafkaBroker(cfg.Kafka.Brokers, messagery.WithKafkaConfig(cfg.Kafka))
+	if cfg.Kafka.Broker == messagery.BrokerMemory {
+		log.Warn("Using the in-memory broker: messages are lost on restart")
+		broker = messagery.NewMemoryBroker(messagery.DefaultMemoryPartitions)
+	}
+
+	codec, err := messagery.NewCodecFromConfig(repository.NewSchemaRepository(db), cfg.Kafka)
 	if err != nil {
-		log.Fatalf("Unable to create Kafka producer: %v", err)
+		log.Fatalf("Unable to create message codec: %v", err)
+	}
+	if err := codec.RegisterSchemas(ctx); err != nil {
+		log.Fatalf("Unable to register event schemas: %v", err)
 	}
 
-	consumerHandler := func(ctx context.Context, msg *messagery.TransactionMessage) error {
-		return txRepo.UpdateStatus(ctx, msg.ID, models.StatusCompleted)
+	producer, err := broker.NewProducer(cfg.Kafka.Topic)
+	if err != nil {
+		log.Fatalf("Unable to create Kafka producer: %v", err)
 	}
+	producer = messagery.NewSerializingProducer(producer, cfg.Kafka.Topic, codec)
 
-	consumer, err := messagery.NewConsumer(
-		cfg.Kafka.Brokers,
-		cfg.Kafka.GroupID,
-		cfg.Kafka.Topic,
-		consumerHandler,
-	)
+	subscriber, err := broker.NewSubscriber(cfg.Kafka.GroupID)
 	if err != nil {
 		log.Fatalf("Unable to create Kafka consumer: %v", err)
 	}
 
+	inspector, err := broker.NewInspector(cfg.Kafka.GroupID)
+	if err != nil {
+		log.Fatalf("Unable to create Kafka inspector: %v", err)
+	}
+	health := messagery.NewHealthCheck(inspector, cfg.Kafka.Topic, cfg.Kafka.HealthMaxLag)
+
+	// Missing transactions and status conflicts will not resolve themselves, so
+	// they skip the retry topics; anything else (e.g. a database hiccup) is retried.
+	permanent := func(err error) error {
+		if errors.Is(err, domain_errors.ErrTransactionNotFound) ||
+			errors.Is(err, domain_errors.ErrInvalidTransition) ||
+			errors.Is(err, domain_errors.ErrConcurrentModification) {
+			return messagery.Permanent(err)
+		}
+		return err
+	}
+
+	var rules []processing.Rule
+	if cfg.Processing.MaxAmountUSD.IsPositive() {
+		rules = append(rules, processing.MaxAmountRule(cfg.Processing.MaxAmountUSD))
+	}
+	if cfg.Processing.MaxAgeDays > 0 {
+		rules = append(rules, processing.MaxAgeRule(time.Duration(cfg.Processing.MaxAgeDays)*24*time.Hour))
+	}
+
+	pipeline := processing.NewPipeline(txRepo,
+		processing.NewValidationStep(),
+		processing.NewEnrichmentStep(),
+		processing.NewRulesStep(rules...),
+		processing.NewPersistenceStep(txRepo),
+	)
+
+	dispatcher := messagery.NewDispatcher()
+	dispatcher.Register(messagery.EventTransactionCreated, messagery.HandleData(
+		func(ctx context.Context, _ *messagery.Event, created *messagery.TransactionCreated) error {
+			if messagery.IsReplayContext(ctx) {
+				return permanent(pipeline.Reprocess(ctx, created.ID))
+			}
+			return permanent(pipeline.Process(ctx, created.ID))
+		},
+	))
+
+	retryPolicy := messagery.RetryPolicy{
+		Tiers:       messagery.DefaultRetryTiers(cfg.Kafka.Topic),
+		MaxAttempts: cfg.Kafka.RetryMaxAttempts,
+	}
+
+	// The consumer subscribes to the retry topics before anything is retried,
+	// so every topic it reads or writes is created upfront.
+	topicsCtx, topicsCancel := context.WithTimeout(ctx, 30*time.Second)
+	topics := append([]string{cfg.Kafka.Topic, cfg.Kafka.DLQTopic}, retryPolicy.Topics()...)
+	if err := broker.CreateTopics(topicsCtx, topics...); err != nil {
+		log.Errorf("Unable to create Kafka topics: %v", err)
+	}
+	topicsCancel()
+
+	consumer := messagery.NewConsumer(
+		subscriber,
+		cfg.Kafka.Topic,
+		dispatcher,
+		messagery.WithWorkers(cfg.Kafka.ConsumerWorkers),
+		messagery.WithCodec(codec),
+		messagery.WithDeadLetterTopic(producer, cfg.Kafka.DLQTopic),
+		messagery.WithRetry(producer, retryPolicy),
+	)
+
+	relay := messagery.NewOutboxRelay(repository.NewOutboxRepository(db), producer)
+
 	var wg sync.WaitGroup
 
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if err := relay.Start(ctx); err != nil && !errors.Is(err, context.Canceled) {
+			log.Errorf("Outbox relay error: %v", err)
+		}
+	}()
+
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
@@ -85,7 +170,59 @@ func main() {
 		}
 	}()
 
-	server := server.NewServer(cfg, db, producer)
+	lagReporter := messagery.NewLagReporter(inspector, 15*time.Second, append([]string{cfg.Kafka.Topic}, retryPolicy.Topics()...)...)
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		lagReporter.Start(ctx)
+	}()
+
+	idempotencyRepo := repository.NewIdempotencyRepository(db)
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(time.Hour)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if deleted, err := idempotencyRepo.DeleteExpired(ctx); err != nil {
+					log.Errorf("Unable to delete expired idempotency keys: %v", err)
+				} else if deleted > 0 {
+					log.Infof("Deleted %d expired idempotency keys", deleted)
+				}
+			}
+		}
+	}()
+
+	if cfg.Treasury.SyncInterval > 0 {
+		rateSyncer := service.NewRateSyncer(
+			treasury.NewClient(treasury.WithMaxRecords(cfg.Treasury.MaxRecords)),
+			repository.NewRateRepository(db),
+		)
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := rateSyncer.Start(ctx, cfg.Treasury.SyncInterval); err != nil && !errors.Is(err, context.Canceled) {
+				log.Errorf("Exchange rate sync error: %v", err)
+			}
+		}()
+	}
+
+	admin := server.NewAdminServer(cfg)
+
+	go func() {
+		if err := admin.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("Admin server error: %v", err)
+		}
+	}()
+
+	server := server.NewServer(cfg, db, producer, health)
 
 	serverShutdown := make(chan struct{})
 
@@ -111,13 +248,21 @@ func main() {
 		log.Errorf("Server forced to shutdown: %v", err)
 	}
 
+	if err := admin.Shutdown(shutdownCtx); err != nil {
+		log.Errorf("Admin server forced to shutdown: %v", err)
+	}
+
+	wg.Wait()
+
 	if err := consumer.Close(); err != nil {
 		log.Errorf("Error closing consumer: %v", err)
 	}
 
-	producer.Close()
+	if err := inspector.Close(); err != nil {
+		log.Errorf("Error closing Kafka inspector: %v", err)
+	}
 
-	wg.Wait()
+	producer.Close()
 
 	<-serverShutdown
 
@@ -0,0 +1,251 @@
+// Command dlq lists, inspects and re-drives messages parked on the Kafka
+// dead-letter topic.
+//
+// Usage:
+//
+//	dlq list [-limit N]
+//	dlq inspect -partition P -offset O
+//	dlq redrive [-partition P -offset O] [-dry-run]
+//
+// Without -partition/-offset, redrive republishes every dead letter not yet
+// re-driven and records its progress under the "<KAFKA_GROUP_ID>.dlq" group,
+// so running it twice does not duplicate messages.
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Athla/vr-software-challenge/config"
+	"github.com/Athla/vr-software-challenge/internal/infrastructure/database"
+	"github.com/Athla/vr-software-challenge/internal/infrastructure/messagery"
+	"github.com/Athla/vr-software-challenge/internal/repository"
+	"github.com/charmbracelet/log"
+	_ "github.com/joho/godotenv/autoload"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Unable to load config: %v", err)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	reader, err := messagery.NewDeadLetterReader(cfg.Kafka.Brokers, cfg.Kafka.GroupID+".dlq", cfg.Kafka.DLQTopic, messagery.WithKafkaConfig(cfg.Kafka))
+	if err != nil {
+		log.Fatalf("Unable to create dead-letter reader: %v", err)
+	}
+	defer reader.Close()
+
+	switch os.Args[1] {
+	case "list":
+		err = list(ctx, reader, os.Args[2:])
+	case "inspect":
+		err = inspect(ctx, cfg, reader, os.Args[2:])
+	case "redrive":
+		err = redrive(ctx, cfg, reader, os.Args[2:])
+	default:
+		usage()
+	}
+	if err != nil {
+		log.Fatalf("%s failed: %v", os.Args[1], err)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: dlq list [-limit N] | inspect -partition P -offset O | redrive [-partition P -offset O] [-dry-run]")
+	os.Exit(2)
+}
+
+func list(ctx context.Context, reader *messagery.DeadLetterReader, args []string) error {
+	fs := flag.NewFlagSet("list", flag.ExitOnError)
+	limit := fs.Int("limit", 100, "maximum number of dead letters to show")
+	fs.Parse(args)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "PARTITION\tOFFSET\tKEY\tORIGIN\tATTEMPTS\tFAILED AT\tERROR")
+
+	shown := 0
+	err := reader.Scan(ctx, false, func(d *messagery.DeadLetter) bool {
+		fmt.Fprintf(w, "%d\t%d\t%s\t%s[%d]@%d\t%d\t%s\t%s\n",
+			d.Partition, d.Offset, d.Key,
+			d.OriginalTopic, d.OriginalPartition, d.OriginalOffset,
+			d.Attempts, d.FailedAt.Format(time.RFC3339), d.Error)
+		shown++
+		return shown < *limit
+	})
+	w.Flush()
+
+	return err
+}
+
+func inspect(ctx context.Context, cfg *config.Config, reader *messagery.DeadLetterReader, args []string) error {
+	fs := flag.NewFlagSet("inspect", flag.ExitOnError)
+	partition := fs.Int("partition", -1, "dead-letter partition")
+	offset := fs.Int64("offset", -1, "dead-letter offset")
+	fs.Parse(args)
+
+	if *partition < 0 || *offset < 0 {
+		return fmt.Errorf("-partition and -offset are required")
+	}
+
+	found, err := find(ctx, reader, int32(*partition), *offset)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Partition: %d\nOffset:    %d\nKey:       %s\nHeaders:\n", found.Partition, found.Offset, found.Key)
+	keys := make([]string, 0, len(found.Headers))
+	for k := range found.Headers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Printf("  %s: %s\n", k, found.Headers[k])
+	}
+
+	fmt.Println("Payload:")
+	if found.Headers[messagery.HeaderContentType] == messagery.ProtobufContentType {
+		return printProtobuf(ctx, cfg, found)
+	}
+
+	var payload any
+	if err := json.Unmarshal(found.Value, &payload); err != nil {
+		fmt.Println(string(found.Value))
+		return nil
+	}
+	pretty, _ := json.MarshalIndent(payload, "", "  ")
+	fmt.Println(string(pretty))
+
+	return nil
+}
+
+// printProtobuf decodes a Protobuf dead letter with the schema named by its
+// schema_id header, read from the schema registry, and prints the event as
+// JSON. A payload that does not decode is dumped as hex.
+func printProtobuf(ctx context.Context, cfg *config.Config, d *messagery.DeadLetter) error {
+	db, err := database.NewConnection(cfg.Database)
+	if err != nil {
+		return fmt.Errorf("connect to the schema registry: %w", err)
+	}
+	defer db.Close()
+
+	codec := messagery.NewCodec(repository.NewSchemaRepository(db))
+	event, err := codec.Decode(ctx, &messagery.Record{
+		Key:     []byte(d.Key),
+		Value:   d.Value,
+		Headers: d.Headers,
+	})
+	if err != nil {
+		log.Warnf("Unable to decode Protobuf payload due: %v", err)
+		fmt.Print(hex.Dump(d.Value))
+		return nil
+	}
+
+	pretty, err := json.MarshalIndent(event, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(pretty))
+
+	return nil
+}
+
+func redrive(ctx context.Context, cfg *config.Config, reader *messagery.DeadLetterReader, args []string) error {
+	fs := flag.NewFlagSet("redrive", flag.ExitOnError)
+	partition := fs.Int("partition", -1, "re-drive a single dead letter from this partition")
+	offset := fs.Int64("offset", -1, "re-drive a single dead letter at this offset")
+	dryRun := fs.Bool("dry-run", false, "show what would be re-driven without publishing")
+	fs.Parse(args)
+
+	producer, err := messagery.NewProducer(cfg.Kafka.Brokers, cfg.Kafka.Topic, messagery.WithKafkaConfig(cfg.Kafka))
+	if err != nil {
+		return err
+	}
+	defer producer.Close()
+
+	send := func(d *messagery.DeadLetter) error {
+		msg := d.RedriveMessage()
+		if msg.Topic == "" {
+			msg.Topic = cfg.Kafka.Topic
+		}
+		if *dryRun {
+			log.Infof("Would re-drive %d@%d (key %s) to %s", d.Partition, d.Offset, d.Key, msg.Topic)
+			return nil
+		}
+		if err := producer.PublishBatch(ctx, []*messagery.Message{msg}); err != nil {
+			return err
+		}
+		log.Infof("Re-drove %d@%d (key %s) to %s", d.Partition, d.Offset, d.Key, msg.Topic)
+		return nil
+	}
+
+	if *partition >= 0 || *offset >= 0 {
+		if *partition < 0 || *offset < 0 {
+			return fmt.Errorf("-partition and -offset must be given together")
+		}
+		found, err := find(ctx, reader, int32(*partition), *offset)
+		if err != nil {
+			return err
+		}
+		return send(found)
+	}
+
+	var sendErr error
+	count := 0
+	err = reader.Scan(ctx, true, func(d *messagery.DeadLetter) bool {
+		if sendErr = send(d); sendErr != nil {
+			return false
+		}
+		if !*dryRun {
+			if sendErr = reader.Commit(d); sendErr != nil {
+				return false
+			}
+		}
+		count++
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	if sendErr != nil {
+		return sendErr
+	}
+
+	log.Infof("Re-drove %d dead letters", count)
+	return nil
+}
+
+func find(ctx context.Context, reader *messagery.DeadLetterReader, partition int32, offset int64) (*messagery.DeadLetter, error) {
+	var found *messagery.DeadLetter
+	err := reader.Scan(ctx, false, func(d *messagery.DeadLetter) bool {
+		if d.Partition == partition && d.Offset == offset {
+			found = d
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	if found == nil {
+		return nil, fmt.Errorf("no dead letter at partition %d offset %d", partition, offset)
+	}
+
+	return found, nil
+}
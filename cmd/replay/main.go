@@ -0,0 +1,321 @@
+// Command replay republishes stored transactions to Kafka as creation events,
+// so they are processed again after a consumer fix.
+//
+// Usage:
+//
+//	replay [-status S1,S2] [-created-from T] [-created-to T]
+//	       [-date-from D] [-date-to D] [-ids ID1,ID2]
+//	       [-rate N] [-topic T] [-dry-run]
+//
+// At least one selector is required. Transactions are replayed oldest first;
+// voided ones only when VOIDED is one of the requested statuses.
+// Every message carries a replay_id header, shared by the whole run, and a
+// replayed_at header.
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Athla/vr-software-challenge/config"
+	"github.com/Athla/vr-software-challenge/internal/domain/models"
+	"github.com/Athla/vr-software-challenge/internal/infrastructure/database"
+	"github.com/Athla/vr-software-challenge/internal/infrastructure/messagery"
+	"github.com/Athla/vr-software-challenge/internal/repository"
+	"github.com/charmbracelet/log"
+	"github.com/google/uuid"
+	_ "github.com/joho/godotenv/autoload"
+	"golang.org/x/time/rate"
+)
+
+// pageSize is how many transactions are read, and published, at once.
+const pageSize = 100
+
+type options struct {
+	filter repository.ListFilter
+	rate   float64
+	topic  string
+	dryRun bool
+}
+
+func main() {
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Unable to load config: %v", err)
+	}
+
+	opts, err := parseFlags(cfg, os.Args[1:])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	db, err := database.NewConnection(cfg.Database)
+	if err != nil {
+		log.Fatalf("Unable to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	repo := repository.NewTransactionRepository(db)
+
+	if opts.dryRun {
+		err = dryRun(ctx, repo, opts)
+	} else {
+		err = replay(ctx, cfg, db, repo, opts)
+	}
+	if err != nil {
+		log.Fatalf("Replay failed: %v", err)
+	}
+}
+
+func parseFlags(cfg *config.Config, args []string) (*options, error) {
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	statuses := fs.String("status", "", "comma separated statuses to replay")
+	createdFrom := fs.String("created-from", "", "replay transactions created at or after this time (RFC 3339 or YYYY-MM-DD)")
+	createdTo := fs.String("created-to", "", "replay transactions created before this time, or up to the end of this day (RFC 3339 or YYYY-MM-DD)")
+	dateFrom := fs.String("date-from", "", "replay transactions dated on or after this day (YYYY-MM-DD)")
+	dateTo := fs.String("date-to", "", "replay transactions dated on or before this day (YYYY-MM-DD)")
+	ids := fs.String("ids", "", "comma separated transaction IDs to replay")
+	perSecond := fs.Float64("rate", 50, "maximum messages published per second (0 for no limit)")
+	topic := fs.String("topic", cfg.Kafka.Topic, "topic to publish to")
+	dry := fs.Bool("dry-run", false, "list the transactions that would be replayed without publishing")
+	fs.Parse(args)
+
+	opts := &options{
+		filter: repository.ListFilter{
+			SortBy:    repository.SortByCreatedAt,
+			Ascending: true,
+			Limit:     pageSize,
+		},
+		rate:   *perSecond,
+		topic:  *topic,
+		dryRun: *dry,
+	}
+	f := &opts.filter
+
+	if *statuses != "" {
+		for _, s := range strings.Split(*statuses, ",") {
+			status := models.TransactionStatus(strings.ToUpper(strings.TrimSpace(s)))
+			if !status.IsValid() {
+				return nil, fmt.Errorf("invalid status %q", s)
+			}
+			f.Statuses = append(f.Statuses, status)
+		}
+	}
+
+	if *ids != "" {
+		for _, s := range strings.Split(*ids, ",") {
+			id, err := uuid.Parse(strings.TrimSpace(s))
+			if err != nil {
+				return nil, fmt.Errorf("invalid transaction ID %q", s)
+			}
+			f.IDs = append(f.IDs, id)
+		}
+	}
+
+	var err error
+	if f.CreatedFrom, err = parseTime(*createdFrom, false); err != nil {
+		return nil, fmt.Errorf("invalid -created-from: %w", err)
+	}
+	if f.CreatedTo, err = parseTime(*createdTo, true); err != nil {
+		return nil, fmt.Errorf("invalid -created-to: %w", err)
+	}
+	if f.DateFrom, err = parseDate(*dateFrom); err != nil {
+		return nil, fmt.Errorf("invalid -date-from: %w", err)
+	}
+	if f.DateTo, err = parseDate(*dateTo); err != nil {
+		return nil, fmt.Errorf("invalid -date-to: %w", err)
+	}
+
+	if len(f.Statuses) == 0 && len(f.IDs) == 0 && f.CreatedFrom == nil && f.CreatedTo == nil && f.DateFrom == nil && f.DateTo == nil {
+		return nil, fmt.Errorf("at least one of -status, -created-from, -created-to, -date-from, -date-to or -ids is required")
+	}
+	if opts.rate < 0 {
+		return nil, fmt.Errorf("-rate must not be negative")
+	}
+
+	return opts, nil
+}
+
+// parseTime reads an RFC 3339 time or a YYYY-MM-DD day. A day used as an
+// exclusive upper bound (endOfDay) stands for the start of the next day, so
+// the whole day is included.
+func parseTime(value string, endOfDay bool) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t, nil
+	}
+
+	t, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return nil, err
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return &t, nil
+}
+
+func parseDate(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	t, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// pages calls fn with every page of transactions matching the filter, oldest
+// first, and returns the total number of matches.
+func pages(ctx context.Context, repo repository.TransactionRepository, filter repository.ListFilter, fn func(txs []models.Transaction, total int) error) error {
+	filter.IncludeTotal = true
+	total := 0
+
+	for {
+		page, err := repo.List(ctx, filter)
+		if err != nil {
+			return err
+		}
+		if page.Total != nil {
+			total = *page.Total
+			filter.IncludeTotal = false
+		}
+
+		if len(page.Transactions) > 0 {
+			if err := fn(page.Transactions, total); err != nil {
+				return err
+			}
+		}
+
+		if page.NextCursor == "" {
+			return nil
+		}
+		filter.Cursor = page.NextCursor
+	}
+}
+
+func dryRun(ctx context.Context, repo repository.TransactionRepository, opts *options) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tSTATUS\tCREATED AT\tTRANSACTION DATE\tAMOUNT USD")
+
+	count, total := 0, 0
+	seen := map[uuid.UUID]bool{}
+	err := pages(ctx, repo, opts.filter, func(txs []models.Transaction, n int) error {
+		total = n
+		for _, tx := range txs {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+				tx.ID, tx.Status, tx.CreatedAt.Format(time.RFC3339),
+				tx.TransactionDate.Format(time.DateOnly), tx.AmountUSD.StringFixed(2))
+			seen[tx.ID] = true
+			count++
+		}
+		return nil
+	})
+	w.Flush()
+	if err != nil {
+		return err
+	}
+
+	reportMissing(opts.filter.IDs, seen)
+	log.Infof("Would replay %d of %d transactions to %s", count, total, opts.topic)
+	return nil
+}
+
+func replay(ctx context.Context, cfg *config.Config, db *sql.DB, repo repository.TransactionRepository, opts *options) error {
+	codec, err := messagery.NewCodecFromConfig(repository.NewSchemaRepository(db), cfg.Kafka)
+	if err != nil {
+		return err
+	}
+
+	kafkaProducer, err := messagery.NewProducer(cfg.Kafka.Brokers, opts.topic, messagery.WithKafkaConfig(cfg.Kafka))
+	if err != nil {
+		return err
+	}
+	defer kafkaProducer.Close()
+	producer := messagery.NewSerializingProducer(kafkaProducer, opts.topic, codec)
+
+	limit := rate.Inf
+	if opts.rate > 0 {
+		limit = rate.Limit(opts.rate)
+	}
+	limiter := rate.NewLimiter(limit, 1)
+
+	replayID := uuid.NewString()
+	log.Infof("Starting replay %s to %s", replayID, opts.topic)
+
+	start := time.Now()
+	replayed := 0
+	seen := map[uuid.UUID]bool{}
+	err = pages(ctx, repo, opts.filter, func(txs []models.Transaction, total int) error {
+		msgs := make([]*messagery.Message, 0, len(txs))
+		for i := range txs {
+			if err := limiter.Wait(ctx); err != nil {
+				return err
+			}
+
+			tx := &txs[i]
+			msg, err := messagery.NewReplayMessage(&messagery.TransactionMessage{
+				ID:              tx.ID,
+				Description:     tx.Description,
+				TransactionDate: tx.TransactionDate,
+				AmountUSD:       tx.AmountUSD,
+				CreatedAt:       tx.CreatedAt,
+			}, replayID, time.Now())
+			if err != nil {
+				return err
+			}
+			msgs = append(msgs, msg)
+			seen[tx.ID] = true
+		}
+
+		if err := producer.PublishBatch(ctx, msgs); err != nil {
+			return fmt.Errorf("publish after %d replayed transactions: %w", replayed, err)
+		}
+		replayed += len(msgs)
+
+		elapsed := time.Since(start)
+		log.Infof("Replayed %d/%d transactions (%.0f%%) in %s, %.1f/s",
+			replayed, total, percent(replayed, total), elapsed.Round(time.Second), float64(replayed)/elapsed.Seconds())
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	reportMissing(opts.filter.IDs, seen)
+	log.Infof("Replay %s finished: %d transactions published to %s", replayID, replayed, opts.topic)
+	return nil
+}
+
+// reportMissing warns about requested IDs that matched no transaction.
+func reportMissing(ids []uuid.UUID, seen map[uuid.UUID]bool) {
+	for _, id := range ids {
+		if !seen[id] {
+			log.Warnf("Transaction %s not found or excluded by the other selectors", id)
+		}
+	}
+}
+
+func percent(done, total int) float64 {
+	if total == 0 {
+		return 100
+	}
+	return float64(done) * 100 / float64(total)
+}
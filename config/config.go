@@ -3,35 +3,46 @@ package config
 import (
 	"fmt"
 	"os"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
 	_ "github.com/joho/godotenv/autoload"
+	"github.com/shopspring/decimal"
 )
 
 func (c *Config) String() string {
 	return fmt.Sprintf(
-		"Config{App: {Env: %s, Port: %d, Debug: %v, LogLevel: %s}, "+
+		"Config{App: {Env: %s, Port: %d, MetricsPort: %d, Debug: %v, LogLevel: %s, IdempotencyTTL: %s, BatchMaxSize: %d}, "+
 			"Database: {Host: %s, Port: %d, User: %s, Name: %s, SSLMode: %s}, "+
-			"Kafka: {Brokers: %v, GroupID: %s, Topic: %s, ClientID: %s}}",
-		c.App.Env, c.App.Port, c.App.Debug, c.App.LogLevel,
+			"Processing: {MaxAmountUSD: %s, MaxAgeDays: %d}, "+
+			"Treasury: {SyncInterval: %s, CacheTTL: %s, CacheStaleTTL: %s, MaxRecords: %d}, "+
+			"Kafka: {Broker: %s, Brokers: %v, GroupID: %s, Topic: %s, ClientID: %s, SecurityProtocol: %s, SASLMechanism: %s, DLQTopic: %s, RetryMaxAttempts: %d, ConsumerWorkers: %d, HealthMaxLag: %d, Serializer: %s, TopicSerializers: %v}}",
+		c.App.Env, c.App.Port, c.App.MetricsPort, c.App.Debug, c.App.LogLevel, c.App.IdempotencyTTL, c.App.BatchMaxSize,
 		c.Database.Host, c.Database.Port, c.Database.User, c.Database.Name, c.Database.SSLMode,
-		c.Kafka.Brokers, c.Kafka.GroupID, c.Kafka.Topic, c.Kafka.ClientID,
+		c.Processing.MaxAmountUSD, c.Processing.MaxAgeDays,
+		c.Treasury.SyncInterval, c.Treasury.CacheTTL, c.Treasury.CacheStaleTTL, c.Treasury.MaxRecords,
+		c.Kafka.Broker, c.Kafka.Brokers, c.Kafka.GroupID, c.Kafka.Topic, c.Kafka.ClientID, c.Kafka.SecurityProtocol, c.Kafka.SASLMechanism, c.Kafka.DLQTopic, c.Kafka.RetryMaxAttempts, c.Kafka.ConsumerWorkers, c.Kafka.HealthMaxLag, c.Kafka.Serializer, c.Kafka.TopicSerializers,
 	)
 }
 
 type Config struct {
-	App      AppConfig
-	Database DatabaseConfig
-	Kafka    KafkaConfig
+	App        AppConfig
+	Database   DatabaseConfig
+	Processing ProcessingConfig
+	Treasury   TreasuryConfig
+	Kafka      KafkaConfig
 }
 
 type AppConfig struct {
-	Env      string
-	Port     int
-	Debug    bool
-	LogLevel string
+	Env            string
+	Port           int
+	MetricsPort    int
+	Debug          bool
+	LogLevel       string
+	IdempotencyTTL time.Duration
+	BatchMaxSize   int
 }
 
 type DatabaseConfig struct {
@@ -46,24 +57,87 @@ type DatabaseConfig struct {
 	ConnMaxLifetime string `default:"5m"`
 }
 
+// ProcessingConfig holds the business rules of the processing pipeline. Zero
+// values disable a rule.
+type ProcessingConfig struct {
+	MaxAmountUSD decimal.Decimal
+	MaxAgeDays   int
+}
+
+// TreasuryConfig holds the settings of the Treasury exchange rate API.
+type TreasuryConfig struct {
+	// SyncInterval is how often new rates are copied into the database; zero
+	// disables the sync and every conversion calls the API.
+	SyncInterval time.Duration
+	// CacheTTL is how long a rate lookup is served from memory, and
+	// CacheStaleTTL how much longer it may be served when the API fails.
+	CacheTTL      time.Duration
+	CacheStaleTTL time.Duration
+	// MaxRecords caps the records a single query may return across its
+	// pages; zero removes the cap.
+	MaxRecords int
+}
+
 type KafkaConfig struct {
-	Brokers  []string
-	GroupID  string
-	Topic    string
-	ClientID string
+	Broker           string
+	Brokers          []string
+	GroupID          string
+	Topic            string
+	ClientID         string
+	DLQTopic         string
+	RetryMaxAttempts int
+	ConsumerWorkers  int
+	HealthMaxLag     int64
+
+	// Serializer encodes the events of topics missing from TopicSerializers.
+	Serializer       string
+	TopicSerializers map[string]string
+
+	// SecurityProtocol is PLAINTEXT, SSL, SASL_PLAINTEXT or SASL_SSL.
+	SecurityProtocol string
+	// SASLMechanism is PLAIN, SCRAM-SHA-256 or SCRAM-SHA-512.
+	SASLMechanism  string
+	SASLUsername   string
+	SASLPassword   string
+	TLSCAFile      string
+	TLSCertFile    string
+	TLSKeyFile     string
+	TLSKeyPassword string
 }
 
+// Supported Kafka security protocols and SASL mechanisms.
+var (
+	kafkaSecurityProtocols = []string{"PLAINTEXT", "SSL", "SASL_PLAINTEXT", "SASL_SSL"}
+	kafkaSASLMechanisms    = []string{"PLAIN", "SCRAM-SHA-256", "SCRAM-SHA-512"}
+	kafkaSerializers       = []string{"json", "protobuf"}
+)
+
 func Load() (*Config, error) {
+	topicSerializers, err := parseTopicSerializers(os.Getenv("KAFKA_TOPIC_SERIALIZERS"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	var maxAmount decimal.Decimal
+	if value := os.Getenv("PROCESSING_MAX_AMOUNT_USD"); value != "" {
+		if maxAmount, err = decimal.NewFromString(value); err != nil {
+			return nil, fmt.Errorf("invalid config: invalid PROCESSING_MAX_AMOUNT_USD %q", value)
+		}
+	}
+
 	port, _ := strconv.Atoi(os.Getenv("PORT"))
 	dbPort, _ := strconv.Atoi(os.Getenv("DB_PORT"))
 	debug, _ := strconv.ParseBool(os.Getenv("DEBUG"))
 
 	config := &Config{
 		App: AppConfig{
-			Env:      os.Getenv("APP_ENV"),
-			Port:     port,
-			Debug:    debug,
-			LogLevel: os.Getenv("LOG_LEVEL"),
+			Env:            os.Getenv("APP_ENV"),
+			Port:           port,
+			MetricsPort:    getInt("METRICS_PORT", 9090),
+			Debug:          debug,
+			LogLevel:       os.Getenv("LOG_LEVEL"),
+			IdempotencyTTL: getDuration("IDEMPOTENCY_TTL", 24*time.Hour),
+			BatchMaxSize:   getInt("BATCH_MAX_SIZE", 100),
 		},
 		Database: DatabaseConfig{
 			Host:     os.Getenv("DB_HOST"),
@@ -73,14 +147,43 @@ func Load() (*Config, error) {
 			Name:     os.Getenv("DB_NAME"),
 			SSLMode:  os.Getenv("DB_SSL_MODE"),
 		},
+		Processing: ProcessingConfig{
+			MaxAmountUSD: maxAmount,
+			MaxAgeDays:   getInt("PROCESSING_MAX_AGE_DAYS", 0),
+		},
+		Treasury: TreasuryConfig{
+			SyncInterval:  getDuration("TREASURY_SYNC_INTERVAL", 24*time.Hour),
+			CacheTTL:      getDuration("TREASURY_CACHE_TTL", time.Hour),
+			CacheStaleTTL: getDuration("TREASURY_CACHE_STALE_TTL", 24*time.Hour),
+			MaxRecords:    getInt("TREASURY_MAX_RECORDS", 50000),
+		},
 		Kafka: KafkaConfig{
-			Brokers:  strings.Split(os.Getenv("KAFKA_BROKERS"), ","),
-			GroupID:  os.Getenv("KAFKA_GROUP_ID"),
-			Topic:    os.Getenv("KAFKA_TOPIC"),
-			ClientID: os.Getenv("KAFKA_CLIENT_ID"),
+			Broker:           getString("BROKER", "kafka"),
+			Brokers:          strings.Split(os.Getenv("KAFKA_BROKERS"), ","),
+			GroupID:          os.Getenv("KAFKA_GROUP_ID"),
+			Topic:            os.Getenv("KAFKA_TOPIC"),
+			ClientID:         os.Getenv("KAFKA_CLIENT_ID"),
+			DLQTopic:         os.Getenv("KAFKA_DLQ_TOPIC"),
+			RetryMaxAttempts: getInt("KAFKA_RETRY_MAX_ATTEMPTS", 4),
+			ConsumerWorkers:  getInt("KAFKA_CONSUMER_WORKERS", 8),
+			HealthMaxLag:     int64(getInt("KAFKA_HEALTH_MAX_LAG", 1000)),
+			Serializer:       strings.ToLower(getString("KAFKA_SERIALIZER", "json")),
+			TopicSerializers: topicSerializers,
+			SecurityProtocol: strings.ToUpper(getString("KAFKA_SECURITY_PROTOCOL", "PLAINTEXT")),
+			SASLMechanism:    strings.ToUpper(os.Getenv("KAFKA_SASL_MECHANISM")),
+			SASLUsername:     os.Getenv("KAFKA_SASL_USERNAME"),
+			SASLPassword:     os.Getenv("KAFKA_SASL_PASSWORD"),
+			TLSCAFile:        os.Getenv("KAFKA_TLS_CA_FILE"),
+			TLSCertFile:      os.Getenv("KAFKA_TLS_CERT_FILE"),
+			TLSKeyFile:       os.Getenv("KAFKA_TLS_KEY_FILE"),
+			TLSKeyPassword:   os.Getenv("KAFKA_TLS_KEY_PASSWORD"),
 		},
 	}
 
+	if config.Kafka.DLQTopic == "" && config.Kafka.Topic != "" {
+		config.Kafka.DLQTopic = config.Kafka.Topic + ".dlq"
+	}
+
 	if err := config.Validate(); err != nil {
 		return nil, fmt.Errorf("invalid config: %w", err)
 	}
@@ -93,6 +196,18 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("invalid port number: %d", c.App.Port)
 	}
 
+	if c.App.MetricsPort <= 0 || c.App.MetricsPort == c.App.Port {
+		return fmt.Errorf("invalid metrics port number: %d", c.App.MetricsPort)
+	}
+
+	if c.App.IdempotencyTTL <= 0 {
+		return fmt.Errorf("invalid idempotency TTL: %s", c.App.IdempotencyTTL)
+	}
+
+	if c.App.BatchMaxSize <= 0 {
+		return fmt.Errorf("invalid batch max size: %d", c.App.BatchMaxSize)
+	}
+
 	if c.Database.Host == "" {
 		return fmt.Errorf("database host is required")
 	}
@@ -113,6 +228,30 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("database name is required")
 	}
 
+	if c.Processing.MaxAmountUSD.IsNegative() {
+		return fmt.Errorf("invalid processing max amount: %s", c.Processing.MaxAmountUSD)
+	}
+
+	if c.Processing.MaxAgeDays < 0 {
+		return fmt.Errorf("invalid processing max age: %d days", c.Processing.MaxAgeDays)
+	}
+
+	if c.Treasury.SyncInterval < 0 {
+		return fmt.Errorf("invalid Treasury sync interval: %s", c.Treasury.SyncInterval)
+	}
+
+	if c.Treasury.CacheTTL < 0 || c.Treasury.CacheStaleTTL < 0 {
+		return fmt.Errorf("invalid Treasury cache TTLs: %s, %s", c.Treasury.CacheTTL, c.Treasury.CacheStaleTTL)
+	}
+
+	if c.Treasury.MaxRecords < 0 {
+		return fmt.Errorf("invalid Treasury max records: %d", c.Treasury.MaxRecords)
+	}
+
+	if c.Kafka.Broker != "kafka" && c.Kafka.Broker != "memory" {
+		return fmt.Errorf("invalid broker %q: must be kafka or memory", c.Kafka.Broker)
+	}
+
 	if len(c.Kafka.Brokers) == 0 {
 		return fmt.Errorf("at least one Kafka broker is required")
 	}
@@ -125,9 +264,131 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("Kafka topic is required")
 	}
 
+	if c.Kafka.DLQTopic == c.Kafka.Topic {
+		return fmt.Errorf("Kafka dead-letter topic must differ from the main topic")
+	}
+
+	if c.Kafka.RetryMaxAttempts <= 0 {
+		return fmt.Errorf("invalid Kafka retry max attempts: %d", c.Kafka.RetryMaxAttempts)
+	}
+
+	if c.Kafka.ConsumerWorkers <= 0 {
+		return fmt.Errorf("invalid Kafka consumer workers: %d", c.Kafka.ConsumerWorkers)
+	}
+
+	if c.Kafka.HealthMaxLag < 0 {
+		return fmt.Errorf("invalid Kafka health max lag: %d", c.Kafka.HealthMaxLag)
+	}
+
+	if !slices.Contains(kafkaSerializers, c.Kafka.Serializer) {
+		return fmt.Errorf("invalid Kafka serializer %q: must be one of %s", c.Kafka.Serializer, strings.Join(kafkaSerializers, ", "))
+	}
+
+	for topic, serializer := range c.Kafka.TopicSerializers {
+		if !slices.Contains(kafkaSerializers, serializer) {
+			return fmt.Errorf("invalid Kafka serializer %q for topic %s: must be one of %s", serializer, topic, strings.Join(kafkaSerializers, ", "))
+		}
+	}
+
+	return c.Kafka.validateSecurity()
+}
+
+// parseTopicSerializers reads a comma separated list of topic=serializer pairs.
+func parseTopicSerializers(value string) (map[string]string, error) {
+	serializers := map[string]string{}
+	if value == "" {
+		return serializers, nil
+	}
+
+	for _, pair := range strings.Split(value, ",") {
+		topic, serializer, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok || topic == "" || serializer == "" {
+			return nil, fmt.Errorf("invalid KAFKA_TOPIC_SERIALIZERS entry %q: must be topic=serializer", pair)
+		}
+		serializers[topic] = strings.ToLower(serializer)
+	}
+
+	return serializers, nil
+}
+
+// validateSecurity checks that the SASL and TLS settings fit the security
+// protocol: SASL protocols need a mechanism and credentials, and TLS files
+// are only read by the TLS protocols.
+func (k *KafkaConfig) validateSecurity() error {
+	if !slices.Contains(kafkaSecurityProtocols, k.SecurityProtocol) {
+		return fmt.Errorf("invalid Kafka security protocol %q: must be one of %s", k.SecurityProtocol, strings.Join(kafkaSecurityProtocols, ", "))
+	}
+
+	sasl := strings.HasPrefix(k.SecurityProtocol, "SASL_")
+	tls := k.SecurityProtocol == "SSL" || k.SecurityProtocol == "SASL_SSL"
+
+	if sasl {
+		if !slices.Contains(kafkaSASLMechanisms, k.SASLMechanism) {
+			return fmt.Errorf("invalid Kafka SASL mechanism %q: must be one of %s", k.SASLMechanism, strings.Join(kafkaSASLMechanisms, ", "))
+		}
+		if k.SASLUsername == "" || k.SASLPassword == "" {
+			return fmt.Errorf("Kafka SASL username and password are required with %s", k.SecurityProtocol)
+		}
+	} else if k.SASLMechanism != "" || k.SASLUsername != "" || k.SASLPassword != "" {
+		return fmt.Errorf("Kafka SASL settings require a SASL security protocol, got %s", k.SecurityProtocol)
+	}
+
+	files := []struct{ name, path string }{
+		{"KAFKA_TLS_CA_FILE", k.TLSCAFile},
+		{"KAFKA_TLS_CERT_FILE", k.TLSCertFile},
+		{"KAFKA_TLS_KEY_FILE", k.TLSKeyFile},
+	}
+	for _, file := range files {
+		if file.path == "" {
+			continue
+		}
+		if !tls {
+			return fmt.Errorf("%s requires the SSL or SASL_SSL security protocol, got %s", file.name, k.SecurityProtocol)
+		}
+		if _, err := os.Stat(file.path); err != nil {
+			return fmt.Errorf("unable to read %s: %w", file.name, err)
+		}
+	}
+
+	if (k.TLSCertFile == "") != (k.TLSKeyFile == "") {
+		return fmt.Errorf("Kafka TLS client certificate and key must be set together")
+	}
+	if k.TLSKeyPassword != "" && k.TLSKeyFile == "" {
+		return fmt.Errorf("Kafka TLS key password requires a key file")
+	}
+
 	return nil
 }
 
+// getString reads a string from the environment, falling back to def when the
+// variable is unset.
+func getString(key, def string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return def
+}
+
+// getDuration reads a duration such as "24h" from the environment, falling back
+// to def when the variable is unset or malformed.
+func getDuration(key string, def time.Duration) time.Duration {
+	duration, err := time.ParseDuration(os.Getenv(key))
+	if err != nil {
+		return def
+	}
+	return duration
+}
+
+// getInt reads an integer from the environment, falling back to def when the
+// variable is unset or malformed.
+func getInt(key string, def int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return def
+	}
+	return value
+}
+
 func (cfg *DatabaseConfig) GetConnMaxLifetime() time.Duration {
 	duration, err := time.ParseDuration(cfg.ConnMaxLifetime)
 	if err != nil {
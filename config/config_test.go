@@ -1,7 +1,10 @@
 package config_test
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/Athla/vr-software-challenge/config"
 	"github.com/stretchr/testify/assert"
@@ -22,3 +25,106 @@ func TestValidateConfig(t *testing.T) {
 	err := cfg.Validate()
 	assert.NoError(t, err)
 }
+
+func TestValidateKafkaSecurity(t *testing.T) {
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	os.WriteFile(caFile, []byte("ca"), 0o600)
+
+	valid := func() *config.Config {
+		return &config.Config{
+			App: config.AppConfig{Port: 8080, MetricsPort: 9090, IdempotencyTTL: time.Hour, BatchMaxSize: 100},
+			Database: config.DatabaseConfig{
+				Host: "localhost", Port: 5432, User: "user", Password: "password", Name: "db",
+			},
+			Kafka: config.KafkaConfig{
+				Broker: "kafka", Brokers: []string{"localhost:9092"}, GroupID: "group", Topic: "transactions",
+				DLQTopic: "transactions.dlq", RetryMaxAttempts: 4, ConsumerWorkers: 8, SecurityProtocol: "PLAINTEXT",
+				Serializer: "json",
+			},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(k *config.KafkaConfig)
+		wantErr string
+	}{
+		{name: "plaintext", modify: func(k *config.KafkaConfig) {}},
+		{
+			name: "SCRAM over TLS with a CA",
+			modify: func(k *config.KafkaConfig) {
+				k.SecurityProtocol, k.SASLMechanism = "SASL_SSL", "SCRAM-SHA-512"
+				k.SASLUsername, k.SASLPassword, k.TLSCAFile = "user", "secret", caFile
+			},
+		},
+		{
+			name:    "unknown protocol",
+			modify:  func(k *config.KafkaConfig) { k.SecurityProtocol = "TLS" },
+			wantErr: "invalid Kafka security protocol",
+		},
+		{
+			name: "SASL without mechanism",
+			modify: func(k *config.KafkaConfig) {
+				k.SecurityProtocol, k.SASLUsername, k.SASLPassword = "SASL_SSL", "user", "secret"
+			},
+			wantErr: "invalid Kafka SASL mechanism",
+		},
+		{
+			name:    "SASL without credentials",
+			modify:  func(k *config.KafkaConfig) { k.SecurityProtocol, k.SASLMechanism = "SASL_PLAINTEXT", "PLAIN" },
+			wantErr: "username and password are required",
+		},
+		{
+			name:    "credentials without SASL",
+			modify:  func(k *config.KafkaConfig) { k.SASLUsername = "user" },
+			wantErr: "require a SASL security protocol",
+		},
+		{
+			name:    "CA without TLS",
+			modify:  func(k *config.KafkaConfig) { k.TLSCAFile = caFile },
+			wantErr: "KAFKA_TLS_CA_FILE requires the SSL or SASL_SSL security protocol",
+		},
+		{
+			name:    "missing CA file",
+			modify:  func(k *config.KafkaConfig) { k.SecurityProtocol, k.TLSCAFile = "SSL", caFile+".missing" },
+			wantErr: "unable to read KAFKA_TLS_CA_FILE",
+		},
+		{
+			name:    "certificate without key",
+			modify:  func(k *config.KafkaConfig) { k.SecurityProtocol, k.TLSCertFile = "SSL", caFile },
+			wantErr: "certificate and key must be set together",
+		},
+		{
+			name: "protobuf for one topic",
+			modify: func(k *config.KafkaConfig) {
+				k.TopicSerializers = map[string]string{"transactions": "protobuf"}
+			},
+		},
+		{
+			name:    "unknown serializer",
+			modify:  func(k *config.KafkaConfig) { k.Serializer = "avro" },
+			wantErr: "invalid Kafka serializer",
+		},
+		{
+			name: "unknown topic serializer",
+			modify: func(k *config.KafkaConfig) {
+				k.TopicSerializers = map[string]string{"transactions": "avro"}
+			},
+			wantErr: "invalid Kafka serializer \"avro\" for topic transactions",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := valid()
+			tt.modify(&cfg.Kafka)
+
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	go_errors "errors"
+	"net/http"
+	"strings"
+
+	"github.com/Athla/vr-software-challenge/internal/domain/errors"
+	"github.com/Athla/vr-software-challenge/internal/domain/models"
+	"github.com/Athla/vr-software-challenge/internal/repository"
+	"github.com/charmbracelet/log"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type AuditHandler struct {
+	Repo   repository.AuditRepository
+	TxRepo repository.TransactionRepository
+}
+
+// AuditPageResponse represents a page of audit log entries.
+// @Description Audit log page
+type AuditPageResponse struct {
+	Data   []models.AuditLog `json:"data"`
+	Limit  int               `json:"limit" example:"10"`
+	Offset int               `json:"offset" example:"0"`
+}
+
+// @Summary Get transaction history
+// @Description Get the chronological audit trail of a transaction
+// @Tags audit
+// @Accept json
+// @Produce json
+// @Param id path string true "Transaction ID"
+// @Param limit query int false "Limit" default(10)
+// @Param offset query int false "Offset" default(0)
+// @Success 200 {object} AuditPageResponse
+// @Failure 400 {object} gin.H
+// @Failure 404 {object} gin.H
+// @Failure 500 {object} gin.H
+// @Router /transactions/{id}/history [get]
+func (h *AuditHandler) History(ctx *gin.Context) {
+	id, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid transaction ID"})
+		return
+	}
+
+	limit, offset, err := parsePagination(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if _, err := h.TxRepo.GetById(ctx.Request.Context(), id); err != nil {
+		if go_errors.Is(err, errors.ErrTransactionNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Transaction not found"})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch transaction history"})
+		return
+	}
+
+	logs, err := h.Repo.ListByTransaction(ctx.Request.Context(), id, limit, offset)
+	if err != nil {
+		log.Errorf("Unable to fetch transaction history due: %s", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch transaction history"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, AuditPageResponse{Data: logs, Limit: limit, Offset: offset})
+}
+
+// @Summary List audit log
+// @Description Get the audit feed across all transactions, newest first
+// @Tags audit
+// @Accept json
+// @Produce json
+// @Param action query string false "Comma separated actions (CREATE, UPDATE, STATUS_CHANGE, VOID)"
+// @Param from query string false "Start of the time range (RFC 3339 or YYYY-MM-DD)"
+// @Param to query string false "End of the time range (RFC 3339 or YYYY-MM-DD)"
+// @Param limit query int false "Limit" default(10)
+// @Param offset query int false "Offset" default(0)
+// @Success 200 {object} AuditPageResponse
+// @Failure 400 {object} gin.H
+// @Failure 500 {object} gin.H
+// @Router /audit [get]
+func (h *AuditHandler) List(ctx *gin.Context) {
+	limit, offset, err := parsePagination(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	filter := repository.AuditFilter{Limit: limit, Offset: offset}
+
+	if actions := ctx.Query("action"); actions != "" {
+		for _, a := range strings.Split(actions, ",") {
+			action := models.AuditAction(strings.ToUpper(strings.TrimSpace(a)))
+			if !action.IsValid() {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid audit action: " + a})
+				return
+			}
+			filter.Actions = append(filter.Actions, action)
+		}
+	}
+
+	if filter.From, err = parseTimeParam(ctx, "from", false); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if filter.To, err = parseTimeParam(ctx, "to", true); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if filter.From != nil && filter.To != nil && filter.From.After(*filter.To) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "from must not be after to"})
+		return
+	}
+
+	logs, err := h.Repo.List(ctx.Request.Context(), filter)
+	if err != nil {
+		log.Errorf("Unable to fetch audit log due: %s", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch audit log"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, AuditPageResponse{Data: logs, Limit: limit, Offset: offset})
+}
@@ -0,0 +1,112 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Athla/vr-software-challenge/internal/api/handlers"
+	"github.com/Athla/vr-software-challenge/internal/domain/models"
+	"github.com/Athla/vr-software-challenge/internal/repository"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupAuditRouter() (*gin.Engine, *repository.MockTransactionRepository, *repository.MockAuditRepository) {
+	txRepo := repository.NewMockTransactionRepository()
+	auditRepo := repository.NewMockAuditRepository()
+	handler := handlers.AuditHandler{
+		Repo:   auditRepo,
+		TxRepo: txRepo,
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.GET("/transactions/:id/history", handler.History)
+	router.GET("/audit", handler.List)
+
+	return router, txRepo, auditRepo
+}
+
+func TestTransactionHistory(t *testing.T) {
+	router, txRepo, auditRepo := setupAuditRouter()
+
+	tx := &models.Transaction{
+		ID:              uuid.New(),
+		Description:     "Test Transaction",
+		TransactionDate: time.Now(),
+		AmountUSD:       decimal.NewFromFloat(100.0),
+		Status:          models.StatusPending,
+	}
+	txRepo.Create(context.Background(), tx)
+
+	pending, processing := models.StatusPending, models.StatusProcessing
+	created := time.Now().Add(-time.Minute)
+	auditRepo.Add(models.AuditLog{TransactionID: tx.ID, Action: models.AuditStatusChange, OldStatus: &pending, NewStatus: &processing, CreatedAt: created.Add(time.Second)})
+	auditRepo.Add(models.AuditLog{TransactionID: tx.ID, Action: models.AuditCreate, NewStatus: &pending, CreatedAt: created})
+	auditRepo.Add(models.AuditLog{TransactionID: uuid.New(), Action: models.AuditCreate, NewStatus: &pending})
+
+	req, _ := http.NewRequest("GET", "/transactions/"+tx.ID.String()+"/history", nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var resp handlers.AuditPageResponse
+	err := json.Unmarshal(rr.Body.Bytes(), &resp)
+	assert.NoError(t, err)
+	assert.Len(t, resp.Data, 2)
+	assert.Equal(t, models.AuditCreate, resp.Data[0].Action)
+	assert.Equal(t, models.AuditStatusChange, resp.Data[1].Action)
+	assert.Equal(t, models.StatusPending, *resp.Data[1].OldStatus)
+
+	req, _ = http.NewRequest("GET", "/transactions/"+uuid.New().String()+"/history", nil)
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
+
+func TestAuditFeed(t *testing.T) {
+	router, _, auditRepo := setupAuditRouter()
+
+	pending, processing := models.StatusPending, models.StatusProcessing
+	auditRepo.Add(models.AuditLog{TransactionID: uuid.New(), Action: models.AuditCreate, NewStatus: &pending, CreatedAt: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)})
+	auditRepo.Add(models.AuditLog{TransactionID: uuid.New(), Action: models.AuditStatusChange, OldStatus: &pending, NewStatus: &processing, CreatedAt: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)})
+	auditRepo.Add(models.AuditLog{TransactionID: uuid.New(), Action: models.AuditCreate, NewStatus: &pending, CreatedAt: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)})
+
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expectedCount  int
+	}{
+		{name: "All entries", query: "", expectedStatus: http.StatusOK, expectedCount: 3},
+		{name: "By action", query: "?action=create", expectedStatus: http.StatusOK, expectedCount: 2},
+		{name: "By time range", query: "?from=2024-01-01&to=2024-01-02", expectedStatus: http.StatusOK, expectedCount: 2},
+		{name: "Invalid action", query: "?action=DELETE", expectedStatus: http.StatusBadRequest},
+		{name: "Invalid limit", query: "?limit=abc", expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", "/audit"+tt.query, nil)
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var resp handlers.AuditPageResponse
+			err := json.Unmarshal(rr.Body.Bytes(), &resp)
+			assert.NoError(t, err)
+			assert.Len(t, resp.Data, tt.expectedCount)
+		})
+	}
+}
@@ -12,6 +12,7 @@ import (
 
 type CurrencyHandler struct {
 	CurrencyService service.CurrencyServicer
+	Catalog         service.CurrencyLister
 }
 
 type ConversionResponse struct {
@@ -23,6 +24,20 @@ type ConversionResponse struct {
 	ConvertedAmount string `json:"converted_amount"`
 	TargetCurrency  string `json:"target_currency"`
 	ExchangeDate    string `json:"exchange_date"`
+	RateSource      string `json:"rate_source"`
+}
+
+type CurrencyResponse struct {
+	Code             string `json:"code,omitempty"`
+	Descriptor       string `json:"descriptor"`
+	Country          string `json:"country"`
+	FirstRecordDate  string `json:"first_record_date"`
+	LatestRecordDate string `json:"latest_record_date"`
+}
+
+type CurrencyListResponse struct {
+	Data []CurrencyResponse `json:"data"`
+	AsOf string             `json:"as_of,omitempty"`
 }
 
 // @Summary Convert transaction amount to different currency
@@ -76,5 +91,44 @@ func (h *CurrencyHandler) ConvertCurrency(ctx *gin.Context) {
 		ConvertedAmount: conversion.ConvertedAmount.String(),
 		TargetCurrency:  conversion.TargetCurrency,
 		ExchangeDate:    conversion.ExchangeDate.Format("2006-01-02"),
+		RateSource:      conversion.RateSource,
 	})
 }
+
+// @Summary List supported currencies
+// @Description List the currencies with Treasury exchange rates and the record dates they cover. With as_of, only currencies with a rate within six months up to that date are listed.
+// @Tags currencies
+// @Produce json
+// @Param as_of query string false "Date the rates must cover (YYYY-MM-DD or RFC 3339)"
+// @Success 200 {object} CurrencyListResponse
+// @Failure 400 {object} gin.H
+// @Router /currencies [get]
+func (h *CurrencyHandler) ListCurrencies(ctx *gin.Context) {
+	asOf, err := parseTimeParam(ctx, "as_of", false)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	listed, err := h.Catalog.ListCurrencies(ctx.Request.Context(), asOf)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list currencies"})
+		return
+	}
+
+	response := CurrencyListResponse{Data: make([]CurrencyResponse, 0, len(listed))}
+	if asOf != nil {
+		response.AsOf = asOf.UTC().Format("2006-01-02")
+	}
+	for _, c := range listed {
+		response.Data = append(response.Data, CurrencyResponse{
+			Code:             c.Code,
+			Descriptor:       c.Descriptor,
+			Country:          c.Country,
+			FirstRecordDate:  c.FirstRecordDate.Format("2006-01-02"),
+			LatestRecordDate: c.LatestRecordDate.Format("2006-01-02"),
+		})
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
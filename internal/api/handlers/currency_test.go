@@ -42,12 +42,13 @@ func TestConvertCurrency(t *testing.T) {
 					Status:          models.StatusCompleted,
 				}
 				repo.Create(context.Background(), tx)
-				tc.AddMockRate("EUR", decimal.NewFromFloat(0.85), time.Now().UTC().Add(-24*time.Hour))
+				tc.AddMockRate("Euro Zone-Euro", decimal.NewFromFloat(0.85), time.Now().UTC().Add(-24*time.Hour))
 			},
 			expectedStatus: http.StatusOK,
 			expectedBody: map[string]interface{}{
 				"converted_amount": "85",
 				"target_currency":  "EUR",
+				"rate_source":      "live",
 			},
 		},
 	}
@@ -85,3 +86,48 @@ func TestConvertCurrency(t *testing.T) {
 		})
 	}
 }
+
+func TestListCurrencies(t *testing.T) {
+	rates := repository.NewMockRateRepository()
+	_, err := rates.Save(context.Background(), models.RateSourceSync, []treasury.ExchangeRate{
+		{Currency: "Euro Zone-Euro", Rate: decimal.NewFromFloat(0.92), EffectiveDate: time.Date(2024, time.June, 30, 0, 0, 0, 0, time.UTC)},
+		{Currency: "Japan-Yen", Rate: decimal.NewFromFloat(110.5), EffectiveDate: time.Date(2020, time.June, 30, 0, 0, 0, 0, time.UTC)},
+	})
+	assert.NoError(t, err)
+
+	handler := &CurrencyHandler{Catalog: service.NewCurrencyCatalog(rates)}
+	router := gin.New()
+	router.GET("/api/v1/currencies", handler.ListCurrencies)
+
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expectedCodes  []string
+	}{
+		{name: "All currencies", expectedStatus: http.StatusOK, expectedCodes: []string{"EUR", "JPY"}},
+		{name: "As of a date", query: "?as_of=2024-09-15", expectedStatus: http.StatusOK, expectedCodes: []string{"EUR"}},
+		{name: "Invalid as_of", query: "?as_of=yesterday", expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/v1/currencies"+tt.query, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedCodes == nil {
+				return
+			}
+
+			var response CurrencyListResponse
+			assert.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+			var codes []string
+			for _, c := range response.Data {
+				codes = append(codes, c.Code)
+			}
+			assert.Equal(t, tt.expectedCodes, codes)
+		})
+	}
+}
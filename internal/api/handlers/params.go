@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	DefaultPageSize = 10
+	MaxPageSize     = 100
+)
+
+// parsePagination reads the limit and offset query parameters, rejecting
+// malformed values and limits above MaxPageSize.
+func parsePagination(ctx *gin.Context) (limit, offset int, err error) {
+	limit = DefaultPageSize
+
+	if l := ctx.Query("limit"); l != "" {
+		limit, err = strconv.Atoi(l)
+		if err != nil || limit < 1 || limit > MaxPageSize {
+			return 0, 0, fmt.Errorf("limit must be an integer between 1 and %d", MaxPageSize)
+		}
+	}
+
+	if o := ctx.Query("offset"); o != "" {
+		offset, err = strconv.Atoi(o)
+		if err != nil || offset < 0 {
+			return 0, 0, fmt.Errorf("offset must be a non-negative integer")
+		}
+	}
+
+	return limit, offset, nil
+}
+
+// parseTimeParam reads an optional RFC 3339 timestamp or YYYY-MM-DD date from
+// the query string. With endOfDay set, a bare date is read as the last instant
+// of that day so it can be used as an inclusive upper bound.
+func parseTimeParam(ctx *gin.Context, name string, endOfDay bool) (*time.Time, error) {
+	value := ctx.Query(name)
+	if value == "" {
+		return nil, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t, nil
+	}
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		if endOfDay {
+			t = t.Add(24*time.Hour - time.Nanosecond)
+		}
+		return &t, nil
+	}
+
+	return nil, fmt.Errorf("%s must be an RFC 3339 timestamp or a YYYY-MM-DD date", name)
+}
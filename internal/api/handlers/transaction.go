@@ -2,13 +2,14 @@ package handlers
 
 import (
 	go_errors "errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/Athla/vr-software-challenge/internal/domain/errors"
 	"github.com/Athla/vr-software-challenge/internal/domain/models"
-	"github.com/Athla/vr-software-challenge/internal/infrastructure/messagery"
 	"github.com/Athla/vr-software-challenge/internal/repository"
 	"github.com/charmbracelet/log"
 
@@ -17,9 +18,12 @@ import (
 	"github.com/shopspring/decimal"
 )
 
+// DefaultMaxBatchSize caps batch creation requests when no limit is configured.
+const DefaultMaxBatchSize = 100
+
 type TransactionHandler struct {
-	Repo     repository.TransactionRepository
-	Producer messagery.Producerer
+	Repo         repository.TransactionRepository
+	MaxBatchSize int
 }
 
 // CreateTransactionRequest represents the request body for creating a transaction.
@@ -54,6 +58,28 @@ type CreateTransactionResponse struct {
 	Message string `json:"message" example:"Transaction created successfully."`
 }
 
+// newTransaction builds and validates a pending transaction from a creation request.
+func newTransaction(req CreateTransactionRequest) (*models.Transaction, error) {
+	date, err := time.Parse("2006-01-02", req.TransactionDate)
+	if err != nil {
+		return nil, errors.ErrInvalidDateFormat
+	}
+
+	tx := &models.Transaction{
+		ID:              uuid.New(),
+		Description:     req.Description,
+		TransactionDate: date,
+		AmountUSD:       req.AmountUSD.Round(2),
+		Status:          models.StatusPending,
+	}
+
+	if err := tx.Validate(); err != nil {
+		return nil, err
+	}
+
+	return tx, nil
+}
+
 // @Summary Create a new transaction
 // @Description Create a new purchase transaction
 // @Tags transactions
@@ -72,22 +98,8 @@ func (h *TransactionHandler) Create(ctx *gin.Context) {
 		return
 	}
 
-	date, err := time.Parse("2006-01-02", req.TransactionDate)
+	tx, err := newTransaction(req)
 	if err != nil {
-		log.Errorf("Invalid transaction date format: %s", err)
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid transaction date format"})
-		return
-	}
-
-	tx := &models.Transaction{
-		ID:              uuid.New(),
-		Description:     req.Description,
-		TransactionDate: date,
-		AmountUSD:       req.AmountUSD.Round(2),
-		Status:          models.StatusPending,
-	}
-
-	if err := tx.Validate(); err != nil {
 		log.Errorf("Transaction validation failed due: %s", err)
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -99,32 +111,6 @@ func (h *TransactionHandler) Create(ctx *gin.Context) {
 		return
 	}
 
-	msg := &messagery.TransactionMessage{
-		ID:              tx.ID,
-		Description:     tx.Description,
-		TransactionDate: tx.TransactionDate,
-		AmountUSD:       tx.AmountUSD,
-		CreatedAt:       tx.CreatedAt,
-	}
-
-	var publishErr error
-	for i := 0; i < 3; i++ {
-		if err := h.Producer.PublishTransaction(ctx.Request.Context(), msg); err == nil {
-			publishErr = nil
-			break
-		} else {
-			publishErr = err
-			log.Errorf("Retry %d: failed to publish transaction due: %s", i+1, err)
-			time.Sleep(2 * time.Second)
-		}
-	}
-
-	if publishErr != nil {
-		log.Errorf("Failed to publish transaction after retries: %s", publishErr)
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to processes transaction"})
-		return
-	}
-
 	ctx.JSON(http.StatusCreated, CreateTransactionResponse{
 		ID:      tx.ID,
 		Status:  string(tx.Status),
@@ -139,6 +125,7 @@ func (h *TransactionHandler) Create(ctx *gin.Context) {
 // @Produce json
 // @Param id path string true "Transaction ID"
 // @Success 200 {object} models.Transaction
+// @Header 200 {string} ETag "Current version of the transaction"
 // @Failure 400 {object} gin.H
 // @Failure 404 {object} gin.H
 // @Failure 500 {object} gin.H
@@ -160,11 +147,28 @@ func (h *TransactionHandler) GetByID(c *gin.Context) {
 		return
 	}
 
+	c.Header("ETag", tx.ETag())
 	c.JSON(http.StatusOK, tx)
 }
 
+// manualFailedStep is recorded as the failed step of transactions failed
+// through the API.
+const manualFailedStep = "manual"
+
+// patchableStatuses returns the statuses UpdateStatus can move tx to. Voiding
+// has its own endpoint, so VOIDED is left out.
+func patchableStatuses(tx *models.Transaction) []models.TransactionStatus {
+	statuses := []models.TransactionStatus{}
+	for _, status := range tx.NextStatuses() {
+		if status != models.StatusVoided {
+			statuses = append(statuses, status)
+		}
+	}
+	return statuses
+}
+
 // @Summary Update transaction status
-// @Description Update the status of a transaction
+// @Description Move a transaction to the next status of its lifecycle
 // @Tags transactions
 // @Accept json
 // @Produce json
@@ -173,6 +177,7 @@ func (h *TransactionHandler) GetByID(c *gin.Context) {
 // @Success 200 {object} gin.H
 // @Failure 400 {object} gin.H
 // @Failure 404 {object} gin.H
+// @Failure 409 {object} gin.H
 // @Failure 500 {object} gin.H
 // @Router /transactions/{id}/status [patch]
 func (h *TransactionHandler) UpdateStatus(ctx *gin.Context) {
@@ -189,44 +194,292 @@ func (h *TransactionHandler) UpdateStatus(ctx *gin.Context) {
 	}
 
 	status := models.TransactionStatus(req["status"])
-	if err := h.Repo.UpdateStatus(ctx.Request.Context(), id, status); err != nil {
+	if !status.IsValid() {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid transaction status"})
+		return
+	}
+	if status == models.StatusVoided {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Use POST /transactions/{id}/void to void a transaction"})
+		return
+	}
+
+	if status == models.StatusFailed && strings.TrimSpace(req["reason"]) == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "A reason is required to fail a transaction"})
+		return
+	}
+
+	tx, err := h.Repo.GetById(ctx.Request.Context(), id)
+	if err != nil {
 		if go_errors.Is(err, errors.ErrTransactionNotFound) {
 			ctx.JSON(http.StatusNotFound, gin.H{"error": "Transaction not found"})
 			return
 		}
-		log.Errorf("Failed to update transaction status: %v", err)
+		log.Errorf("Failed to fetch transaction: %v", err)
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update transaction status"})
 		return
 	}
 
+	if !tx.Status.CanTransitionTo(status) {
+		ctx.JSON(http.StatusConflict, gin.H{
+			"error":          "Invalid status transition",
+			"current_status": tx.Status,
+			"allowed":        patchableStatuses(tx),
+		})
+		return
+	}
+
+	if status == models.StatusFailed {
+		err = h.Repo.Fail(ctx.Request.Context(), id, manualFailedStep, req["reason"])
+	} else {
+		err = h.Repo.UpdateStatus(ctx.Request.Context(), id, tx.Status, status)
+	}
+	if err != nil {
+		switch {
+		case go_errors.Is(err, errors.ErrTransactionNotFound):
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Transaction not found"})
+		case go_errors.Is(err, errors.ErrConcurrentModification):
+			ctx.JSON(http.StatusConflict, gin.H{"error": "Transaction was modified concurrently, please retry"})
+		default:
+			log.Errorf("Failed to update transaction status: %v", err)
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update transaction status"})
+		}
+		return
+	}
+
 	ctx.JSON(http.StatusOK, gin.H{"message": "Transaction status updated successfully"})
 }
 
+// maxVoidReasonLength bounds the free text reason given when voiding.
+const maxVoidReasonLength = 255
+
+// VoidTransactionRequest represents the request body for voiding a transaction.
+// @Description Transaction void request
+type VoidTransactionRequest struct {
+	// Why the transaction is being voided
+	// @Example "Duplicate purchase"
+	Reason string `json:"reason" binding:"required" example:"Duplicate purchase"`
+}
+
+// @Summary Void a transaction
+// @Description Void a mistaken transaction. The reason is recorded in the audit log and a transaction.voided event is published.
+// @Tags transactions
+// @Accept json
+// @Produce json
+// @Param id path string true "Transaction ID"
+// @Param void body VoidTransactionRequest true "Void reason"
+// @Success 200 {object} gin.H
+// @Failure 400 {object} gin.H
+// @Failure 404 {object} gin.H
+// @Failure 409 {object} gin.H
+// @Failure 500 {object} gin.H
+// @Router /transactions/{id}/void [post]
+func (h *TransactionHandler) Void(ctx *gin.Context) {
+	id, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid transaction ID format"})
+		return
+	}
+
+	var req VoidTransactionRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil || strings.TrimSpace(req.Reason) == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": errors.ErrVoidReasonRequired.Error()})
+		return
+	}
+	reason := strings.TrimSpace(req.Reason)
+	if len(reason) > maxVoidReasonLength {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("reason exceeds %d characters", maxVoidReasonLength)})
+		return
+	}
+
+	tx, err := h.Repo.GetById(ctx.Request.Context(), id)
+	if err != nil {
+		if go_errors.Is(err, errors.ErrTransactionNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Transaction not found"})
+			return
+		}
+		log.Errorf("Failed to fetch transaction: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to void transaction"})
+		return
+	}
+
+	if !tx.Status.CanTransitionTo(models.StatusVoided) {
+		ctx.JSON(http.StatusConflict, gin.H{
+			"error":          "Transaction cannot be voided in its current status",
+			"current_status": tx.Status,
+			"allowed":        tx.NextStatuses(),
+		})
+		return
+	}
+
+	if err := h.Repo.Void(ctx.Request.Context(), id, tx.Status, reason); err != nil {
+		switch {
+		case go_errors.Is(err, errors.ErrTransactionNotFound):
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Transaction not found"})
+		case go_errors.Is(err, errors.ErrConcurrentModification):
+			ctx.JSON(http.StatusConflict, gin.H{"error": "Transaction was modified concurrently, please retry"})
+		default:
+			log.Errorf("Failed to void transaction: %v", err)
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to void transaction"})
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"id":      id,
+		"status":  models.StatusVoided,
+		"message": "Transaction voided successfully",
+	})
+}
+
+// TransactionListResponse represents a page of transactions.
+// @Description Transaction list page
+type TransactionListResponse struct {
+	Data []models.Transaction `json:"data"`
+
+	// Cursor to pass as `cursor` to fetch the next page, null on the last page
+	NextCursor *string `json:"next_cursor"`
+
+	// Number of transactions matching the filters, only set when include_total=true
+	Total *int `json:"total,omitempty"`
+}
+
 // @Summary List transactions
-// @Description Get a list of transactions with pagination
+// @Description Get a filtered list of transactions with cursor pagination
 // @Tags transactions
 // @Accept json
 // @Produce json
 // @Param limit query int false "Limit" default(10)
-// @Param offset query int false "Offset" default(0)
-// @Success 200 {array} models.Transaction
+// @Param cursor query string false "Cursor returned as next_cursor by the previous page"
+// @Param status query string false "Comma separated statuses"
+// @Param date_from query string false "Earliest transaction date (YYYY-MM-DD)"
+// @Param date_to query string false "Latest transaction date (YYYY-MM-DD)"
+// @Param amount_min query string false "Minimum amount in USD"
+// @Param amount_max query string false "Maximum amount in USD"
+// @Param description_prefix query string false "Description starts with (case insensitive)"
+// @Param description_contains query string false "Description contains (case insensitive)"
+// @Param sort query string false "created_at, transaction_date or amount_usd" default(created_at)
+// @Param order query string false "asc or desc" default(desc)
+// @Param include_total query bool false "Include the total count of matching transactions"
+// @Param include_voided query bool false "Include voided transactions when no status filter is given"
+// @Success 200 {object} TransactionListResponse
+// @Failure 400 {object} gin.H
 // @Failure 500 {object} gin.H
 // @Router /transactions [get]
 func (h *TransactionHandler) List(ctx *gin.Context) {
-	limit := 10
-	offset := 0
-
-	if l := ctx.Query("limit"); l != "" {
-		limit, _ = strconv.Atoi(l)
-	}
-	if o := ctx.Query("offset"); o != "" {
-		offset, _ = strconv.Atoi(o)
+	filter, err := parseListFilter(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
-	transactions, err := h.Repo.List(ctx.Request.Context(), limit, offset)
+
+	result, err := h.Repo.List(ctx.Request.Context(), filter)
 	if err != nil {
+		if go_errors.Is(err, errors.ErrInvalidCursor) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list transactions"})
 		return
 	}
 
-	ctx.JSON(http.StatusOK, transactions)
+	resp := TransactionListResponse{
+		Data:  result.Transactions,
+		Total: result.Total,
+	}
+	if result.NextCursor != "" {
+		resp.NextCursor = &result.NextCursor
+	}
+
+	ctx.JSON(http.StatusOK, resp)
+}
+
+// parseListFilter builds a repository filter from the listing query parameters.
+func parseListFilter(ctx *gin.Context) (repository.ListFilter, error) {
+	filter := repository.ListFilter{
+		Limit:               DefaultPageSize,
+		Cursor:              ctx.Query("cursor"),
+		DescriptionPrefix:   ctx.Query("description_prefix"),
+		DescriptionContains: ctx.Query("description_contains"),
+	}
+
+	if ctx.Query("offset") != "" {
+		return filter, fmt.Errorf("offset is not supported, use the cursor returned as next_cursor")
+	}
+
+	if l := ctx.Query("limit"); l != "" {
+		limit, err := strconv.Atoi(l)
+		if err != nil || limit < 1 || limit > MaxPageSize {
+			return filter, fmt.Errorf("limit must be an integer between 1 and %d", MaxPageSize)
+		}
+		filter.Limit = limit
+	}
+
+	if statuses := ctx.Query("status"); statuses != "" {
+		for _, s := range strings.Split(statuses, ",") {
+			status := models.TransactionStatus(strings.ToUpper(strings.TrimSpace(s)))
+			if !status.IsValid() {
+				return filter, fmt.Errorf("invalid status: %s", s)
+			}
+			filter.Statuses = append(filter.Statuses, status)
+		}
+	}
+
+	dateParams := []struct {
+		name   string
+		target **time.Time
+	}{{"date_from", &filter.DateFrom}, {"date_to", &filter.DateTo}}
+	for _, param := range dateParams {
+		if value := ctx.Query(param.name); value != "" {
+			date, err := time.Parse("2006-01-02", value)
+			if err != nil {
+				return filter, fmt.Errorf("%s must be a YYYY-MM-DD date", param.name)
+			}
+			*param.target = &date
+		}
+	}
+
+	amountParams := []struct {
+		name   string
+		target **decimal.Decimal
+	}{{"amount_min", &filter.AmountMin}, {"amount_max", &filter.AmountMax}}
+	for _, param := range amountParams {
+		if value := ctx.Query(param.name); value != "" {
+			amount, err := decimal.NewFromString(value)
+			if err != nil {
+				return filter, fmt.Errorf("%s must be a decimal number", param.name)
+			}
+			*param.target = &amount
+		}
+	}
+
+	if sortBy := ctx.Query("sort"); sortBy != "" {
+		filter.SortBy = repository.SortField(sortBy)
+		if !filter.SortBy.IsValid() {
+			return filter, fmt.Errorf("sort must be one of created_at, transaction_date or amount_usd")
+		}
+	}
+
+	switch strings.ToLower(ctx.DefaultQuery("order", "desc")) {
+	case "asc":
+		filter.Ascending = true
+	case "desc":
+	default:
+		return filter, fmt.Errorf("order must be asc or desc")
+	}
+
+	boolParams := []struct {
+		name   string
+		target *bool
+	}{{"include_total", &filter.IncludeTotal}, {"include_voided", &filter.IncludeVoided}}
+	for _, param := range boolParams {
+		if value := ctx.Query(param.name); value != "" {
+			parsed, err := strconv.ParseBool(value)
+			if err != nil {
+				return filter, fmt.Errorf("%s must be a boolean", param.name)
+			}
+			*param.target = parsed
+		}
+	}
+
+	return filter, nil
 }
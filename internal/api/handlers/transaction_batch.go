@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/Athla/vr-software-challenge/internal/domain/models"
+	"github.com/charmbracelet/log"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type BatchMode string
+
+const (
+	// BatchModeAllOrNothing creates every transaction or none of them.
+	BatchModeAllOrNothing BatchMode = "all_or_nothing"
+	// BatchModePartial creates the valid transactions and reports the invalid ones.
+	BatchModePartial BatchMode = "partial"
+)
+
+const (
+	BatchItemCreated = "created"
+	BatchItemInvalid = "invalid"
+	BatchItemSkipped = "skipped"
+)
+
+// CreateTransactionBatchRequest represents the request body for creating transactions in bulk.
+// @Description Batch transaction creation request
+type CreateTransactionBatchRequest struct {
+	// Either "all_or_nothing" (default) or "partial"
+	// @Example "partial"
+	Mode BatchMode `json:"mode" example:"partial"`
+
+	// Transactions to create
+	Transactions []CreateTransactionRequest `json:"transactions" binding:"required"`
+}
+
+// BatchItemResult reports the outcome of a single batch item.
+// @Description Batch item result
+type BatchItemResult struct {
+	// Position of the item in the request
+	Index int `json:"index" example:"0"`
+
+	// Identifier of the created transaction
+	ID *uuid.UUID `json:"id,omitempty" example:"123e4567-e89b-12d3-a456-426614174000"`
+
+	// One of "created", "invalid" or "skipped"
+	Status string `json:"status" example:"created"`
+
+	// Validation error for invalid items
+	Error string `json:"error,omitempty" example:"amount must be greater than zero"`
+}
+
+// CreateTransactionBatchResponse represents the response for a batch creation.
+// @Description Batch transaction creation response
+type CreateTransactionBatchResponse struct {
+	Mode    BatchMode         `json:"mode" example:"partial"`
+	Created int               `json:"created" example:"2"`
+	Failed  int               `json:"failed" example:"1"`
+	Results []BatchItemResult `json:"results"`
+}
+
+// @Summary Create transactions in bulk
+// @Description Create up to the configured maximum of transactions in a single request.
+// @Description In all_or_nothing mode an invalid item rejects the whole batch; in partial mode valid items are created and invalid ones reported.
+// @Tags transactions
+// @Accept json
+// @Produce json
+// @Param batch body CreateTransactionBatchRequest true "Transactions"
+// @Success 201 {object} CreateTransactionBatchResponse
+// @Success 207 {object} CreateTransactionBatchResponse
+// @Failure 400 {object} gin.H
+// @Failure 422 {object} CreateTransactionBatchResponse
+// @Failure 500 {object} gin.H
+// @Router /transactions/batch [post]
+func (h *TransactionHandler) CreateBatch(ctx *gin.Context) {
+	var req CreateTransactionBatchRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		log.Errorf("Invalid body request: %s", err)
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format."})
+		return
+	}
+
+	if req.Mode == "" {
+		req.Mode = BatchModeAllOrNothing
+	}
+	if req.Mode != BatchModeAllOrNothing && req.Mode != BatchModePartial {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid batch mode."})
+		return
+	}
+
+	maxSize := h.MaxBatchSize
+	if maxSize <= 0 {
+		maxSize = DefaultMaxBatchSize
+	}
+	if len(req.Transactions) == 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Batch must contain at least one transaction."})
+		return
+	}
+	if len(req.Transactions) > maxSize {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Batch cannot contain more than %d transactions.", maxSize)})
+		return
+	}
+
+	results := make([]BatchItemResult, len(req.Transactions))
+	valid := make([]*models.Transaction, 0, len(req.Transactions))
+	validIndexes := make([]int, 0, len(req.Transactions))
+	failed := 0
+
+	for i, item := range req.Transactions {
+		results[i] = BatchItemResult{Index: i}
+
+		tx, err := newTransaction(item)
+		if err != nil {
+			results[i].Status = BatchItemInvalid
+			results[i].Error = err.Error()
+			failed++
+			continue
+		}
+
+		valid = append(valid, tx)
+		validIndexes = append(validIndexes, i)
+	}
+
+	resp := CreateTransactionBatchResponse{
+		Mode:    req.Mode,
+		Failed:  failed,
+		Results: results,
+	}
+
+	if len(valid) == 0 || (failed > 0 && req.Mode == BatchModeAllOrNothing) {
+		for _, i := range validIndexes {
+			results[i].Status = BatchItemSkipped
+		}
+		ctx.JSON(http.StatusUnprocessableEntity, resp)
+		return
+	}
+
+	if err := h.Repo.CreateBatch(ctx.Request.Context(), valid); err != nil {
+		log.Errorf("Unable to create transaction batch due: %s", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create transactions."})
+		return
+	}
+
+	for n, i := range validIndexes {
+		id := valid[n].ID
+		results[i].ID = &id
+		results[i].Status = BatchItemCreated
+	}
+	resp.Created = len(valid)
+
+	status := http.StatusCreated
+	if failed > 0 {
+		status = http.StatusMultiStatus
+	}
+	ctx.JSON(status, resp)
+}
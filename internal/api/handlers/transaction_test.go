@@ -12,7 +12,6 @@ import (
 
 	"github.com/Athla/vr-software-challenge/internal/api/handlers"
 	"github.com/Athla/vr-software-challenge/internal/domain/models"
-	"github.com/Athla/vr-software-challenge/internal/infrastructure/messagery"
 	"github.com/Athla/vr-software-challenge/internal/repository"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -22,10 +21,8 @@ import (
 
 func TestCreateTransaction(t *testing.T) {
 	repo := repository.NewMockTransactionRepository()
-	producer := messagery.NewMockProducer()
 	handler := handlers.TransactionHandler{
-		Repo:     repo,
-		Producer: producer,
+		Repo: repo,
 	}
 
 	gin.SetMode(gin.TestMode)
@@ -56,10 +53,8 @@ func TestCreateTransaction(t *testing.T) {
 
 func TestGetTransactionByID(t *testing.T) {
 	repo := repository.NewMockTransactionRepository()
-	producer := messagery.NewMockProducer()
 	handler := handlers.TransactionHandler{
-		Repo:     repo,
-		Producer: producer,
+		Repo: repo,
 	}
 
 	gin.SetMode(gin.TestMode)
@@ -87,14 +82,13 @@ func TestGetTransactionByID(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, tx.ID, fetchedTx.ID)
 	assert.Equal(t, tx.Description, fetchedTx.Description)
+	assert.Equal(t, `"1"`, rr.Header().Get("ETag"))
 }
 
 func TestUpdateTransactionStatus(t *testing.T) {
 	repo := repository.NewMockTransactionRepository()
-	producer := messagery.NewMockProducer()
 	handler := handlers.TransactionHandler{
-		Repo:     repo,
-		Producer: producer,
+		Repo: repo,
 	}
 
 	gin.SetMode(gin.TestMode)
@@ -111,7 +105,7 @@ func TestUpdateTransactionStatus(t *testing.T) {
 	}
 	repo.Create(context.Background(), tx)
 
-	reqBody := map[string]string{"status": "COMPLETED"}
+	reqBody := map[string]string{"status": "PROCESSING"}
 	body, _ := json.Marshal(reqBody)
 	req, _ := http.NewRequest("PATCH", "/transactions/"+tx.ID.String()+"/status", bytes.NewBuffer(body))
 	req.Header.Set("Content-Type", "application/json")
@@ -126,16 +120,148 @@ func TestUpdateTransactionStatus(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, "Transaction status updated successfully", resp["message"])
 
+	updatedTx, _ := repo.GetById(context.Background(), tx.ID)
+	assert.Equal(t, models.StatusProcessing, updatedTx.Status)
+}
+
+func TestUpdateTransactionStatusInvalidTransition(t *testing.T) {
+	repo := repository.NewMockTransactionRepository()
+	handler := handlers.TransactionHandler{
+		Repo: repo,
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.PATCH("/transactions/:id/status", handler.UpdateStatus)
+
+	tx := &models.Transaction{
+		ID:              uuid.New(),
+		Description:     "Test Transaction",
+		TransactionDate: time.Now(),
+		AmountUSD:       decimal.NewFromFloat(100.0),
+		Status:          models.StatusCompleted,
+	}
+	repo.Create(context.Background(), tx)
+
+	tests := []struct {
+		name           string
+		status         string
+		expectedStatus int
+	}{
+		{name: "Completed back to pending", status: "PENDING", expectedStatus: http.StatusConflict},
+		{name: "Unknown status", status: "DONE", expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, _ := json.Marshal(map[string]string{"status": tt.status})
+			req, _ := http.NewRequest("PATCH", "/transactions/"+tx.ID.String()+"/status", bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+		})
+	}
+
 	updatedTx, _ := repo.GetById(context.Background(), tx.ID)
 	assert.Equal(t, models.StatusCompleted, updatedTx.Status)
 }
 
+func TestUpdateTransactionStatusConflictListsPatchableStatuses(t *testing.T) {
+	repo := repository.NewMockTransactionRepository()
+	handler := handlers.TransactionHandler{
+		Repo: repo,
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.PATCH("/transactions/:id/status", handler.UpdateStatus)
+
+	tests := []struct {
+		name    string
+		current models.TransactionStatus
+		status  string
+		allowed []any
+	}{
+		{name: "Pending", current: models.StatusPending, status: "COMPLETED", allowed: []any{"PROCESSING"}},
+		{name: "Completed", current: models.StatusCompleted, status: "PENDING", allowed: []any{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tx := &models.Transaction{
+				ID:              uuid.New(),
+				Description:     "Test Transaction",
+				TransactionDate: time.Now(),
+				AmountUSD:       decimal.NewFromFloat(100.0),
+				Status:          tt.current,
+			}
+			repo.Create(context.Background(), tx)
+
+			body, _ := json.Marshal(map[string]string{"status": tt.status})
+			req, _ := http.NewRequest("PATCH", "/transactions/"+tx.ID.String()+"/status", bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+			assert.Equal(t, http.StatusConflict, rr.Code)
+
+			var resp map[string]any
+			assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			assert.Equal(t, tt.allowed, resp["allowed"], "VOIDED is reached through the void endpoint")
+		})
+	}
+}
+
+func TestFailTransaction(t *testing.T) {
+	repo := repository.NewMockTransactionRepository()
+	handler := handlers.TransactionHandler{
+		Repo: repo,
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.GET("/transactions/:id", handler.GetByID)
+	router.PATCH("/transactions/:id/status", handler.UpdateStatus)
+
+	tx := &models.Transaction{
+		ID:              uuid.New(),
+		Description:     "Test Transaction",
+		TransactionDate: time.Now(),
+		AmountUSD:       decimal.NewFromFloat(100.0),
+		Status:          models.StatusProcessing,
+	}
+	repo.Create(context.Background(), tx)
+
+	patch := func(body map[string]string) int {
+		b, _ := json.Marshal(body)
+		req, _ := http.NewRequest("PATCH", "/transactions/"+tx.ID.String()+"/status", bytes.NewBuffer(b))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	assert.Equal(t, http.StatusBadRequest, patch(map[string]string{"status": "FAILED"}))
+	assert.Equal(t, http.StatusOK, patch(map[string]string{"status": "FAILED", "reason": "Card declined"}))
+
+	req, _ := http.NewRequest("GET", "/transactions/"+tx.ID.String(), nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	var resp map[string]any
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	assert.Equal(t, "FAILED", resp["status"])
+	assert.Equal(t, "manual", resp["failedstep"])
+	assert.Equal(t, "Card declined", resp["failurereason"])
+}
+
 func TestListTransactions(t *testing.T) {
 	repo := repository.NewMockTransactionRepository()
-	producer := messagery.NewMockProducer()
 	handler := handlers.TransactionHandler{
-		Repo:     repo,
-		Producer: producer,
+		Repo: repo,
 	}
 
 	gin.SetMode(gin.TestMode)
@@ -160,8 +286,309 @@ func TestListTransactions(t *testing.T) {
 
 	assert.Equal(t, http.StatusOK, rr.Code)
 
-	var transactions []models.Transaction
-	err := json.Unmarshal(rr.Body.Bytes(), &transactions)
+	var resp handlers.TransactionListResponse
+	err := json.Unmarshal(rr.Body.Bytes(), &resp)
 	assert.NoError(t, err)
-	assert.Len(t, transactions, 5)
+	assert.Len(t, resp.Data, 5)
+	assert.Nil(t, resp.NextCursor)
+}
+
+func TestListTransactionsFilteredAndPaginated(t *testing.T) {
+	repo := repository.NewMockTransactionRepository()
+	handler := handlers.TransactionHandler{
+		Repo: repo,
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.GET("/transactions", handler.List)
+
+	for i := 0; i < 5; i++ {
+		tx := &models.Transaction{
+			ID:              uuid.New(),
+			Description:     fmt.Sprintf("Office Supplies %d", i),
+			TransactionDate: time.Now(),
+			AmountUSD:       decimal.NewFromInt(int64(10 * (i + 1))),
+			Status:          models.StatusPending,
+		}
+		repo.Create(context.Background(), tx)
+	}
+	repo.Create(context.Background(), &models.Transaction{
+		ID:              uuid.New(),
+		Description:     "Travel",
+		TransactionDate: time.Now(),
+		AmountUSD:       decimal.NewFromInt(15),
+		Status:          models.StatusCompleted,
+	})
+
+	fetch := func(query string) (int, handlers.TransactionListResponse) {
+		req, _ := http.NewRequest("GET", "/transactions"+query, nil)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		var resp handlers.TransactionListResponse
+		json.Unmarshal(rr.Body.Bytes(), &resp)
+		return rr.Code, resp
+	}
+
+	code, first := fetch("?status=PENDING&amount_min=20&sort=amount_usd&order=asc&limit=2&include_total=true")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Len(t, first.Data, 2)
+	assert.Equal(t, "20", first.Data[0].AmountUSD.String())
+	assert.Equal(t, 4, *first.Total)
+	assert.NotNil(t, first.NextCursor)
+
+	code, second := fetch("?status=PENDING&amount_min=20&sort=amount_usd&order=asc&limit=2&cursor=" + *first.NextCursor)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Len(t, second.Data, 2)
+	assert.Equal(t, "40", second.Data[0].AmountUSD.String())
+	assert.Nil(t, second.NextCursor)
+
+	code, _ = fetch("?description_prefix=travel")
+	assert.Equal(t, http.StatusOK, code)
+
+	badQueries := []string{
+		"?limit=abc",
+		"?limit=1000",
+		"?offset=5",
+		"?status=DONE",
+		"?sort=description",
+		"?cursor=not-a-cursor",
+		"?sort=transaction_date&cursor=" + *first.NextCursor,
+	}
+	for _, query := range badQueries {
+		code, _ := fetch(query)
+		assert.Equal(t, http.StatusBadRequest, code, query)
+	}
+}
+
+func TestCreateTransactionBatch(t *testing.T) {
+	valid := handlers.CreateTransactionRequest{
+		Description:     "Valid Transaction",
+		TransactionDate: "2023-10-10",
+		AmountUSD:       decimal.NewFromFloat(10.0),
+	}
+	invalid := handlers.CreateTransactionRequest{
+		Description:     "Invalid Transaction",
+		TransactionDate: "2023-10-10",
+		AmountUSD:       decimal.NewFromFloat(-1.0),
+	}
+
+	tests := []struct {
+		name            string
+		mode            handlers.BatchMode
+		items           []handlers.CreateTransactionRequest
+		expectedStatus  int
+		expectedCreated int
+		expectedResults []string
+	}{
+		{
+			name:            "All valid",
+			mode:            handlers.BatchModeAllOrNothing,
+			items:           []handlers.CreateTransactionRequest{valid, valid},
+			expectedStatus:  http.StatusCreated,
+			expectedCreated: 2,
+			expectedResults: []string{handlers.BatchItemCreated, handlers.BatchItemCreated},
+		},
+		{
+			name:            "All or nothing rejects batch",
+			mode:            handlers.BatchModeAllOrNothing,
+			items:           []handlers.CreateTransactionRequest{valid, invalid},
+			expectedStatus:  http.StatusUnprocessableEntity,
+			expectedCreated: 0,
+			expectedResults: []string{handlers.BatchItemSkipped, handlers.BatchItemInvalid},
+		},
+		{
+			name:            "Partial creates valid items",
+			mode:            handlers.BatchModePartial,
+			items:           []handlers.CreateTransactionRequest{valid, invalid},
+			expectedStatus:  http.StatusMultiStatus,
+			expectedCreated: 1,
+			expectedResults: []string{handlers.BatchItemCreated, handlers.BatchItemInvalid},
+		},
+		{
+			name:           "Too many items",
+			mode:           handlers.BatchModePartial,
+			items:          []handlers.CreateTransactionRequest{valid, valid, valid},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := repository.NewMockTransactionRepository()
+			handler := handlers.TransactionHandler{
+				Repo:         repo,
+				MaxBatchSize: 2,
+			}
+
+			gin.SetMode(gin.TestMode)
+			router := gin.Default()
+			router.POST("/transactions/batch", handler.CreateBatch)
+
+			body, _ := json.Marshal(handlers.CreateTransactionBatchRequest{
+				Mode:         tt.mode,
+				Transactions: tt.items,
+			})
+			req, _ := http.NewRequest("POST", "/transactions/batch", bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			if tt.expectedResults == nil {
+				return
+			}
+
+			var resp handlers.CreateTransactionBatchResponse
+			err := json.Unmarshal(rr.Body.Bytes(), &resp)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedCreated, resp.Created)
+			for i, status := range tt.expectedResults {
+				assert.Equal(t, status, resp.Results[i].Status)
+			}
+
+			stored, _ := repo.List(context.Background(), repository.ListFilter{Limit: 10})
+			assert.Len(t, stored.Transactions, tt.expectedCreated)
+		})
+	}
+}
+
+func TestVoidTransaction(t *testing.T) {
+	repo := repository.NewMockTransactionRepository()
+	handler := handlers.TransactionHandler{
+		Repo: repo,
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.POST("/transactions/:id/void", handler.Void)
+	router.GET("/transactions", handler.List)
+
+	newTx := func(status models.TransactionStatus) *models.Transaction {
+		tx := &models.Transaction{
+			ID:              uuid.New(),
+			Description:     "Test Transaction",
+			TransactionDate: time.Now(),
+			AmountUSD:       decimal.NewFromFloat(100.0),
+			Status:          status,
+		}
+		repo.Create(context.Background(), tx)
+		return tx
+	}
+
+	completed := newTx(models.StatusCompleted)
+	processing := newTx(models.StatusProcessing)
+
+	tests := []struct {
+		name           string
+		id             uuid.UUID
+		body           string
+		expectedStatus int
+	}{
+		{name: "Missing reason", id: completed.ID, body: `{}`, expectedStatus: http.StatusBadRequest},
+		{name: "Blank reason", id: completed.ID, body: `{"reason":"  "}`, expectedStatus: http.StatusBadRequest},
+		{name: "Unknown transaction", id: uuid.New(), body: `{"reason":"Duplicate"}`, expectedStatus: http.StatusNotFound},
+		{name: "Processing cannot be voided", id: processing.ID, body: `{"reason":"Duplicate"}`, expectedStatus: http.StatusConflict},
+		{name: "Void completed", id: completed.ID, body: `{"reason":"Duplicate"}`, expectedStatus: http.StatusOK},
+		{name: "Already voided", id: completed.ID, body: `{"reason":"Duplicate"}`, expectedStatus: http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("POST", "/transactions/"+tt.id.String()+"/void", bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+		})
+	}
+
+	voided, _ := repo.GetById(context.Background(), completed.ID)
+	assert.Equal(t, models.StatusVoided, voided.Status)
+	assert.Equal(t, "Duplicate", *voided.VoidReason)
+
+	list := func(query string) handlers.TransactionListResponse {
+		req, _ := http.NewRequest("GET", "/transactions"+query, nil)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		var resp handlers.TransactionListResponse
+		json.Unmarshal(rr.Body.Bytes(), &resp)
+		return resp
+	}
+
+	assert.Len(t, list("").Data, 1)
+	assert.Len(t, list("?include_voided=true").Data, 2)
+	assert.Len(t, list("?status=VOIDED").Data, 1)
+}
+
+func TestUpdateTransaction(t *testing.T) {
+	repo := repository.NewMockTransactionRepository()
+	handler := handlers.TransactionHandler{
+		Repo: repo,
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.PATCH("/transactions/:id", handler.Update)
+
+	newTx := func(status models.TransactionStatus) *models.Transaction {
+		tx := &models.Transaction{
+			ID:              uuid.New(),
+			Description:     "Test Transaction",
+			TransactionDate: time.Now().AddDate(0, 0, -1),
+			AmountUSD:       decimal.NewFromFloat(100.0),
+			Status:          status,
+		}
+		repo.Create(context.Background(), tx)
+		return tx
+	}
+
+	pending := newTx(models.StatusPending)
+	completed := newTx(models.StatusCompleted)
+
+	tests := []struct {
+		name           string
+		id             uuid.UUID
+		ifMatch        string
+		body           string
+		expectedStatus int
+		expectedETag   string
+	}{
+		{name: "Missing If-Match", id: pending.ID, body: `{"description":"Edited"}`, expectedStatus: http.StatusPreconditionRequired},
+		{name: "Malformed If-Match", id: pending.ID, ifMatch: "1", body: `{"description":"Edited"}`, expectedStatus: http.StatusBadRequest},
+		{name: "Empty body", id: pending.ID, ifMatch: `"1"`, body: `{}`, expectedStatus: http.StatusBadRequest},
+		{name: "Unknown transaction", id: uuid.New(), ifMatch: `"1"`, body: `{"description":"Edited"}`, expectedStatus: http.StatusNotFound},
+		{name: "Invalid amount", id: pending.ID, ifMatch: `"1"`, body: `{"amount_usd":-5}`, expectedStatus: http.StatusBadRequest},
+		{name: "Edit pending", id: pending.ID, ifMatch: `"1"`, body: `{"description":"Edited","amount_usd":42.5}`, expectedStatus: http.StatusOK, expectedETag: `"2"`},
+		{name: "Stale version", id: pending.ID, ifMatch: `"1"`, body: `{"description":"Lost update"}`, expectedStatus: http.StatusPreconditionFailed, expectedETag: `"2"`},
+		{name: "Not pending", id: completed.ID, ifMatch: `"1"`, body: `{"description":"Edited"}`, expectedStatus: http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("PATCH", "/transactions/"+tt.id.String(), bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.ifMatch != "" {
+				req.Header.Set("If-Match", tt.ifMatch)
+			}
+
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			if tt.expectedETag != "" {
+				assert.Equal(t, tt.expectedETag, rr.Header().Get("ETag"))
+			}
+		})
+	}
+
+	edited, _ := repo.GetById(context.Background(), pending.ID)
+	assert.Equal(t, "Edited", edited.Description)
+	assert.True(t, decimal.NewFromFloat(42.5).Equal(edited.AmountUSD))
+	assert.Equal(t, 2, edited.Version)
 }
@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	go_errors "errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Athla/vr-software-challenge/internal/domain/errors"
+	"github.com/charmbracelet/log"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// UpdateTransactionRequest represents the request body for editing a pending transaction.
+// Omitted fields keep their current value.
+// @Description Transaction edit request
+type UpdateTransactionRequest struct {
+	// Description of the purchase
+	// @Example "Office Supplies"
+	Description *string `json:"description,omitempty" example:"Office Supplies"`
+
+	// Date of the transaction in YYYY-MM-DD format
+	// @Example "2024-01-10"
+	TransactionDate *string `json:"transaction_date,omitempty" example:"2024-01-10"`
+
+	// Amount in USD with up to 2 decimal places
+	// @Example 123.45
+	AmountUSD *decimal.Decimal `json:"amount_usd,omitempty" example:"123.45"`
+}
+
+func (r UpdateTransactionRequest) isEmpty() bool {
+	return r.Description == nil && r.TransactionDate == nil && r.AmountUSD == nil
+}
+
+// parseIfMatch extracts the version from an If-Match header holding a single
+// strong ETag as returned by GET /transactions/{id}.
+func parseIfMatch(header string) (int, bool) {
+	header = strings.TrimSpace(header)
+	if len(header) < 2 || header[0] != '"' || header[len(header)-1] != '"' {
+		return 0, false
+	}
+
+	version, err := strconv.Atoi(header[1 : len(header)-1])
+	if err != nil || version < 1 {
+		return 0, false
+	}
+
+	return version, true
+}
+
+// @Summary Edit a pending transaction
+// @Description Change the description, date or amount of a PENDING transaction. The If-Match header must carry the ETag of the version being edited.
+// @Tags transactions
+// @Accept json
+// @Produce json
+// @Param id path string true "Transaction ID"
+// @Param If-Match header string true "ETag returned by GET /transactions/{id}"
+// @Param transaction body UpdateTransactionRequest true "Fields to change"
+// @Success 200 {object} models.Transaction
+// @Header 200 {string} ETag "New version of the transaction"
+// @Failure 400 {object} gin.H
+// @Failure 404 {object} gin.H
+// @Failure 409 {object} gin.H
+// @Failure 412 {object} gin.H
+// @Failure 428 {object} gin.H
+// @Failure 500 {object} gin.H
+// @Router /transactions/{id} [patch]
+func (h *TransactionHandler) Update(ctx *gin.Context) {
+	id, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid transaction ID format"})
+		return
+	}
+
+	ifMatch := ctx.GetHeader("If-Match")
+	if ifMatch == "" {
+		ctx.JSON(http.StatusPreconditionRequired, gin.H{"error": "If-Match header is required"})
+		return
+	}
+	version, ok := parseIfMatch(ifMatch)
+	if !ok {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "If-Match must be the ETag of the transaction"})
+		return
+	}
+
+	var req UpdateTransactionRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+	if req.isEmpty() {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "At least one field must be provided"})
+		return
+	}
+
+	current, err := h.Repo.GetById(ctx.Request.Context(), id)
+	if err != nil {
+		if go_errors.Is(err, errors.ErrTransactionNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Transaction not found"})
+			return
+		}
+		log.Errorf("Failed to fetch transaction: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update transaction"})
+		return
+	}
+
+	if current.Version != version {
+		ctx.Header("ETag", current.ETag())
+		ctx.JSON(http.StatusPreconditionFailed, gin.H{
+			"error":           errors.ErrVersionMismatch.Error(),
+			"current_version": current.Version,
+		})
+		return
+	}
+	if !current.IsEditable() {
+		ctx.JSON(http.StatusConflict, gin.H{
+			"error":          errors.ErrTransactionNotEditable.Error(),
+			"current_status": current.Status,
+		})
+		return
+	}
+
+	tx := *current
+	if req.Description != nil {
+		tx.Description = *req.Description
+	}
+	if req.TransactionDate != nil {
+		date, err := time.Parse("2006-01-02", *req.TransactionDate)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": errors.ErrInvalidDateFormat.Error()})
+			return
+		}
+		tx.TransactionDate = date
+	}
+	if req.AmountUSD != nil {
+		tx.AmountUSD = req.AmountUSD.Round(2)
+	}
+
+	if err := tx.Validate(); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.Repo.Update(ctx.Request.Context(), &tx, version); err != nil {
+		switch {
+		case go_errors.Is(err, errors.ErrTransactionNotFound):
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Transaction not found"})
+		case go_errors.Is(err, errors.ErrVersionMismatch):
+			ctx.JSON(http.StatusPreconditionFailed, gin.H{"error": err.Error()})
+		case go_errors.Is(err, errors.ErrTransactionNotEditable):
+			ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			log.Errorf("Unable to update transaction due: %v", err)
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update transaction"})
+		}
+		return
+	}
+
+	ctx.Header("ETag", tx.ETag())
+	ctx.JSON(http.StatusOK, tx)
+}
@@ -0,0 +1,132 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/Athla/vr-software-challenge/internal/domain/models"
+	"github.com/Athla/vr-software-challenge/internal/repository"
+	"github.com/charmbracelet/log"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	IdempotencyKeyHeader      = "Idempotency-Key"
+	IdempotentReplayedHeader  = "Idempotent-Replayed"
+	maxIdempotencyKeyLength   = 255
+	idempotencyResponseFormat = "application/json; charset=utf-8"
+)
+
+// responseRecorder keeps a copy of the body written by the wrapped handler.
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *responseRecorder) Write(data []byte) (int, error) {
+	r.body.Write(data)
+	return r.ResponseWriter.Write(data)
+}
+
+func (r *responseRecorder) WriteString(s string) (int, error) {
+	r.body.WriteString(s)
+	return r.ResponseWriter.WriteString(s)
+}
+
+// Idempotency makes a handler safe to retry when the client sends an
+// Idempotency-Key header. The first request under a key runs the handler and
+// stores its response; a replay with the same body gets the stored response,
+// a different body under the same key gets 422 and a replay while the first
+// request is still running gets 409. Server errors and panics are not stored,
+// so the request can be retried with the same key.
+func Idempotency(repo repository.IdempotencyRepository, ttl time.Duration) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		key := ctx.GetHeader(IdempotencyKeyHeader)
+		if key == "" {
+			ctx.Next()
+			return
+		}
+
+		if len(key) > maxIdempotencyKeyLength {
+			ctx.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Idempotency-Key is too long."})
+			return
+		}
+
+		body, err := io.ReadAll(ctx.Request.Body)
+		if err != nil {
+			log.Errorf("Unable to read request body due: %s", err)
+			ctx.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid request format."})
+			return
+		}
+		ctx.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		fingerprint := requestFingerprint(ctx.Request, body)
+
+		record, reserved, err := repo.Reserve(ctx.Request.Context(), key, fingerprint, time.Now().Add(ttl))
+		if err != nil {
+			log.Errorf("Unable to reserve idempotency key due: %s", err)
+			ctx.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to process request."})
+			return
+		}
+
+		if !reserved {
+			switch {
+			case record.Fingerprint != fingerprint:
+				ctx.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": "Idempotency-Key was already used with a different request."})
+			case record.Status == models.IdempotencyInProgress:
+				ctx.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "A request with this Idempotency-Key is already in progress."})
+			default:
+				ctx.Header(IdempotentReplayedHeader, "true")
+				ctx.Data(record.ResponseCode, idempotencyResponseFormat, record.ResponseBody)
+				ctx.Abort()
+			}
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: ctx.Writer}
+		ctx.Writer = recorder
+
+		// The outcome must be stored even if the client has gone away.
+		storeCtx := context.WithoutCancel(ctx.Request.Context())
+
+		// The key is released unless a response is stored, so a request that
+		// failed, including one whose handler panicked, can be retried with
+		// the same key.
+		stored := false
+		defer func() {
+			if stored {
+				return
+			}
+			if err := repo.Release(storeCtx, key); err != nil {
+				log.Errorf("Unable to release idempotency key due: %s", err)
+			}
+		}()
+
+		ctx.Next()
+
+		if recorder.Status() >= http.StatusInternalServerError {
+			return
+		}
+
+		stored = true
+		if err := repo.Complete(storeCtx, key, recorder.Status(), recorder.body.Bytes()); err != nil {
+			log.Errorf("Unable to store idempotent response due: %s", err)
+		}
+	}
+}
+
+// requestFingerprint identifies a request by its method, path and body.
+func requestFingerprint(req *http.Request, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(req.Method))
+	h.Write([]byte{0})
+	h.Write([]byte(req.URL.Path))
+	h.Write([]byte{0})
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
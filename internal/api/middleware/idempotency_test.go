@@ -0,0 +1,142 @@
+package middleware_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Athla/vr-software-challenge/internal/api/middleware"
+	"github.com/Athla/vr-software-challenge/internal/repository"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupIdempotentRouter(repo repository.IdempotencyRepository, calls *int32, status int) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/transactions", middleware.Idempotency(repo, time.Hour), func(ctx *gin.Context) {
+		atomic.AddInt32(calls, 1)
+		ctx.JSON(status, gin.H{"id": uuid.New().String()})
+	})
+	return router
+}
+
+func doRequest(router *gin.Engine, key, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("POST", "/transactions", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	if key != "" {
+		req.Header.Set(middleware.IdempotencyKeyHeader, key)
+	}
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestIdempotency_ReplaysStoredResponse(t *testing.T) {
+	var calls int32
+	router := setupIdempotentRouter(repository.NewMockIdempotencyRepository(), &calls, http.StatusCreated)
+
+	first := doRequest(router, "key-1", `{"description":"a"}`)
+	second := doRequest(router, "key-1", `{"description":"a"}`)
+
+	assert.Equal(t, http.StatusCreated, first.Code)
+	assert.Equal(t, http.StatusCreated, second.Code)
+	assert.Equal(t, first.Body.String(), second.Body.String())
+	assert.Equal(t, "true", second.Header().Get(middleware.IdempotentReplayedHeader))
+	assert.Equal(t, int32(1), calls)
+}
+
+func TestIdempotency_MismatchedBody(t *testing.T) {
+	var calls int32
+	router := setupIdempotentRouter(repository.NewMockIdempotencyRepository(), &calls, http.StatusCreated)
+
+	doRequest(router, "key-1", `{"description":"a"}`)
+	rr := doRequest(router, "key-1", `{"description":"b"}`)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	assert.Equal(t, int32(1), calls)
+}
+
+func TestIdempotency_InFlightDuplicate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	entered := make(chan struct{})
+	release := make(chan struct{})
+
+	router := gin.New()
+	router.POST("/transactions", middleware.Idempotency(repository.NewMockIdempotencyRepository(), time.Hour), func(ctx *gin.Context) {
+		close(entered)
+		<-release
+		ctx.JSON(http.StatusCreated, gin.H{"id": uuid.New().String()})
+	})
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() {
+		done <- doRequest(router, "key-1", `{"description":"a"}`)
+	}()
+
+	<-entered
+	rr := doRequest(router, "key-1", `{"description":"a"}`)
+	close(release)
+
+	assert.Equal(t, http.StatusConflict, rr.Code)
+	assert.Equal(t, http.StatusCreated, (<-done).Code)
+}
+
+func TestIdempotency_ServerErrorReleasesKey(t *testing.T) {
+	var calls int32
+	router := setupIdempotentRouter(repository.NewMockIdempotencyRepository(), &calls, http.StatusInternalServerError)
+
+	doRequest(router, "key-1", `{"description":"a"}`)
+	doRequest(router, "key-1", `{"description":"a"}`)
+
+	assert.Equal(t, int32(2), calls)
+}
+
+func TestIdempotency_PanicReleasesKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var calls int32
+
+	router := gin.New()
+	router.Use(gin.Recovery())
+	router.POST("/transactions", middleware.Idempotency(repository.NewMockIdempotencyRepository(), time.Hour), func(ctx *gin.Context) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			panic("handler failed")
+		}
+		ctx.JSON(http.StatusCreated, gin.H{"id": uuid.New().String()})
+	})
+
+	first := doRequest(router, "key-1", `{"description":"a"}`)
+	second := doRequest(router, "key-1", `{"description":"a"}`)
+
+	assert.Equal(t, http.StatusInternalServerError, first.Code)
+	assert.Equal(t, http.StatusCreated, second.Code)
+	assert.Equal(t, int32(2), calls)
+}
+
+func TestIdempotency_ExpiredKeyIsReused(t *testing.T) {
+	var calls int32
+	repo := repository.NewMockIdempotencyRepository()
+	router := setupIdempotentRouter(repo, &calls, http.StatusCreated)
+
+	_, _, err := repo.Reserve(context.Background(), "key-1", "stale", time.Now().Add(-time.Minute))
+	assert.NoError(t, err)
+
+	rr := doRequest(router, "key-1", `{"description":"a"}`)
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	assert.Equal(t, int32(1), calls)
+}
+
+func TestIdempotency_NoKey(t *testing.T) {
+	var calls int32
+	router := setupIdempotentRouter(repository.NewMockIdempotencyRepository(), &calls, http.StatusCreated)
+
+	doRequest(router, "", `{"description":"a"}`)
+	doRequest(router, "", `{"description":"a"}`)
+
+	assert.Equal(t, int32(2), calls)
+}
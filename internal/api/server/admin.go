@@ -0,0 +1,25 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Athla/vr-software-challenge/config"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// NewAdminServer serves operational endpoints on their own port, so they are
+// not exposed next to the public API.
+func NewAdminServer(cfg *config.Config) *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+
+	return &http.Server{
+		Addr:         fmt.Sprintf(":%d", cfg.App.MetricsPort),
+		Handler:      mux,
+		IdleTimeout:  time.Minute,
+		ReadTimeout:  10 * time.Second,
+		WriteTimeout: 30 * time.Second,
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"net/http"
 
 	"github.com/Athla/vr-software-challenge/internal/api/handlers"
+	"github.com/Athla/vr-software-challenge/internal/api/middleware"
 	"github.com/Athla/vr-software-challenge/internal/infrastructure/messagery"
 	"github.com/Athla/vr-software-challenge/internal/infrastructure/treasury"
 	"github.com/Athla/vr-software-challenge/internal/repository"
@@ -21,38 +22,61 @@ func (s *Server) RegisterRoutes() http.Handler {
 	r.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"http://localhost:5173"},
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS", "PATCH"},
-		AllowHeaders:     []string{"Accept", "Authorization", "Content-Type"},
+		AllowHeaders:     []string{"Accept", "Authorization", "Content-Type", "If-Match", middleware.IdempotencyKeyHeader},
+		ExposeHeaders:    []string{"ETag"},
 		AllowCredentials: true,
 	}))
 
 	limiter := tollbooth.NewLimiter(100, nil)
 	r.Use(tollbooth_gin.LimitHandler(limiter))
 
-	producer, err := messagery.NewProducer(s.cfg.Kafka.Brokers, s.cfg.Kafka.Topic)
-	if err != nil {
-		log.Errorf("Unable generate producer due: %s", err)
+	transactionHandler := handlers.TransactionHandler{
+		Repo:         repository.NewTransactionRepository(s.db),
+		MaxBatchSize: s.cfg.App.BatchMaxSize,
 	}
 
-	transactionHandler := handlers.TransactionHandler{
-		Repo:     repository.NewTransactionRepository(s.db),
-		Producer: producer,
+	auditHandler := handlers.AuditHandler{
+		Repo:   repository.NewAuditRepository(s.db),
+		TxRepo: transactionHandler.Repo,
 	}
 
+	idempotency := middleware.Idempotency(
+		repository.NewIdempotencyRepository(s.db),
+		s.cfg.App.IdempotencyTTL,
+	)
+
+	treasuryClient := treasury.NewCachingClient(
+		treasury.NewClient(treasury.WithMaxRecords(s.cfg.Treasury.MaxRecords)),
+		treasury.WithCacheTTL(s.cfg.Treasury.CacheTTL),
+		treasury.WithCacheStaleTTL(s.cfg.Treasury.CacheStaleTTL),
+	)
+
+	rateRepo := repository.NewRateRepository(s.db)
+
 	currencyService := service.NewCurrencyService(
-		treasury.NewClient(),
+		treasuryClient,
 		repository.NewTransactionRepository(s.db),
+		service.WithRateRepository(rateRepo),
+		service.WithEventPublisher(s.producer),
 	)
 
 	currencyHandler := handlers.CurrencyHandler{
 		CurrencyService: currencyService,
+		Catalog:         service.NewCurrencyCatalog(rateRepo, service.WithCatalogTTL(s.cfg.Treasury.CacheTTL)),
 	}
 	v1 := r.Group("/api/v1")
 	{
+		v1.GET("/currencies", currencyHandler.ListCurrencies)
 		v1.GET("/transactions/:id/convert", currencyHandler.ConvertCurrency)
-		v1.POST("/transactions", transactionHandler.Create)
+		v1.POST("/transactions", idempotency, transactionHandler.Create)
+		v1.POST("/transactions/batch", idempotency, transactionHandler.CreateBatch)
 		v1.GET("/transactions/:id", transactionHandler.GetByID)
+		v1.PATCH("/transactions/:id", transactionHandler.Update)
 		v1.PATCH("/transactions/:id/status", transactionHandler.UpdateStatus)
+		v1.POST("/transactions/:id/void", transactionHandler.Void)
 		v1.GET("/transactions", transactionHandler.List)
+		v1.GET("/transactions/:id/history", auditHandler.History)
+		v1.GET("/audit", auditHandler.List)
 	}
 
 	r.GET("/health", func(ctx *gin.Context) {
@@ -62,13 +86,13 @@ func (s *Server) RegisterRoutes() http.Handler {
 			return
 		}
 
-		if err := messagery.NewHealthCheck(producer, nil).Check(ctx.Request.Context()); err != nil {
-			log.Errorf("Unable to check health of kafka due: %s", err)
-			ctx.JSON(http.StatusInternalServerError, gin.H{"status": "Kafka connection failed."})
+		kafka := s.health.Check(ctx.Request.Context())
+		if kafka.Status == messagery.HealthDown {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"status": "Kafka connection failed.", "kafka": kafka})
 			return
 		}
 
-		ctx.JSON(http.StatusOK, gin.H{"status": "ok"})
+		ctx.JSON(http.StatusOK, gin.H{"status": kafka.Status, "kafka": kafka})
 	})
 
 	return r
@@ -16,14 +16,16 @@ type Server struct {
 	cfg      *config.Config
 	db       *sql.DB
 	producer messagery.Producerer
+	health   *messagery.HealthCheck
 }
 
-func NewServer(cfg *config.Config, db *sql.DB, producer messagery.Producerer) *http.Server {
+func NewServer(cfg *config.Config, db *sql.DB, producer messagery.Producerer, health *messagery.HealthCheck) *http.Server {
 	server := &Server{
 		port:     cfg.App.Port,
 		cfg:      cfg,
 		db:       db,
 		producer: producer,
+		health:   health,
 	}
 
 	return &http.Server{
@@ -0,0 +1,209 @@
+// Package currencies maps ISO 4217 currency codes to the descriptors the
+// Treasury rates of exchange dataset files them under (country_currency_desc),
+// such as "Euro Zone-Euro" for EUR.
+package currencies
+
+import (
+	"slices"
+	"strings"
+	"time"
+)
+
+// Currency is an ISO 4217 currency and the Treasury descriptors of its rates.
+type Currency struct {
+	Code       string
+	MinorUnits int
+	Country    string
+	// Descriptors are ordered oldest first. Most currencies have a single
+	// one; a currency renamed by its issuer, keeping its ISO code, has one
+	// per name.
+	Descriptors []Descriptor
+}
+
+// Descriptor is a Treasury country_currency_desc and the record dates it was
+// used for. A zero From or Until leaves that end open.
+type Descriptor struct {
+	Name  string
+	From  time.Time
+	Until time.Time
+}
+
+// overlaps reports whether the descriptor was in use between from and to.
+func (d Descriptor) overlaps(from, to time.Time) bool {
+	return (d.From.IsZero() || !d.From.After(to)) && (d.Until.IsZero() || !d.Until.Before(from))
+}
+
+// DescriptorsBetween returns the descriptors used for record dates between
+// from and to, inclusive.
+func (c Currency) DescriptorsBetween(from, to time.Time) []string {
+	var names []string
+	for _, d := range c.Descriptors {
+		if d.overlaps(from, to) {
+			names = append(names, d.Name)
+		}
+	}
+	return names
+}
+
+// Lookup finds a currency by ISO 4217 code or by any of its Treasury
+// descriptors, ignoring case.
+func Lookup(codeOrDescriptor string) (Currency, bool) {
+	key := strings.ToUpper(strings.TrimSpace(codeOrDescriptor))
+	if c, ok := byCode[key]; ok {
+		return c, true
+	}
+	c, ok := byDescriptor[key]
+	return c, ok
+}
+
+// defaultMinorUnits is assumed for currencies missing from the catalog.
+const defaultMinorUnits = 2
+
+// Uncatalogued describes a currency missing from the catalog by its Treasury
+// descriptor: it has that single descriptor, no code, two minor units, and its
+// country is the part before the last hyphen. Whether Treasury has rates under
+// the descriptor is up to the caller to find out.
+func Uncatalogued(descriptor string) Currency {
+	name := strings.TrimSpace(descriptor)
+	country := name
+	if i := strings.LastIndex(name, "-"); i > 0 {
+		country = name[:i]
+	}
+
+	return Currency{MinorUnits: defaultMinorUnits, Country: country, Descriptors: single(name)}
+}
+
+// Name is the ISO code of the currency, or its descriptor when uncatalogued.
+func (c Currency) Name() string {
+	if c.Code != "" || len(c.Descriptors) == 0 {
+		return c.Code
+	}
+	return c.Descriptors[0].Name
+}
+
+// All returns the catalog ordered by code.
+func All() []Currency {
+	return slices.Clone(catalog)
+}
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func single(name string) []Descriptor {
+	return []Descriptor{{Name: name}}
+}
+
+// catalog is ordered by code. Descriptors follow the spelling of the Treasury
+// dataset; windows are record dates.
+var catalog = []Currency{
+	{Code: "AED", MinorUnits: 2, Country: "United Arab Emirates", Descriptors: single("United Arab Emirates-Dirham")},
+	{Code: "AFN", MinorUnits: 2, Country: "Afghanistan", Descriptors: single("Afghanistan-Afghani")},
+	{Code: "ALL", MinorUnits: 2, Country: "Albania", Descriptors: single("Albania-Lek")},
+	{Code: "AOA", MinorUnits: 2, Country: "Angola", Descriptors: single("Angola-Kwanza")},
+	{Code: "ARS", MinorUnits: 2, Country: "Argentina", Descriptors: single("Argentina-Peso")},
+	{Code: "AUD", MinorUnits: 2, Country: "Australia", Descriptors: single("Australia-Dollar")},
+	{Code: "BDT", MinorUnits: 2, Country: "Bangladesh", Descriptors: single("Bangladesh-Taka")},
+	{Code: "BHD", MinorUnits: 3, Country: "Bahrain", Descriptors: single("Bahrain-Dinar")},
+	{Code: "BOB", MinorUnits: 2, Country: "Bolivia", Descriptors: single("Bolivia-Boliviano")},
+	{Code: "BRL", MinorUnits: 2, Country: "Brazil", Descriptors: single("Brazil-Real")},
+	{Code: "BWP", MinorUnits: 2, Country: "Botswana", Descriptors: single("Botswana-Pula")},
+	{Code: "CAD", MinorUnits: 2, Country: "Canada", Descriptors: single("Canada-Dollar")},
+	{Code: "CHF", MinorUnits: 2, Country: "Switzerland", Descriptors: single("Switzerland-Franc")},
+	{Code: "CLP", MinorUnits: 0, Country: "Chile", Descriptors: single("Chile-Peso")},
+	{Code: "CNY", MinorUnits: 2, Country: "China", Descriptors: single("China-Renminbi")},
+	{Code: "COP", MinorUnits: 2, Country: "Colombia", Descriptors: single("Colombia-Peso")},
+	{Code: "CRC", MinorUnits: 2, Country: "Costa Rica", Descriptors: single("Costa Rica-Colon")},
+	{Code: "CZK", MinorUnits: 2, Country: "Czech Republic", Descriptors: single("Czech Republic-Koruna")},
+	{Code: "DKK", MinorUnits: 2, Country: "Denmark", Descriptors: single("Denmark-Krone")},
+	{Code: "DOP", MinorUnits: 2, Country: "Dominican Republic", Descriptors: single("Dominican Republic-Peso")},
+	{Code: "DZD", MinorUnits: 2, Country: "Algeria", Descriptors: single("Algeria-Dinar")},
+	{Code: "EGP", MinorUnits: 2, Country: "Egypt", Descriptors: single("Egypt-Pound")},
+	{Code: "ETB", MinorUnits: 2, Country: "Ethiopia", Descriptors: single("Ethiopia-Birr")},
+	{Code: "EUR", MinorUnits: 2, Country: "Euro Zone", Descriptors: single("Euro Zone-Euro")},
+	{Code: "GBP", MinorUnits: 2, Country: "United Kingdom", Descriptors: single("United Kingdom-Pound")},
+	{Code: "GEL", MinorUnits: 2, Country: "Georgia", Descriptors: single("Georgia-Lari")},
+	{Code: "GHS", MinorUnits: 2, Country: "Ghana", Descriptors: single("Ghana-Cedi")},
+	{Code: "GTQ", MinorUnits: 2, Country: "Guatemala", Descriptors: single("Guatemala-Quetzal")},
+	{Code: "HKD", MinorUnits: 2, Country: "Hong Kong", Descriptors: single("Hong Kong-Dollar")},
+	{Code: "HNL", MinorUnits: 2, Country: "Honduras", Descriptors: single("Honduras-Lempira")},
+	// Croatia adopted the euro in 2023.
+	{Code: "HRK", MinorUnits: 2, Country: "Croatia", Descriptors: []Descriptor{
+		{Name: "Croatia-Kuna", Until: date(2022, time.December, 31)},
+	}},
+	{Code: "HUF", MinorUnits: 2, Country: "Hungary", Descriptors: single("Hungary-Forint")},
+	{Code: "IDR", MinorUnits: 2, Country: "Indonesia", Descriptors: single("Indonesia-Rupiah")},
+	{Code: "ILS", MinorUnits: 2, Country: "Israel", Descriptors: single("Israel-Shekel")},
+	{Code: "INR", MinorUnits: 2, Country: "India", Descriptors: single("India-Rupee")},
+	{Code: "IQD", MinorUnits: 3, Country: "Iraq", Descriptors: single("Iraq-Dinar")},
+	{Code: "ISK", MinorUnits: 0, Country: "Iceland", Descriptors: single("Iceland-Krona")},
+	{Code: "JMD", MinorUnits: 2, Country: "Jamaica", Descriptors: single("Jamaica-Dollar")},
+	{Code: "JOD", MinorUnits: 3, Country: "Jordan", Descriptors: single("Jordan-Dinar")},
+	{Code: "JPY", MinorUnits: 0, Country: "Japan", Descriptors: single("Japan-Yen")},
+	{Code: "KES", MinorUnits: 2, Country: "Kenya", Descriptors: single("Kenya-Shilling")},
+	{Code: "KHR", MinorUnits: 2, Country: "Cambodia", Descriptors: single("Cambodia-Riel")},
+	{Code: "KRW", MinorUnits: 0, Country: "Korea", Descriptors: single("Korea-Won")},
+	{Code: "KWD", MinorUnits: 3, Country: "Kuwait", Descriptors: single("Kuwait-Dinar")},
+	{Code: "KZT", MinorUnits: 2, Country: "Kazakhstan", Descriptors: single("Kazakhstan-Tenge")},
+	{Code: "LBP", MinorUnits: 2, Country: "Lebanon", Descriptors: single("Lebanon-Pound")},
+	{Code: "LKR", MinorUnits: 2, Country: "Sri Lanka", Descriptors: single("Sri Lanka-Rupee")},
+	{Code: "MAD", MinorUnits: 2, Country: "Morocco", Descriptors: single("Morocco-Dirham")},
+	{Code: "MNT", MinorUnits: 2, Country: "Mongolia", Descriptors: single("Mongolia-Tugrik")},
+	{Code: "MXN", MinorUnits: 2, Country: "Mexico", Descriptors: single("Mexico-Peso")},
+	{Code: "MYR", MinorUnits: 2, Country: "Malaysia", Descriptors: single("Malaysia-Ringgit")},
+	{Code: "NGN", MinorUnits: 2, Country: "Nigeria", Descriptors: single("Nigeria-Naira")},
+	{Code: "NOK", MinorUnits: 2, Country: "Norway", Descriptors: single("Norway-Krone")},
+	{Code: "NPR", MinorUnits: 2, Country: "Nepal", Descriptors: single("Nepal-Rupee")},
+	{Code: "NZD", MinorUnits: 2, Country: "New Zealand", Descriptors: single("New Zealand-Dollar")},
+	{Code: "OMR", MinorUnits: 3, Country: "Oman", Descriptors: single("Oman-Rial")},
+	// The Nuevo Sol was renamed Sol in 2015, keeping its code.
+	{Code: "PEN", MinorUnits: 2, Country: "Peru", Descriptors: []Descriptor{
+		{Name: "Peru-Nuevo Sol", Until: date(2015, time.December, 31)},
+		{Name: "Peru-Sol", From: date(2016, time.January, 1)},
+	}},
+	{Code: "PHP", MinorUnits: 2, Country: "Philippines", Descriptors: single("Philippines-Peso")},
+	{Code: "PKR", MinorUnits: 2, Country: "Pakistan", Descriptors: single("Pakistan-Rupee")},
+	{Code: "PLN", MinorUnits: 2, Country: "Poland", Descriptors: single("Poland-Zloty")},
+	{Code: "PYG", MinorUnits: 0, Country: "Paraguay", Descriptors: single("Paraguay-Guarani")},
+	{Code: "QAR", MinorUnits: 2, Country: "Qatar", Descriptors: single("Qatar-Riyal")},
+	{Code: "RSD", MinorUnits: 2, Country: "Serbia", Descriptors: single("Serbia-Dinar")},
+	{Code: "RUB", MinorUnits: 2, Country: "Russia", Descriptors: single("Russia-Ruble")},
+	{Code: "SAR", MinorUnits: 2, Country: "Saudi Arabia", Descriptors: single("Saudi Arabia-Riyal")},
+	{Code: "SEK", MinorUnits: 2, Country: "Sweden", Descriptors: single("Sweden-Krona")},
+	{Code: "SGD", MinorUnits: 2, Country: "Singapore", Descriptors: single("Singapore-Dollar")},
+	{Code: "THB", MinorUnits: 2, Country: "Thailand", Descriptors: single("Thailand-Baht")},
+	{Code: "TND", MinorUnits: 3, Country: "Tunisia", Descriptors: single("Tunisia-Dinar")},
+	// The New Lira was renamed Lira in 2009, keeping its code.
+	{Code: "TRY", MinorUnits: 2, Country: "Turkey", Descriptors: []Descriptor{
+		{Name: "Turkey-New Lira", Until: date(2008, time.December, 31)},
+		{Name: "Turkey-Lira", From: date(2009, time.January, 1)},
+	}},
+	{Code: "TWD", MinorUnits: 2, Country: "Taiwan", Descriptors: single("Taiwan-Dollar")},
+	{Code: "TZS", MinorUnits: 2, Country: "Tanzania", Descriptors: single("Tanzania-Shilling")},
+	{Code: "UAH", MinorUnits: 2, Country: "Ukraine", Descriptors: single("Ukraine-Hryvnia")},
+	{Code: "UGX", MinorUnits: 0, Country: "Uganda", Descriptors: single("Uganda-Shilling")},
+	{Code: "UYU", MinorUnits: 2, Country: "Uruguay", Descriptors: single("Uruguay-Peso")},
+	// The Bolivar Fuerte was replaced by the Bolivar Soberano in 2018.
+	{Code: "VEF", MinorUnits: 2, Country: "Venezuela", Descriptors: []Descriptor{
+		{Name: "Venezuela-Bolivar Fuerte", Until: date(2018, time.September, 30)},
+	}},
+	{Code: "VES", MinorUnits: 2, Country: "Venezuela", Descriptors: []Descriptor{
+		{Name: "Venezuela-Bolivar Soberano", From: date(2018, time.August, 20)},
+	}},
+	{Code: "VND", MinorUnits: 0, Country: "Vietnam", Descriptors: single("Vietnam-Dong")},
+	{Code: "ZAR", MinorUnits: 2, Country: "South Africa", Descriptors: single("South Africa-Rand")},
+}
+
+var (
+	byCode       = map[string]Currency{}
+	byDescriptor = map[string]Currency{}
+)
+
+func init() {
+	for _, c := range catalog {
+		byCode[c.Code] = c
+		for _, d := range c.Descriptors {
+			byDescriptor[strings.ToUpper(d.Name)] = c
+		}
+	}
+}
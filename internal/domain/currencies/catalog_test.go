@@ -0,0 +1,91 @@
+package currencies
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLookup(t *testing.T) {
+	tests := []struct {
+		input string
+		code  string
+	}{
+		{input: "EUR", code: "EUR"},
+		{input: "eur", code: "EUR"},
+		{input: "Euro Zone-Euro", code: "EUR"},
+		{input: "euro zone-euro", code: "EUR"},
+		{input: "Peru-Nuevo Sol", code: "PEN"},
+		{input: "Peru-Sol", code: "PEN"},
+		{input: " JPY ", code: "JPY"},
+		{input: "PLN", code: "PLN"},
+		{input: "Israel-Shekel", code: "ILS"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			c, ok := Lookup(tt.input)
+			require.True(t, ok)
+			assert.Equal(t, tt.code, c.Code)
+		})
+	}
+
+	for _, input := range []string{"", "XYZ", "Euro", "Atlantis-Dollar"} {
+		_, ok := Lookup(input)
+		assert.False(t, ok, input)
+	}
+}
+
+func TestUncatalogued(t *testing.T) {
+	ngn := Uncatalogued(" Bhutan-Ngultrum ")
+	assert.Empty(t, ngn.Code)
+	assert.Equal(t, "Bhutan-Ngultrum", ngn.Name())
+	assert.Equal(t, "Bhutan", ngn.Country)
+	assert.Equal(t, 2, ngn.MinorUnits)
+	assert.Equal(t, []string{"Bhutan-Ngultrum"}, ngn.DescriptorsBetween(date(2024, time.January, 1), date(2024, time.June, 30)))
+
+	gnb := Uncatalogued("Guinea-Bissau-CFA Franc")
+	assert.Equal(t, "Guinea-Bissau", gnb.Country)
+}
+
+func TestDescriptorsBetween(t *testing.T) {
+	pen, _ := Lookup("PEN")
+
+	assert.Equal(t, []string{"Peru-Nuevo Sol"},
+		pen.DescriptorsBetween(date(2015, time.January, 1), date(2015, time.June, 30)))
+	assert.Equal(t, []string{"Peru-Nuevo Sol", "Peru-Sol"},
+		pen.DescriptorsBetween(date(2015, time.September, 1), date(2016, time.March, 1)))
+	assert.Equal(t, []string{"Peru-Sol"},
+		pen.DescriptorsBetween(date(2023, time.September, 1), date(2024, time.March, 1)))
+
+	hrk, _ := Lookup("HRK")
+	assert.Empty(t, hrk.DescriptorsBetween(date(2023, time.September, 1), date(2024, time.March, 1)))
+
+	eur, _ := Lookup("EUR")
+	assert.Equal(t, []string{"Euro Zone-Euro"}, eur.DescriptorsBetween(date(2001, time.January, 1), date(2024, time.March, 1)))
+}
+
+func TestCatalog(t *testing.T) {
+	descriptors := map[string]string{}
+	for i, c := range catalog {
+		if i > 0 {
+			assert.Less(t, catalog[i-1].Code, c.Code, "the catalog is ordered by code")
+		}
+		assert.Len(t, c.Code, 3)
+		assert.NotEmpty(t, c.Country)
+		require.NotEmpty(t, c.Descriptors, c.Code)
+
+		for j, d := range c.Descriptors {
+			if other, ok := descriptors[d.Name]; ok {
+				t.Errorf("descriptor %q is used by %s and %s", d.Name, other, c.Code)
+			}
+			descriptors[d.Name] = c.Code
+
+			if j > 0 {
+				assert.True(t, c.Descriptors[j-1].Until.Before(d.From), "%s descriptors are ordered and do not overlap", c.Code)
+			}
+		}
+	}
+}
@@ -15,5 +15,14 @@ var (
 	ErrNoValidExchangeRate    = errors.New("no valid exchange rate found within 6 months of transaction date")
 	ErrInvalidCurrency        = errors.New("invalid currency code")
 	ErrTreasuryAPIError       = errors.New("treasury API error")
+	ErrTooManyExchangeRates   = errors.New("too many exchange rate records")
 	ErrConversionFailed       = errors.New("currency conversion failed")
+	ErrInvalidStatus          = errors.New("invalid transaction status")
+	ErrInvalidTransition      = errors.New("invalid transaction status transition")
+	ErrInvalidDateFormat      = errors.New("invalid transaction date format")
+	ErrInvalidCursor          = errors.New("invalid pagination cursor")
+	ErrVoidReasonRequired     = errors.New("a reason is required to void a transaction")
+	ErrVersionMismatch        = errors.New("transaction version does not match")
+	ErrTransactionNotEditable = errors.New("only pending transactions can be edited")
+	ErrFailureReasonRequired  = errors.New("a failed step and reason are required to fail a transaction")
 )
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type AuditAction string
+
+const (
+	AuditCreate       AuditAction = "CREATE"
+	AuditUpdate       AuditAction = "UPDATE"
+	AuditStatusChange AuditAction = "STATUS_CHANGE"
+	AuditVoid         AuditAction = "VOID"
+)
+
+// IsValid reports whether the action is one recorded by the audit trigger.
+func (a AuditAction) IsValid() bool {
+	switch a {
+	case AuditCreate, AuditUpdate, AuditStatusChange, AuditVoid:
+		return true
+	}
+	return false
+}
+
+// AuditLog is an entry of transaction_audit_logs, written by the database
+// whenever a transaction is created or changed.
+type AuditLog struct {
+	ID            uuid.UUID          `db:"id" json:"id"`
+	TransactionID uuid.UUID          `db:"transaction_id" json:"transaction_id"`
+	Action        AuditAction        `db:"action" json:"action"`
+	OldStatus     *TransactionStatus `db:"old_status" json:"old_status"`
+	NewStatus     *TransactionStatus `db:"new_status" json:"new_status"`
+	Reason        *string            `db:"reason" json:"reason,omitempty"`
+	Changes       json.RawMessage    `db:"changes" json:"changes,omitempty"`
+	CreatedAt     time.Time          `db:"created_at" json:"created_at"`
+}
@@ -17,19 +17,25 @@ type CurrencyConversion struct {
 	ExchangeDate    time.Time
 	TargetCurrency  string
 	ConvertedAmount decimal.Decimal
+	// RateSource tells whether the rate was fetched live, served from a
+	// cache or store, or served stale because Treasury was unavailable.
+	RateSource string
 }
 
+// NewCurrencyConversion converts the amount of tx at exchangeRate, rounded to
+// the minorUnits of the target currency (0 for JPY, 3 for KWD).
 func NewCurrencyConversion(
 	tx *Transaction,
 	targetCurrency string,
 	exchangeRate decimal.Decimal,
 	exchangeDate time.Time,
+	minorUnits int32,
 ) (*CurrencyConversion, error) {
 	if err := validateExchangeDate(tx.TransactionDate, exchangeDate); err != nil {
 		return nil, err
 	}
 
-	convertedAmount := tx.AmountUSD.Mul(exchangeRate).Round(4)
+	convertedAmount := tx.AmountUSD.Mul(exchangeRate).Round(minorUnits)
 
 	return &CurrencyConversion{
 		TransactionID:   tx.ID,
@@ -58,6 +58,7 @@ func TestNewCurrencyConversion(t *testing.T) {
 				tt.targetCurrency,
 				tt.exchangeRate,
 				tt.exchangeDate,
+				2,
 			)
 
 			if tt.wantErr {
@@ -71,3 +72,31 @@ func TestNewCurrencyConversion(t *testing.T) {
 		})
 	}
 }
+
+func TestNewCurrencyConversion_MinorUnits(t *testing.T) {
+	tx := &Transaction{
+		ID:              uuid.New(),
+		Description:     "Test Transaction",
+		TransactionDate: time.Now(),
+		AmountUSD:       decimal.RequireFromString("100.77"),
+	}
+
+	tests := []struct {
+		currency   string
+		rate       string
+		minorUnits int32
+		want       string
+	}{
+		{currency: "EUR", rate: "0.8333", minorUnits: 2, want: "83.97"},
+		{currency: "JPY", rate: "149.5312", minorUnits: 0, want: "15068"},
+		{currency: "KWD", rate: "0.3071", minorUnits: 3, want: "30.946"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.currency, func(t *testing.T) {
+			conversion, err := NewCurrencyConversion(tx, tt.currency, decimal.RequireFromString(tt.rate), tx.TransactionDate, tt.minorUnits)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, conversion.ConvertedAmount.String())
+		})
+	}
+}
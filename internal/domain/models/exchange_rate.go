@@ -0,0 +1,45 @@
+package models
+
+import "time"
+
+// RateSource tells how a stored exchange rate was obtained.
+type RateSource string
+
+const (
+	// RateSourceSync rates were pulled by the sync job.
+	RateSourceSync RateSource = "sync"
+	// RateSourceLive rates were fetched during a conversion to fill a gap in
+	// the synced ones.
+	RateSourceLive RateSource = "live"
+)
+
+// RateSync is a run of the exchange rate sync job.
+type RateSync struct {
+	ID         int64     `db:"id" json:"id"`
+	StartedAt  time.Time `db:"started_at" json:"started_at"`
+	FinishedAt time.Time `db:"finished_at" json:"finished_at"`
+	// Since is the record date the run fetched from.
+	Since time.Time `db:"since" json:"since"`
+	// Fetched counts the records returned by Treasury, Stored the new ones.
+	Fetched int `db:"fetched" json:"fetched"`
+	Stored  int `db:"stored" json:"stored"`
+}
+
+// RateCoverage is the range of record dates stored for a Treasury currency
+// descriptor.
+type RateCoverage struct {
+	Currency         string    `db:"currency" json:"currency"`
+	FirstRecordDate  time.Time `db:"first_record_date" json:"first_record_date"`
+	LatestRecordDate time.Time `db:"latest_record_date" json:"latest_record_date"`
+}
+
+// SupportedCurrency is a currency conversions can target. Code and Country
+// come from the currency catalog; Code is empty for descriptors the catalog
+// does not know.
+type SupportedCurrency struct {
+	Code             string    `json:"code,omitempty"`
+	Descriptor       string    `json:"descriptor"`
+	Country          string    `json:"country"`
+	FirstRecordDate  time.Time `json:"first_record_date"`
+	LatestRecordDate time.Time `json:"latest_record_date"`
+}
@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+type IdempotencyStatus string
+
+const (
+	IdempotencyInProgress IdempotencyStatus = "IN_PROGRESS"
+	IdempotencyCompleted  IdempotencyStatus = "COMPLETED"
+)
+
+// IdempotencyRecord holds the outcome of a request made with an Idempotency-Key.
+type IdempotencyRecord struct {
+	Key          string            `db:"key" json:"key"`
+	Fingerprint  string            `db:"fingerprint" json:"fingerprint"`
+	Status       IdempotencyStatus `db:"status" json:"status"`
+	ResponseCode int               `db:"response_code" json:"response_code"`
+	ResponseBody []byte            `db:"response_body" json:"response_body"`
+	CreatedAt    time.Time         `db:"created_at" json:"created_at"`
+	ExpiresAt    time.Time         `db:"expires_at" json:"expires_at"`
+}
+
+// Expired reports whether the record may be discarded and its key reused.
+func (r *IdempotencyRecord) Expired(now time.Time) bool {
+	return !now.Before(r.ExpiresAt)
+}
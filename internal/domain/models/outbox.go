@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// OutboxMessage is an event stored alongside the change that produced it,
+// waiting to be relayed to the message broker.
+type OutboxMessage struct {
+	ID          int64      `db:"id" json:"id"`
+	AggregateID uuid.UUID  `db:"aggregate_id" json:"aggregate_id"`
+	EventType   string     `db:"event_type" json:"event_type"`
+	Payload     []byte     `db:"payload" json:"payload"`
+	CreatedAt   time.Time  `db:"created_at" json:"created_at"`
+	SentAt      *time.Time `db:"sent_at" json:"sent_at"`
+	Attempts    int        `db:"attempts" json:"attempts"`
+}
@@ -1,6 +1,8 @@
 package models
 
 import (
+	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
@@ -16,8 +18,44 @@ const (
 	StatusProcessing TransactionStatus = "PROCESSING"
 	StatusCompleted  TransactionStatus = "COMPLETED"
 	StatusFailed     TransactionStatus = "FAILED"
+	StatusVoided     TransactionStatus = "VOIDED"
 )
 
+// statusTransitions is the transaction lifecycle graph. A status may only move
+// to one of the statuses listed under it; FAILED may be retried by moving it
+// back into PROCESSING. Any settled transaction may be voided, which is final.
+var statusTransitions = map[TransactionStatus][]TransactionStatus{
+	StatusPending:    {StatusProcessing, StatusVoided},
+	StatusProcessing: {StatusCompleted, StatusFailed},
+	StatusCompleted:  {StatusVoided},
+	StatusFailed:     {StatusProcessing, StatusVoided},
+	StatusVoided:     {},
+}
+
+// IsValid reports whether the status is part of the transaction lifecycle.
+func (s TransactionStatus) IsValid() bool {
+	_, ok := statusTransitions[s]
+	return ok
+}
+
+// NextStatuses returns the statuses reachable from s in a single transition.
+func (s TransactionStatus) NextStatuses() []TransactionStatus {
+	next := statusTransitions[s]
+	out := make([]TransactionStatus, len(next))
+	copy(out, next)
+	return out
+}
+
+// CanTransitionTo reports whether s may move directly to next.
+func (s TransactionStatus) CanTransitionTo(next TransactionStatus) bool {
+	for _, allowed := range statusTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
 type Transaction struct {
 	ID              uuid.UUID         `db:"id" json:"id"`
 	Description     string            `db:"description" json:"description"`
@@ -26,6 +64,40 @@ type Transaction struct {
 	CreatedAt       time.Time         `db:"createdat" json:"createdat"`
 	ProcessedAt     *time.Time        `db:"processedat" json:"processedat"`
 	Status          TransactionStatus `db:"status" json:"status"`
+	VoidReason      *string           `db:"voidreason" json:"voidreason,omitempty"`
+	VoidedAt        *time.Time        `db:"voidedat" json:"voidedat,omitempty"`
+	Version         int               `db:"version" json:"version"`
+	// FailedStep and FailureReason explain why processing last failed. They
+	// are cleared once the transaction completes.
+	FailedStep    *string `db:"failedstep" json:"failedstep,omitempty"`
+	FailureReason *string `db:"failurereason" json:"failurereason,omitempty"`
+}
+
+// NextStatuses returns the statuses the transaction may move to from its current status.
+func (t *Transaction) NextStatuses() []TransactionStatus {
+	return t.Status.NextStatuses()
+}
+
+// TransitionTo moves the transaction to the given status if the lifecycle allows it.
+func (t *Transaction) TransitionTo(next TransactionStatus) error {
+	if !next.IsValid() {
+		return errors.ErrInvalidStatus
+	}
+	if !t.Status.CanTransitionTo(next) {
+		return errors.ErrInvalidTransition
+	}
+	t.Status = next
+	return nil
+}
+
+// IsEditable reports whether description, date and amount may still be changed.
+func (t *Transaction) IsEditable() bool {
+	return t.Status == StatusPending
+}
+
+// ETag is the entity tag identifying the current version of the transaction.
+func (t *Transaction) ETag() string {
+	return fmt.Sprintf("%q", strconv.Itoa(t.Version))
 }
 
 func (t *Transaction) Standardize() {
@@ -37,6 +109,9 @@ func (t *Transaction) Standardize() {
 	if t.ProcessedAt != nil {
 		*t.ProcessedAt = t.ProcessedAt.UTC()
 	}
+	if t.VoidedAt != nil {
+		*t.VoidedAt = t.VoidedAt.UTC()
+	}
 }
 
 func (t *Transaction) Validate() error {
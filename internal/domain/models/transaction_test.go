@@ -0,0 +1,48 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/Athla/vr-software-challenge/internal/domain/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransaction_TransitionTo(t *testing.T) {
+	tests := []struct {
+		name          string
+		from          TransactionStatus
+		to            TransactionStatus
+		expectedError error
+	}{
+		{name: "Pending to processing", from: StatusPending, to: StatusProcessing},
+		{name: "Processing to completed", from: StatusProcessing, to: StatusCompleted},
+		{name: "Processing to failed", from: StatusProcessing, to: StatusFailed},
+		{name: "Retry from failed", from: StatusFailed, to: StatusProcessing},
+		{name: "Void completed", from: StatusCompleted, to: StatusVoided},
+		{name: "Void while processing", from: StatusProcessing, to: StatusVoided, expectedError: errors.ErrInvalidTransition},
+		{name: "Voided is final", from: StatusVoided, to: StatusPending, expectedError: errors.ErrInvalidTransition},
+		{name: "Pending to completed", from: StatusPending, to: StatusCompleted, expectedError: errors.ErrInvalidTransition},
+		{name: "Completed to pending", from: StatusCompleted, to: StatusPending, expectedError: errors.ErrInvalidTransition},
+		{name: "Unknown status", from: StatusPending, to: TransactionStatus("DONE"), expectedError: errors.ErrInvalidStatus},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tx := &Transaction{Status: tt.from}
+			err := tx.TransitionTo(tt.to)
+
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+				assert.Equal(t, tt.from, tx.Status)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.to, tx.Status)
+			}
+		})
+	}
+}
+
+func TestTransactionStatus_NextStatuses(t *testing.T) {
+	assert.Equal(t, []TransactionStatus{StatusCompleted, StatusFailed}, StatusProcessing.NextStatuses())
+	assert.Empty(t, StatusVoided.NextStatuses())
+}
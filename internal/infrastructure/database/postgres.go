@@ -36,8 +36,10 @@ func NewConnection(cfg config.DatabaseConfig) (*sql.DB, error) {
 	return db, nil
 }
 
-func Transaction(db *sql.DB, fn func(*sql.Tx) error) error {
-	tx, err := db.BeginTx(context.Background(), &sql.TxOptions{
+// Transaction runs fn inside a read-committed database transaction, committing
+// when fn succeeds and rolling back otherwise.
+func Transaction(ctx context.Context, db *sql.DB, fn func(*sql.Tx) error) error {
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{
 		Isolation: sql.LevelReadCommitted,
 		ReadOnly:  false,
 	})
@@ -0,0 +1,141 @@
+package messagery
+
+import (
+	"context"
+	go_errors "errors"
+	"fmt"
+	"time"
+)
+
+// Supported broker implementations.
+const (
+	BrokerKafka  = "kafka"
+	BrokerMemory = "memory"
+)
+
+// TopicPartition identifies a partition of a topic.
+type TopicPartition struct {
+	Topic     string
+	Partition int32
+}
+
+func (tp TopicPartition) String() string {
+	return fmt.Sprintf("%s[%d]", tp.Topic, tp.Partition)
+}
+
+// Record is a message read from a broker.
+type Record struct {
+	TopicPartition
+	Offset    int64
+	Key       []byte
+	Value     []byte
+	Headers   map[string]string
+	Timestamp time.Time
+}
+
+func (r *Record) String() string {
+	return fmt.Sprintf("%s@%d", r.TopicPartition, r.Offset)
+}
+
+// RebalanceEvent reports partitions assigned to or revoked from a subscriber.
+type RebalanceEvent struct {
+	Assigned []TopicPartition
+	Revoked  []TopicPartition
+}
+
+// RebalanceCallback is called from within Poll whenever the subscriber's
+// assignment changes. Revoked partitions are still owned, and can still be
+// committed, until the callback returns.
+type RebalanceCallback func(RebalanceEvent)
+
+// Subscriber is the consuming side of a broker: a member of a consumer group
+// reading the partitions assigned to it.
+type Subscriber interface {
+	Subscribe(topics []string, callback RebalanceCallback) error
+	// Poll returns the next record, or nil when none arrived within timeout.
+	// Errors marked with Fatal mean the subscriber cannot be used anymore;
+	// others are transient and polling again may succeed.
+	Poll(timeout time.Duration) (*Record, error)
+	// Commit stores offset as the next offset the group reads from tp.
+	Commit(tp TopicPartition, offset int64) error
+	Pause(tp TopicPartition) error
+	Resume(tp TopicPartition) error
+	Seek(tp TopicPartition, offset int64) error
+	Close() error
+}
+
+type fatalError struct {
+	err error
+}
+
+func (e *fatalError) Error() string { return e.err.Error() }
+func (e *fatalError) Unwrap() error { return e.err }
+
+// Fatal marks a subscriber error as unrecoverable: the consumer stops.
+func Fatal(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &fatalError{err: err}
+}
+
+// IsFatal reports whether err was marked with Fatal.
+func IsFatal(err error) bool {
+	var f *fatalError
+	return go_errors.As(err, &f)
+}
+
+// ErrTopicNotFound is returned when inspecting a topic the broker does not have.
+var ErrTopicNotFound = go_errors.New("topic not found")
+
+// PartitionInfo describes a partition as reported by the broker metadata.
+// Leader is the ID of the broker leading the partition, or -1 when it has none.
+type PartitionInfo struct {
+	Partition int32 `json:"partition"`
+	Leader    int32 `json:"leader"`
+}
+
+// PartitionLag compares the committed offset of a consumer group with the end
+// of a partition. Committed is -1 when the group has not committed anything,
+// in which case the whole partition counts as lag.
+type PartitionLag struct {
+	Partition     int32 `json:"partition"`
+	Committed     int64 `json:"committed"`
+	HighWatermark int64 `json:"high_watermark"`
+	Lag           int64 `json:"lag"`
+}
+
+// Inspector reads the state of topics and of a consumer group without
+// producing, consuming or joining the group.
+type Inspector interface {
+	Partitions(ctx context.Context, topic string) ([]PartitionInfo, error)
+	Lag(ctx context.Context, topic string) ([]PartitionLag, error)
+	Close() error
+}
+
+// Broker creates the producers, subscribers and inspectors of one messaging backend.
+type Broker interface {
+	// CreateTopics creates the topics the broker does not have yet.
+	CreateTopics(ctx context.Context, topics ...string) error
+	NewProducer(topic string) (Producerer, error)
+	NewSubscriber(groupID string) (Subscriber, error)
+	NewInspector(groupID string) (Inspector, error)
+}
+
+// lag computes the lag of a partition spanning [low, high).
+func lag(partition int32, committed, low, high int64) PartitionLag {
+	if committed < 0 {
+		committed = -1
+	}
+
+	l := PartitionLag{Partition: partition, Committed: committed, HighWatermark: high}
+	if committed < low {
+		l.Lag = high - low
+	} else {
+		l.Lag = high - committed
+	}
+	if l.Lag < 0 {
+		l.Lag = 0
+	}
+	return l
+}
@@ -0,0 +1,283 @@
+package messagery
+
+import (
+	"context"
+	go_errors "errors"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/Athla/vr-software-challenge/config"
+	"github.com/charmbracelet/log"
+)
+
+// Codec encodes events with the serializer chosen for their topic and stamps
+// the registry ID of their data schema, and decodes them back whatever
+// serializer wrote them.
+type Codec struct {
+	registry    SchemaRegistry
+	serializers map[string]Serializer
+	fallback    Serializer
+
+	mu      sync.Mutex
+	ids     map[string]int
+	schemas map[int]*Schema
+}
+
+type CodecOption func(*Codec)
+
+// WithTopicSerializer encodes the events published to topic with s.
+func WithTopicSerializer(topic string, s Serializer) CodecOption {
+	return func(c *Codec) {
+		c.serializers[topic] = s
+	}
+}
+
+// WithDefaultSerializer encodes the events of topics without their own
+// serializer with s, instead of JSON.
+func WithDefaultSerializer(s Serializer) CodecOption {
+	return func(c *Codec) {
+		c.fallback = s
+	}
+}
+
+func NewCodec(registry SchemaRegistry, opts ...CodecOption) *Codec {
+	c := &Codec{
+		registry:    registry,
+		serializers: map[string]Serializer{},
+		fallback:    JSONSerializer{},
+		ids:         map[string]int{},
+		schemas:     map[int]*Schema{},
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// NewCodecFromConfig builds a codec with the default and per-topic serializers
+// of cfg.
+func NewCodecFromConfig(registry SchemaRegistry, cfg config.KafkaConfig) (*Codec, error) {
+	fallback, err := NewSerializer(cfg.Serializer)
+	if err != nil {
+		return nil, err
+	}
+	opts := []CodecOption{WithDefaultSerializer(fallback)}
+
+	for topic, name := range cfg.TopicSerializers {
+		s, err := NewSerializer(name)
+		if err != nil {
+			return nil, fmt.Errorf("topic %s: %w", topic, err)
+		}
+		opts = append(opts, WithTopicSerializer(topic, s))
+	}
+
+	return NewCodec(registry, opts...), nil
+}
+
+// RegisterSchemas registers the current data schema of every event type, so
+// an incompatible change fails at startup rather than on the first message.
+func (c *Codec) RegisterSchemas(ctx context.Context) error {
+	for _, schema := range EventSchemas() {
+		if _, err := c.schemaID(ctx, schema); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Codec) serializer(topic string) Serializer {
+	if s, ok := c.serializers[topic]; ok {
+		return s
+	}
+	return c.fallback
+}
+
+// Encode writes the event for topic with the topic's serializer.
+func (c *Codec) Encode(ctx context.Context, topic string, e *Event) (*Message, error) {
+	schema, ok := eventSchemas[e.Type]
+	if !ok || e.DataVersion != eventVersions[e.Type] {
+		return nil, fmt.Errorf("no schema for %s v%d", e.Type, e.DataVersion)
+	}
+	id, err := c.schemaID(ctx, schema)
+	if err != nil {
+		return nil, err
+	}
+
+	s := c.serializer(topic)
+	value, err := s.Marshal(e, schema)
+	if err != nil {
+		return nil, err
+	}
+
+	headers := e.headers()
+	headers[HeaderContentType] = s.ContentType()
+	headers[HeaderSchemaID] = strconv.Itoa(id)
+
+	return &Message{
+		Topic:   topic,
+		Key:     e.Subject,
+		Value:   value,
+		Headers: headers,
+	}, nil
+}
+
+// Decode reads the event carried by a record and upcasts its data to the
+// current version. Records without a Protobuf content type are JSON, enveloped
+// or not, and decoded as DecodeEvent does. Errors are Permanent, except when
+// the schema registry could not be read.
+func (c *Codec) Decode(ctx context.Context, r *Record) (*Event, error) {
+	if r.Headers[HeaderContentType] != ProtobufContentType {
+		event, err := DecodeEvent(r)
+		return event, Permanent(err)
+	}
+
+	id, err := strconv.Atoi(r.Headers[HeaderSchemaID])
+	if err != nil {
+		return nil, Permanent(fmt.Errorf("decode event: invalid %s header %q", HeaderSchemaID, r.Headers[HeaderSchemaID]))
+	}
+	schema, err := c.schema(ctx, id)
+	if go_errors.Is(err, ErrSchemaNotFound) {
+		return nil, Permanent(err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("look up schema %d: %w", id, err)
+	}
+
+	event, err := ProtobufSerializer{}.Unmarshal(r.Value, schema)
+	if err != nil {
+		return nil, Permanent(err)
+	}
+	if err := upcast(event); err != nil {
+		return nil, Permanent(err)
+	}
+
+	return event, nil
+}
+
+// schemaID registers schema once per process and remembers its ID.
+func (c *Codec) schemaID(ctx context.Context, schema *Schema) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if id, ok := c.ids[schema.Subject]; ok {
+		return id, nil
+	}
+
+	id, err := c.registry.Register(ctx, schema)
+	if err != nil {
+		log.Errorf("Unable to register schema %s due: %v", schema.Subject, err)
+		return 0, err
+	}
+	c.ids[schema.Subject] = id
+	c.schemas[id] = schema
+
+	return id, nil
+}
+
+// schema looks a schema up once per process; registered schemas never change.
+func (c *Codec) schema(ctx context.Context, id int) (*Schema, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if schema, ok := c.schemas[id]; ok {
+		return schema, nil
+	}
+
+	schema, err := c.registry.Schema(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	c.schemas[id] = schema
+
+	return schema, nil
+}
+
+// SerializingProducer re-encodes the JSON events published through it with
+// the codec, using the serializer of their topic. Messages that are not JSON
+// events, such as retried records already encoded by another serializer, are
+// published as they are.
+type SerializingProducer struct {
+	Producerer
+	codec *Codec
+	topic string
+}
+
+// NewSerializingProducer wraps producer, whose default topic is topic.
+func NewSerializingProducer(producer Producerer, topic string, codec *Codec) *SerializingProducer {
+	return &SerializingProducer{
+		Producerer: producer,
+		codec:      codec,
+		topic:      topic,
+	}
+}
+
+func (p *SerializingProducer) PublishTransaction(ctx context.Context, msg *TransactionMessage) error {
+	message, err := newTransactionCreatedMessage(msg)
+	if err != nil {
+		return err
+	}
+
+	return p.Publish(ctx, message)
+}
+
+func (p *SerializingProducer) Publish(ctx context.Context, msg *Message) error {
+	return p.PublishBatch(ctx, []*Message{msg})
+}
+
+func (p *SerializingProducer) PublishBatch(ctx context.Context, msgs []*Message) error {
+	encoded := make([]*Message, len(msgs))
+	for i, msg := range msgs {
+		m, err := p.encode(ctx, msg)
+		if err != nil {
+			log.Errorf("Unable to encode message due: %v", err)
+			return err
+		}
+		encoded[i] = m
+	}
+
+	return p.Producerer.PublishBatch(ctx, encoded)
+}
+
+// encode re-encodes a JSON event, keeping the headers the codec does not set.
+func (p *SerializingProducer) encode(ctx context.Context, msg *Message) (*Message, error) {
+	if msg.Headers[HeaderContentType] != EventContentType {
+		return msg, nil
+	}
+
+	event, err := JSONSerializer{}.Unmarshal(msg.Value, nil)
+	if err != nil {
+		return nil, err
+	}
+	// Outbox rows written before a version bump are published as the
+	// current version, the only one with a schema.
+	if err := upcast(event); err != nil {
+		return nil, err
+	}
+
+	topic := msg.Topic
+	if topic == "" {
+		topic = p.topic
+	}
+	encoded, err := p.codec.Encode(ctx, topic, event)
+	if err != nil {
+		return nil, err
+	}
+
+	headers := make(map[string]string, len(msg.Headers)+len(encoded.Headers))
+	for k, v := range msg.Headers {
+		headers[k] = v
+	}
+	for k, v := range encoded.Headers {
+		headers[k] = v
+	}
+
+	return &Message{
+		Topic:   msg.Topic,
+		Key:     msg.Key,
+		Value:   encoded.Value,
+		Headers: headers,
+	}, nil
+}
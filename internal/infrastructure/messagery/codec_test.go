@@ -0,0 +1,107 @@
+package messagery
+
+import (
+	"context"
+	go_errors "errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// failingRegistry stands for a registry that cannot be reached.
+type failingRegistry struct{}
+
+func (failingRegistry) Register(context.Context, *Schema) (int, error) {
+	return 0, go_errors.New("connection refused")
+}
+
+func (failingRegistry) Schema(context.Context, int) (*Schema, error) {
+	return nil, go_errors.New("connection refused")
+}
+
+func TestSerializingProducerAndConsumer(t *testing.T) {
+	ctx := context.Background()
+	broker := NewMemoryBroker(1)
+	registry := NewMemorySchemaRegistry()
+	codec := NewCodec(registry, WithTopicSerializer("transactions", ProtobufSerializer{}))
+	require.NoError(t, codec.RegisterSchemas(ctx))
+
+	raw, _ := broker.NewProducer("transactions")
+	producer := NewSerializingProducer(raw, "transactions", codec)
+
+	id := uuid.New()
+	publishCreated(t, producer, id, "protobuf")
+	event, _ := NewEvent(EventTransactionCreated, id.String(), TransactionCreated{ID: id, Description: "json"})
+	msg, _ := event.Message()
+	msg.Topic = "transactions.json"
+	msg.Headers[HeaderAttempts] = "2"
+	require.NoError(t, producer.Publish(ctx, msg))
+
+	records := broker.Messages("transactions")
+	require.Len(t, records, 1)
+	assert.Equal(t, ProtobufContentType, records[0].Headers[HeaderContentType])
+	assert.Equal(t, EventTransactionCreated, records[0].Headers[HeaderEventType])
+	schemaID, err := strconv.Atoi(records[0].Headers[HeaderSchemaID])
+	require.NoError(t, err)
+	schema, err := registry.Schema(ctx, schemaID)
+	require.NoError(t, err)
+	assert.Equal(t, "transaction.created.v2", schema.Subject)
+
+	others := broker.Messages("transactions.json")
+	require.Len(t, others, 1)
+	assert.Equal(t, EventContentType, others[0].Headers[HeaderContentType], "other topics stay JSON")
+	assert.Equal(t, records[0].Headers[HeaderSchemaID], others[0].Headers[HeaderSchemaID])
+	assert.Equal(t, "2", others[0].Headers[HeaderAttempts], "headers the codec does not set are kept")
+
+	// A consumer with its own codec reads both, looking the schema up by ID.
+	subscriber, _ := broker.NewSubscriber("group")
+	handler := &gatedHandler{handled: map[uuid.UUID][]string{}}
+	dispatcher := NewDispatcher()
+	handler.register(dispatcher)
+	consumer := NewConsumer(subscriber, "transactions", dispatcher, WithCodec(NewCodec(registry)))
+
+	runCtx, cancel := context.WithCancel(ctx)
+	done := make(chan error)
+	go func() { done <- consumer.Start(runCtx) }()
+	assert.Eventually(t, func() bool { return handler.count() == 1 }, 2*time.Second, 10*time.Millisecond)
+	cancel()
+	require.NoError(t, <-done)
+	consumer.Close()
+	assert.Equal(t, []string{"protobuf"}, handler.handled[id])
+
+	decoded, err := NewCodec(registry).Decode(ctx, &others[0])
+	require.NoError(t, err)
+	assert.Equal(t, event.ID, decoded.ID)
+}
+
+func TestCodecDecodeErrors(t *testing.T) {
+	ctx := context.Background()
+	registry := NewMemorySchemaRegistry()
+	codec := NewCodec(registry, WithDefaultSerializer(ProtobufSerializer{}))
+
+	id := uuid.New()
+	event, _ := NewEvent(EventTransactionCreated, id.String(), TransactionCreated{ID: id})
+	msg, err := codec.Encode(ctx, "transactions", event)
+	require.NoError(t, err)
+	record := &Record{Key: []byte(msg.Key), Value: msg.Value, Headers: msg.Headers}
+
+	unknown := *record
+	unknown.Headers = map[string]string{HeaderContentType: ProtobufContentType, HeaderSchemaID: "42"}
+	_, err = codec.Decode(ctx, &unknown)
+	assert.ErrorIs(t, err, ErrSchemaNotFound)
+	assert.True(t, IsPermanent(err), "an unknown schema never resolves")
+
+	_, err = NewCodec(failingRegistry{}).Decode(ctx, record)
+	assert.Error(t, err)
+	assert.False(t, IsPermanent(err), "an unreachable registry is retried")
+
+	_, err = codec.Decode(ctx, &Record{Value: []byte("not json")})
+	assert.True(t, IsPermanent(err))
+
+	_, err = NewCodec(failingRegistry{}).Encode(ctx, "transactions", event)
+	assert.ErrorContains(t, err, "connection refused")
+}
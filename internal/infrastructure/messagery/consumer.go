@@ -2,88 +2,419 @@ package messagery
 
 import (
 	"context"
-	"strings"
+	"hash/fnv"
+	"sync"
 	"time"
 
-	"encoding/json"
-
 	"github.com/charmbracelet/log"
-	"github.com/confluentinc/confluent-kafka-go/kafka"
 )
 
-type MessageHandler func(context.Context, *TransactionMessage) error
-
+// Consumer reads a topic and its retry tiers and hands the events to a pool of
+// workers. Records with the same key always go to the same worker, so events
+// of a transaction are handled in order while different transactions are
+// handled in parallel. Offsets are committed once every earlier record of the
+// partition is done.
 type Consumer struct {
-	consumer *kafka.Consumer
-	handler  MessageHandler
-	topic    string
+	subscriber Subscriber
+	dispatcher *Dispatcher
+	topic      string
+	codec      *Codec
+
+	workers int
+	queues  []chan *Record
+	results chan processed
+	offsets map[TopicPartition]*offsetTracker
+
+	deadLetter      Producerer
+	deadLetterTopic string
+
+	retry         Producerer
+	retryPolicy   RetryPolicy
+	delayedTopics map[string]bool
+	paused        map[TopicPartition]time.Time
 }
 
-func NewConsumer(brokers []string, groupID, topic string, handler MessageHandler) (*Consumer, error) {
-	c, err := kafka.NewConsumer(&kafka.ConfigMap{
-		"bootstrap.servers":    strings.Join(brokers, ","),
-		"group.id":             groupID,
-		"auto.offset.reset":    "earliest",
-		"enable.auto.commit":   false,
-		"max.poll.interval.ms": 300000,
-		"session.timeout.ms":   45000,
-	})
+// ConsumerOption configures optional Consumer behaviour.
+type ConsumerOption func(*Consumer)
 
-	if err != nil {
-		log.Errorf("Unable to create consumer due: %s", err)
-		return nil, err
+// WithDeadLetterTopic makes the consumer publish messages it cannot process to
+// topic through producer before committing them.
+func WithDeadLetterTopic(producer Producerer, topic string) ConsumerOption {
+	return func(c *Consumer) {
+		c.deadLetter = producer
+		c.deadLetterTopic = topic
+	}
+}
+
+// WithRetry makes the consumer move failed messages through the retry tiers of
+// policy, publishing them with producer, and also consume those tiers.
+func WithRetry(producer Producerer, policy RetryPolicy) ConsumerOption {
+	return func(c *Consumer) {
+		c.retry = producer
+		c.retryPolicy = policy
+		for _, topic := range policy.Topics() {
+			c.delayedTopics[topic] = true
+		}
+	}
+}
+
+// WithCodec makes the consumer decode records through codec, so events written
+// with a serializer other than JSON can be read. Without it only JSON is read.
+func WithCodec(codec *Codec) ConsumerOption {
+	return func(c *Consumer) {
+		c.codec = codec
+	}
+}
+
+// WithWorkers sets how many records the consumer handles at once. It defaults to 1.
+func WithWorkers(n int) ConsumerOption {
+	return func(c *Consumer) {
+		if n > 0 {
+			c.workers = n
+		}
 	}
+}
+
+// workerBacklog is how many records may wait for each worker before polling
+// blocks until one of them is done.
+const workerBacklog = 16
 
-	return &Consumer{
-		consumer: c,
-		handler:  handler,
-		topic:    topic,
-	}, nil
+// processed reports that a worker is done with a record. Handled is false when
+// the record was neither handled nor handed on and must be read again.
+type processed struct {
+	record  *Record
+	handled bool
 }
 
+// republishBackoff is how long the consumer waits before retrying a failed
+// publish to a retry or dead-letter topic.
+const republishBackoff = time.Second
+
+// NewConsumer creates a consumer of topic reading through subscriber, which
+// must not have subscribed yet, and handing events to dispatcher.
+func NewConsumer(subscriber Subscriber, topic string, dispatcher *Dispatcher, opts ...ConsumerOption) *Consumer {
+	consumer := &Consumer{
+		subscriber:    subscriber,
+		dispatcher:    dispatcher,
+		topic:         topic,
+		workers:       1,
+		results:       make(chan processed),
+		offsets:       map[TopicPartition]*offsetTracker{},
+		delayedTopics: map[string]bool{},
+		paused:        map[TopicPartition]time.Time{},
+	}
+	for _, opt := range opts {
+		opt(consumer)
+	}
+
+	return consumer
+}
+
+// Start polls until ctx is cancelled. On return every worker has finished and
+// the records done so far are committed. The subscriber is only used from the
+// calling goroutine; workers report back through the results channel.
 func (c *Consumer) Start(ctx context.Context) error {
-	if err := c.consumer.SubscribeTopics([]string{c.topic}, nil); err != nil {
+	topics := append([]string{c.topic}, c.retryPolicy.Topics()...)
+	if err := c.subscriber.Subscribe(topics, c.rebalance); err != nil {
 		log.Errorf("Unable to subscribe to topics due: %s", err)
 		return err
 	}
 
+	var wg sync.WaitGroup
+	c.queues = make([]chan *Record, c.workers)
+	for i := range c.queues {
+		c.queues[i] = make(chan *Record, workerBacklog)
+		wg.Add(1)
+		go func(queue <-chan *Record) {
+			defer wg.Done()
+			c.work(ctx, queue)
+		}(c.queues[i])
+	}
+	defer c.stop(&wg)
+
 	for {
 		select {
 		case <-ctx.Done():
 			return nil
+		case result := <-c.results:
+			c.complete(result)
 		default:
-			msg, err := c.consumer.ReadMessage(100 * time.Millisecond)
+			c.resumeDue()
+
+			record, err := c.subscriber.Poll(100 * time.Millisecond)
+			if IsFatal(err) {
+				log.Errorf("Unable to read message from consumer due: %s", err)
+				return err
+			}
 			if err != nil {
-				if err.(kafka.Error).IsFatal() {
-					log.Errorf("FATAL ERROR. Unable to read from consumer due: %s", err)
-					return err
-				}
-				if err.(kafka.Error).Code() != kafka.ErrTimedOut {
-					log.Warnf("Unable to read message from consumer due: %s", err)
-					return err
-				}
+				log.Warnf("Unable to read message from consumer due: %s", err)
 				continue
-
 			}
-			var transaction TransactionMessage
-			if err := json.Unmarshal(msg.Value, &transaction); err != nil {
-				log.Warnf("Unable to unmarshal error due: %s", err)
+			if record == nil {
 				continue
 			}
-			if err := c.handler(ctx, &transaction); err != nil {
-				log.Warnf("Unable to handle message due: %s", err)
+
+			// Messages still buffered for a paused partition are read again
+			// once it resumes.
+			if _, ok := c.paused[record.TopicPartition]; ok {
 				continue
 			}
 
-			if _, err = c.consumer.CommitMessage(msg); err != nil {
-				log.Warnf("Unable to commit message due: %s", err)
+			// Retry topics hold messages whose delay may not have elapsed yet.
+			// Their partition is paused until it has, keeping the poll loop,
+			// and with it the group membership, alive meanwhile.
+			if c.delayedTopics[record.Topic] && time.Now().Before(notBefore(record)) && c.delay(record) {
 				continue
 			}
 
+			c.dispatch(ctx, record)
+		}
+	}
+}
+
+// dispatch queues a record for the worker owning its key. While that worker is
+// busy the results of the others are collected, so commits keep advancing.
+func (c *Consumer) dispatch(ctx context.Context, record *Record) {
+	queue := c.queues[c.worker(record)]
+	for {
+		select {
+		case queue <- record:
+			tracker, ok := c.offsets[record.TopicPartition]
+			if !ok {
+				tracker = newOffsetTracker()
+				c.offsets[record.TopicPartition] = tracker
+			}
+			tracker.add(record.Offset)
+			return
+		case result := <-c.results:
+			c.complete(result)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// worker returns the index of the worker handling record. Records without a
+// key have no order to keep and are spread by offset.
+func (c *Consumer) worker(record *Record) int {
+	if len(record.Key) == 0 {
+		return int(record.Offset % int64(c.workers))
+	}
+
+	h := fnv.New32a()
+	h.Write(record.Key)
+	return int(h.Sum32() % uint32(c.workers))
+}
+
+// work handles the records of one queue in order. Records still queued once
+// ctx is cancelled are given back unhandled.
+func (c *Consumer) work(ctx context.Context, queue <-chan *Record) {
+	for record := range queue {
+		handled := false
+		if ctx.Err() == nil {
+			handled = c.process(ctx, record)
 		}
+		c.results <- processed{record: record, handled: handled}
+	}
+}
+
+// stop closes the worker queues and collects results until every worker has
+// returned.
+func (c *Consumer) stop(wg *sync.WaitGroup) {
+	for _, queue := range c.queues {
+		close(queue)
+	}
+
+	finished := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(finished)
+	}()
+
+	for {
+		select {
+		case result := <-c.results:
+			c.complete(result)
+		case <-finished:
+			return
+		}
+	}
+}
+
+// complete records a finished record and commits its partition up to the
+// first record that is not done yet.
+func (c *Consumer) complete(result processed) {
+	tp := result.record.TopicPartition
+	tracker, ok := c.offsets[tp]
+	if !ok {
+		return
+	}
+
+	if !result.handled {
+		log.Warnf("Message %s was not handled and will be read again", result.record)
+	}
+	if next, ok := tracker.complete(result.record.Offset, result.handled); ok {
+		c.commit(tp, next)
+	}
+}
+
+// process handles a single message. It reports whether the message has been
+// handled, moved to a retry tier or parked on the dead-letter topic, and may
+// therefore be committed.
+func (c *Consumer) process(ctx context.Context, record *Record) bool {
+	event, err := c.decode(ctx, record)
+	if err != nil {
+		log.Warnf("Unable to decode event due: %s", err)
+		return c.outcome(record, "unknown", c.fail(ctx, record, err))
+	}
+
+	handlerCtx := ctx
+	if IsReplay(record) {
+		handlerCtx = withReplay(ctx)
+	}
+
+	start := time.Now()
+	err = c.dispatcher.Dispatch(handlerCtx, event)
+	messageHandlerDuration.WithLabelValues(event.Type).Observe(time.Since(start).Seconds())
+	if err != nil {
+		log.Warnf("Unable to handle %s event due: %s", event.Type, err)
+		return c.outcome(record, event.Type, c.fail(ctx, record, err))
+	}
+
+	// A replayed event keeps the time of the original, so it would only
+	// measure how long ago the replay source was written.
+	if c.dispatcher.Handles(event.Type) && !event.Time.IsZero() && !IsReplay(record) {
+		messageEndToEndLatency.WithLabelValues(event.Type).Observe(time.Since(event.Time).Seconds())
+	}
+	return c.outcome(record, event.Type, outcomeHandled)
+}
+
+// decode reads the event of a record. Undecodable records are permanent
+// failures; a schema registry that cannot be reached is not.
+func (c *Consumer) decode(ctx context.Context, record *Record) (*Event, error) {
+	if c.codec == nil {
+		event, err := DecodeEvent(record)
+		return event, Permanent(err)
+	}
+	return c.codec.Decode(ctx, record)
+}
+
+// outcome counts a processed message and reports whether it may be committed.
+func (c *Consumer) outcome(record *Record, eventType, outcome string) bool {
+	messageProcessed.WithLabelValues(record.Topic, eventType, outcome).Inc()
+	return outcome != outcomeUnhandled
+}
+
+func (c *Consumer) commit(tp TopicPartition, offset int64) {
+	if err := c.subscriber.Commit(tp, offset); err != nil {
+		log.Warnf("Unable to commit message due: %s", err)
+	}
+}
+
+// fail routes a message that could not be processed to its next retry tier,
+// or parks it on the dead-letter topic when the error is permanent or the
+// attempts are exhausted, and returns the outcome. Without a retry or
+// dead-letter topic the message is left unhandled, and so uncommitted.
+func (c *Consumer) fail(ctx context.Context, record *Record, cause error) string {
+	now := time.Now()
+	failures := attempts(record) + 1
+
+	if c.retry != nil && !IsPermanent(cause) {
+		if tier, ok := c.retryPolicy.next(failures); ok {
+			if !c.republish(ctx, c.retry, newRetryMessage(record, tier, failures, now), record) {
+				return outcomeUnhandled
+			}
+			return outcomeRetried
+		}
+	}
+
+	if c.deadLetter == nil || !c.republish(ctx, c.deadLetter, newDeadLetterMessage(record, c.deadLetterTopic, cause, now), record) {
+		return outcomeUnhandled
+	}
+	return outcomeDeadLettered
+}
+
+// republish publishes out, retrying until it succeeds or ctx is cancelled, so a
+// message is never committed without having been handled or handed on.
+func (c *Consumer) republish(ctx context.Context, producer Producerer, out *Message, record *Record) bool {
+	for {
+		err := producer.PublishBatch(ctx, []*Message{out})
+		if err == nil {
+			log.Warnf("Moved message %s to %s", record, out.Topic)
+			return true
+		}
+		log.Errorf("Unable to publish to %s due: %s", out.Topic, err)
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(republishBackoff):
+		}
+	}
+}
+
+// delay pauses the partition of a retried message that is not due yet and
+// rewinds it to that message, so it is read again once the partition resumes.
+// When the partition cannot be paused the message is processed right away.
+func (c *Consumer) delay(record *Record) bool {
+	tp := record.TopicPartition
+	if err := c.subscriber.Pause(tp); err != nil {
+		log.Warnf("Unable to pause %s due: %s", tp, err)
+		return false
+	}
+	if err := c.subscriber.Seek(tp, record.Offset); err != nil {
+		log.Warnf("Unable to rewind %s due: %s", tp, err)
+	}
+	c.paused[tp] = notBefore(record)
+	return true
+}
+
+// resumeDue resumes the paused partitions whose next message is now due.
+func (c *Consumer) resumeDue() {
+	now := time.Now()
+	for tp, until := range c.paused {
+		if now.Before(until) {
+			continue
+		}
+
+		if err := c.subscriber.Resume(tp); err != nil {
+			log.Warnf("Unable to resume %s due: %s", tp, err)
+			continue
+		}
+		delete(c.paused, tp)
+	}
+}
+
+// rebalance waits for the workers to finish the records of revoked partitions
+// and commits them before the partitions are handed over, then forgets them;
+// their new owner reads on from the last committed offset.
+func (c *Consumer) rebalance(event RebalanceEvent) {
+	c.drain(event.Revoked)
+	for _, tp := range event.Revoked {
+		delete(c.paused, tp)
+		delete(c.offsets, tp)
+	}
+}
+
+// drain collects results until no worker is handling a record of partitions.
+func (c *Consumer) drain(partitions []TopicPartition) {
+	for {
+		busy := false
+		for _, tp := range partitions {
+			if tracker, ok := c.offsets[tp]; ok && tracker.busy() {
+				busy = true
+				break
+			}
+		}
+		if !busy {
+			return
+		}
+
+		c.complete(<-c.results)
 	}
 }
 
+// Close leaves the group. It must not be called before Start has returned.
 func (c *Consumer) Close() error {
-	return c.consumer.Close()
+	return c.subscriber.Close()
 }
@@ -0,0 +1,289 @@
+package messagery
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// gatedHandler records the order in which transactions are handled and blocks
+// on the ones in gates until their channel is closed.
+type gatedHandler struct {
+	mu      sync.Mutex
+	handled map[uuid.UUID][]string
+	gates   map[string]chan struct{}
+}
+
+func (h *gatedHandler) register(dispatcher *Dispatcher) {
+	dispatcher.Register(EventTransactionCreated, HandleData(func(ctx context.Context, _ *Event, msg *TransactionCreated) error {
+		if gate, ok := h.gates[msg.Description]; ok {
+			<-gate
+		}
+
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		h.handled[msg.ID] = append(h.handled[msg.ID], msg.Description)
+		return nil
+	}))
+}
+
+func (h *gatedHandler) count() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	n := 0
+	for _, descriptions := range h.handled {
+		n += len(descriptions)
+	}
+	return n
+}
+
+func publishCreated(t *testing.T, producer Producerer, id uuid.UUID, description string) {
+	event, err := NewEvent(EventTransactionCreated, id.String(), TransactionCreated{ID: id, Description: description})
+	require.NoError(t, err)
+	msg, err := event.Message()
+	require.NoError(t, err)
+	require.NoError(t, producer.Publish(context.Background(), msg))
+}
+
+func TestConsumerWorkersKeepKeyOrderAndCommitContiguously(t *testing.T) {
+	broker := NewMemoryBroker(1)
+	producer, _ := broker.NewProducer("transactions")
+	subscriber, _ := broker.NewSubscriber("group")
+	tp := TopicPartition{Topic: "transactions", Partition: 0}
+
+	slow := make(chan struct{})
+	handler := &gatedHandler{
+		handled: map[uuid.UUID][]string{},
+		gates:   map[string]chan struct{}{"slow": slow},
+	}
+	dispatcher := NewDispatcher()
+	handler.register(dispatcher)
+
+	consumer := NewConsumer(subscriber, "transactions", dispatcher, WithWorkers(4))
+
+	// The two transactions must not share a worker.
+	blocked, other := uuid.New(), uuid.New()
+	for consumer.worker(&Record{Key: []byte(blocked.String())}) == consumer.worker(&Record{Key: []byte(other.String())}) {
+		other = uuid.New()
+	}
+	publishCreated(t, producer, blocked, "slow")
+	publishCreated(t, producer, blocked, "after slow")
+	for i := 0; i < 5; i++ {
+		publishCreated(t, producer, other, "fast")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- consumer.Start(ctx) }()
+
+	assert.Eventually(t, func() bool { return handler.count() == 5 }, 2*time.Second, 10*time.Millisecond,
+		"other keys are handled while the slow one blocks")
+	assert.Equal(t, int64(-1), broker.Committed("group", tp), "nothing is committed past the slow record")
+
+	close(slow)
+	assert.Eventually(t, func() bool { return broker.Committed("group", tp) == 7 }, 2*time.Second, 10*time.Millisecond)
+
+	cancel()
+	require.NoError(t, <-done)
+	consumer.Close()
+
+	assert.Equal(t, []string{"slow", "after slow"}, handler.handled[blocked], "records of a key keep their order")
+}
+
+func TestConsumerDrainsRevokedPartitions(t *testing.T) {
+	broker := NewMemoryBroker(2)
+	producer, _ := broker.NewProducer("transactions")
+	first, _ := broker.NewSubscriber("group")
+
+	slow := make(chan struct{})
+	handler := &gatedHandler{
+		handled: map[uuid.UUID][]string{},
+		gates:   map[string]chan struct{}{"slow": slow},
+	}
+	dispatcher := NewDispatcher()
+	handler.register(dispatcher)
+
+	consumer := NewConsumer(first, "transactions", dispatcher, WithWorkers(2))
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- consumer.Start(ctx) }()
+
+	// Two transactions per partition, so both have records in flight.
+	var ids []uuid.UUID
+	perPartition := map[uint32]int{}
+	for len(ids) < 4 {
+		id := uuid.New()
+		h := fnv.New32a()
+		h.Write([]byte(id.String()))
+		if p := h.Sum32() % 2; perPartition[p] < 2 {
+			perPartition[p]++
+			ids = append(ids, id)
+		}
+	}
+	for _, id := range ids {
+		publishCreated(t, producer, id, "slow")
+	}
+	// Let the consumer read every record; they wait on the workers.
+	time.Sleep(50 * time.Millisecond)
+
+	second, _ := broker.NewSubscriber("group")
+	var mu sync.Mutex
+	var assigned []TopicPartition
+	require.NoError(t, second.Subscribe([]string{"transactions"}, func(e RebalanceEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		assigned = append(assigned, e.Assigned...)
+	}))
+
+	second.Poll(10 * time.Millisecond)
+	mu.Lock()
+	assert.Empty(t, assigned, "partitions are not handed over while their records are in flight")
+	mu.Unlock()
+
+	close(slow)
+	var record *Record
+	assert.Eventually(t, func() bool {
+		r, _ := second.Poll(10 * time.Millisecond)
+		mu.Lock()
+		defer mu.Unlock()
+		if r != nil {
+			record = r
+		}
+		return len(assigned) > 0
+	}, 2*time.Second, 10*time.Millisecond)
+
+	mu.Lock()
+	for _, tp := range assigned {
+		count := 0
+		for _, m := range broker.Messages("transactions") {
+			if m.TopicPartition == tp {
+				count++
+			}
+		}
+		assert.Equal(t, int64(count), broker.Committed("group", tp), "%s was committed before being handed over", tp)
+	}
+	mu.Unlock()
+	assert.Nil(t, record, "the new owner has nothing left to read")
+	assert.Eventually(t, func() bool { return handler.count() == len(ids) }, 2*time.Second, 10*time.Millisecond)
+
+	cancel()
+	require.NoError(t, <-done)
+	consumer.Close()
+	second.Close()
+}
+
+func TestConsumerRecordsMetrics(t *testing.T) {
+	broker := NewMemoryBroker(1)
+	producer, _ := broker.NewProducer("metrics")
+	subscriber, _ := broker.NewSubscriber("group")
+
+	handler := &gatedHandler{handled: map[uuid.UUID][]string{}}
+	dispatcher := NewDispatcher()
+	handler.register(dispatcher)
+
+	handled := messageProcessed.WithLabelValues("metrics", EventTransactionCreated, outcomeHandled)
+	deadLettered := messageProcessed.WithLabelValues("metrics", "unknown", outcomeDeadLettered)
+	before := testutil.ToFloat64(handled)
+
+	consumer := NewConsumer(subscriber, "metrics", dispatcher, WithDeadLetterTopic(producer, "metrics.dlq"))
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- consumer.Start(ctx) }()
+
+	publishCreated(t, producer, uuid.New(), "measured")
+	producer.Publish(ctx, &Message{Key: "garbage", Value: []byte("not json")})
+
+	assert.Eventually(t, func() bool { return len(broker.Consumed("group", "metrics")) == 2 }, 2*time.Second, 10*time.Millisecond)
+	cancel()
+	require.NoError(t, <-done)
+	consumer.Close()
+
+	assert.Equal(t, before+1, testutil.ToFloat64(handled))
+	assert.Equal(t, float64(1), testutil.ToFloat64(deadLettered))
+	assert.Equal(t, 1, testutil.CollectAndCount(messageEndToEndLatency, "kafka_message_end_to_end_latency_seconds"))
+}
+
+func TestLagReporter(t *testing.T) {
+	broker := NewMemoryBroker(2)
+	producer, _ := broker.NewProducer("lagging")
+	for _, key := range []string{"a", "b", "c"} {
+		producer.Publish(context.Background(), &Message{Key: key})
+	}
+	inspector, _ := broker.NewInspector("group")
+
+	NewLagReporter(inspector, time.Second, "lagging").report(context.Background())
+
+	total := testutil.ToFloat64(consumerLag.WithLabelValues("lagging", "0")) +
+		testutil.ToFloat64(consumerLag.WithLabelValues("lagging", "1"))
+	assert.Equal(t, float64(3), total)
+}
+
+// flakySubscriber fails the first polls with a transient error, like a
+// subscribed topic not created yet.
+type flakySubscriber struct {
+	Subscriber
+	failures int
+}
+
+func (s *flakySubscriber) Poll(timeout time.Duration) (*Record, error) {
+	if s.failures > 0 {
+		s.failures--
+		return nil, errors.New("Broker: Unknown topic or partition")
+	}
+	return s.Subscriber.Poll(timeout)
+}
+
+func TestConsumerKeepsPollingAfterTransientErrors(t *testing.T) {
+	broker := NewMemoryBroker(1)
+	producer, _ := broker.NewProducer("transactions")
+	memory, _ := broker.NewSubscriber("group")
+	subscriber := &flakySubscriber{Subscriber: memory, failures: 3}
+
+	handler := &gatedHandler{handled: map[uuid.UUID][]string{}}
+	dispatcher := NewDispatcher()
+	handler.register(dispatcher)
+
+	id := uuid.New()
+	publishCreated(t, producer, id, "after errors")
+
+	consumer := NewConsumer(subscriber, "transactions", dispatcher)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- consumer.Start(ctx) }()
+
+	assert.Eventually(t, func() bool { return handler.count() == 1 }, 2*time.Second, 10*time.Millisecond)
+	assert.Zero(t, subscriber.failures)
+
+	cancel()
+	require.NoError(t, <-done)
+	consumer.Close()
+}
+
+// fatalSubscriber fails every poll with a fatal error.
+type fatalSubscriber struct {
+	Subscriber
+}
+
+func (s *fatalSubscriber) Poll(time.Duration) (*Record, error) {
+	return nil, Fatal(errors.New("Local: Fatal error"))
+}
+
+func TestConsumerStopsOnFatalErrors(t *testing.T) {
+	broker := NewMemoryBroker(1)
+	memory, _ := broker.NewSubscriber("group")
+	consumer := NewConsumer(&fatalSubscriber{Subscriber: memory}, "transactions", NewDispatcher())
+
+	err := consumer.Start(context.Background())
+	assert.True(t, IsFatal(err))
+	assert.EqualError(t, err, "Local: Fatal error")
+	consumer.Close()
+}
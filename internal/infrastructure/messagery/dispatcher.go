@@ -0,0 +1,52 @@
+package messagery
+
+import (
+	"context"
+)
+
+// EventHandler handles one decoded event.
+type EventHandler func(ctx context.Context, event *Event) error
+
+// Dispatcher routes events to the handler registered for their type. Events
+// of other types are acknowledged without being handled.
+type Dispatcher struct {
+	handlers map[string]EventHandler
+}
+
+func NewDispatcher() *Dispatcher {
+	return &Dispatcher{
+		handlers: map[string]EventHandler{},
+	}
+}
+
+// Register sets the handler of eventType, replacing any previous one.
+func (d *Dispatcher) Register(eventType string, handler EventHandler) {
+	d.handlers[eventType] = handler
+}
+
+// Handles reports whether a handler is registered for eventType.
+func (d *Dispatcher) Handles(eventType string) bool {
+	_, ok := d.handlers[eventType]
+	return ok
+}
+
+// Dispatch runs the handler registered for the event type, if any.
+func (d *Dispatcher) Dispatch(ctx context.Context, event *Event) error {
+	handler, ok := d.handlers[event.Type]
+	if !ok {
+		return nil
+	}
+	return handler(ctx, event)
+}
+
+// HandleData adapts a handler of typed event data, such as TransactionCreated,
+// into an EventHandler. Data that does not decode is a permanent failure.
+func HandleData[T any](fn func(ctx context.Context, event *Event, data *T) error) EventHandler {
+	return func(ctx context.Context, event *Event) error {
+		var data T
+		if err := event.DecodeData(&data); err != nil {
+			return Permanent(err)
+		}
+		return fn(ctx, event, &data)
+	}
+}
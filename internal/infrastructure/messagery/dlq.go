@@ -0,0 +1,256 @@
+package messagery
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+)
+
+// Headers stamped on messages moved to the dead-letter topic.
+const (
+	HeaderDLQOriginalTopic     = "dlq_original_topic"
+	HeaderDLQOriginalPartition = "dlq_original_partition"
+	HeaderDLQOriginalOffset    = "dlq_original_offset"
+	HeaderDLQError             = "dlq_error"
+	HeaderDLQFailedAt          = "dlq_failed_at"
+)
+
+const metadataTimeoutMs = 5000
+
+// DeadLetter is a message read back from the dead-letter topic.
+type DeadLetter struct {
+	Partition         int32
+	Offset            int64
+	Key               string
+	Value             []byte
+	Headers           map[string]string
+	OriginalTopic     string
+	OriginalPartition int32
+	OriginalOffset    int64
+	Error             string
+	Attempts          int
+	FailedAt          time.Time
+}
+
+// newDeadLetterMessage wraps a message that could not be processed so it can be
+// published to the dead-letter topic. The original key, value and headers are
+// kept; the failure context is added as dlq_* headers.
+func newDeadLetterMessage(r *Record, topic string, cause error, failedAt time.Time) *Message {
+	headers := copyHeaders(r.Headers)
+	headers[HeaderAttempts] = strconv.Itoa(attempts(r) + 1)
+	headers[HeaderDLQOriginalTopic] = r.Topic
+	headers[HeaderDLQOriginalPartition] = strconv.Itoa(int(r.Partition))
+	headers[HeaderDLQOriginalOffset] = strconv.FormatInt(r.Offset, 10)
+	headers[HeaderDLQError] = cause.Error()
+	headers[HeaderDLQFailedAt] = failedAt.UTC().Format(time.RFC3339Nano)
+
+	return &Message{
+		Topic:   topic,
+		Key:     string(r.Key),
+		Value:   r.Value,
+		Headers: headers,
+	}
+}
+
+// newDeadLetter decodes a record consumed from the dead-letter topic.
+func newDeadLetter(r *Record) *DeadLetter {
+	headers := r.Headers
+	d := &DeadLetter{
+		Partition:     r.Partition,
+		Offset:        r.Offset,
+		Key:           string(r.Key),
+		Value:         r.Value,
+		Headers:       headers,
+		OriginalTopic: headers[HeaderDLQOriginalTopic],
+		Error:         headers[HeaderDLQError],
+		Attempts:      attempts(r),
+	}
+
+	if partition, err := strconv.ParseInt(headers[HeaderDLQOriginalPartition], 10, 32); err == nil {
+		d.OriginalPartition = int32(partition)
+	}
+	if offset, err := strconv.ParseInt(headers[HeaderDLQOriginalOffset], 10, 64); err == nil {
+		d.OriginalOffset = offset
+	}
+	if failedAt, err := time.Parse(time.RFC3339Nano, headers[HeaderDLQFailedAt]); err == nil {
+		d.FailedAt = failedAt
+	}
+
+	return d
+}
+
+// RedriveMessage builds the message that puts a dead letter back on its
+// original topic. The dlq_* headers are dropped; the attempt count is kept so
+// a message that keeps failing is recognisable.
+func (d *DeadLetter) RedriveMessage() *Message {
+	headers := make(map[string]string, len(d.Headers))
+	for k, v := range d.Headers {
+		if !strings.HasPrefix(k, "dlq_") {
+			headers[k] = v
+		}
+	}
+
+	return &Message{
+		Topic:   d.OriginalTopic,
+		Key:     d.Key,
+		Value:   d.Value,
+		Headers: headers,
+	}
+}
+
+// attempts returns the number of failed processing attempts recorded on a record.
+func attempts(r *Record) int {
+	n, err := strconv.Atoi(r.Headers[HeaderAttempts])
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+func copyHeaders(headers map[string]string) map[string]string {
+	copied := make(map[string]string, len(headers)+1)
+	for k, v := range headers {
+		copied[k] = v
+	}
+	return copied
+}
+
+// DeadLetterReader reads the dead-letter topic for inspection and re-driving.
+// It assigns partitions directly instead of joining the group, and only
+// commits offsets when asked to, so listing never consumes anything.
+type DeadLetterReader struct {
+	consumer *kafka.Consumer
+	topic    string
+}
+
+// NewDeadLetterReader creates a reader of the dead-letter topic. The group ID
+// only tracks which dead letters have been re-driven.
+func NewDeadLetterReader(brokers []string, groupID, topic string, opts ...KafkaOption) (*DeadLetterReader, error) {
+	c, err := kafka.NewConsumer(newKafkaConfigMap(brokers, kafka.ConfigMap{
+		"group.id":           groupID,
+		"enable.auto.commit": false,
+	}, opts))
+	if err != nil {
+		log.Errorf("Unable to create dead-letter reader due: %s", err)
+		return nil, err
+	}
+
+	return &DeadLetterReader{
+		consumer: c,
+		topic:    topic,
+	}, nil
+}
+
+// Scan calls fn for every dead letter up to the current end of the topic. With
+// fromCommitted set, each partition starts at the offset last committed by
+// Commit instead of at the beginning. Scanning stops early when fn returns
+// false.
+func (r *DeadLetterReader) Scan(ctx context.Context, fromCommitted bool, fn func(*DeadLetter) bool) error {
+	metadata, err := r.consumer.GetMetadata(&r.topic, false, metadataTimeoutMs)
+	if err != nil {
+		log.Errorf("Unable to fetch dead-letter topic metadata due: %s", err)
+		return err
+	}
+	topic, ok := metadata.Topics[r.topic]
+	if !ok || topic.Error.Code() != kafka.ErrNoError {
+		return fmt.Errorf("dead-letter topic %q not found", r.topic)
+	}
+
+	partitions := make([]kafka.TopicPartition, 0, len(topic.Partitions))
+	for _, p := range topic.Partitions {
+		partitions = append(partitions, kafka.TopicPartition{Topic: &r.topic, Partition: p.ID})
+	}
+
+	committed := map[int32]kafka.Offset{}
+	if fromCommitted {
+		offsets, err := r.consumer.Committed(partitions, metadataTimeoutMs)
+		if err != nil {
+			log.Errorf("Unable to fetch committed offsets due: %s", err)
+			return err
+		}
+		for _, tp := range offsets {
+			if tp.Offset >= 0 {
+				committed[tp.Partition] = tp.Offset
+			}
+		}
+	}
+
+	assignment := make([]kafka.TopicPartition, 0, len(partitions))
+	ends := map[int32]int64{}
+	for _, tp := range partitions {
+		low, high, err := r.consumer.QueryWatermarkOffsets(r.topic, tp.Partition, metadataTimeoutMs)
+		if err != nil {
+			log.Errorf("Unable to query watermarks due: %s", err)
+			return err
+		}
+
+		start := kafka.Offset(low)
+		if offset, ok := committed[tp.Partition]; ok && int64(offset) > low {
+			start = offset
+		}
+		if int64(start) >= high {
+			continue
+		}
+
+		tp.Offset = start
+		assignment = append(assignment, tp)
+		ends[tp.Partition] = high
+	}
+	if len(assignment) == 0 {
+		return nil
+	}
+
+	if err := r.consumer.Assign(assignment); err != nil {
+		log.Errorf("Unable to assign dead-letter partitions due: %s", err)
+		return err
+	}
+	defer r.consumer.Unassign()
+
+	for len(ends) > 0 {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		msg, err := r.consumer.ReadMessage(time.Second)
+		if err != nil {
+			if kerr, ok := err.(kafka.Error); ok && kerr.Code() == kafka.ErrTimedOut {
+				continue
+			}
+			log.Errorf("Unable to read dead letter due: %s", err)
+			return err
+		}
+
+		partition := msg.TopicPartition.Partition
+		if int64(msg.TopicPartition.Offset)+1 >= ends[partition] {
+			delete(ends, partition)
+		}
+
+		if !fn(newDeadLetter(newRecord(msg))) {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// Commit marks a dead letter, and every earlier one in its partition, as re-driven.
+func (r *DeadLetterReader) Commit(d *DeadLetter) error {
+	_, err := r.consumer.CommitOffsets([]kafka.TopicPartition{{
+		Topic:     &r.topic,
+		Partition: d.Partition,
+		Offset:    kafka.Offset(d.Offset + 1),
+	}})
+	if err != nil {
+		log.Errorf("Unable to commit dead-letter offset due: %s", err)
+	}
+	return err
+}
+
+func (r *DeadLetterReader) Close() error {
+	return r.consumer.Close()
+}
@@ -0,0 +1,60 @@
+package messagery
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeadLetterRoundTrip(t *testing.T) {
+	original := &Record{
+		TopicPartition: TopicPartition{Topic: "transactions", Partition: 2},
+		Offset:         41,
+		Key:            []byte("key-1"),
+		Value:          []byte(`{"id":"not-a-uuid"}`),
+		Headers: map[string]string{
+			HeaderEventType: EventTransactionCreated,
+			HeaderAttempts:  "1",
+		},
+	}
+	failedAt := time.Date(2024, 1, 20, 15, 30, 0, 0, time.UTC)
+
+	msg := newDeadLetterMessage(original, "transactions.dlq", errors.New("boom"), failedAt)
+
+	assert.Equal(t, "transactions.dlq", msg.Topic)
+	assert.Equal(t, "key-1", msg.Key)
+	assert.Equal(t, original.Value, msg.Value)
+	assert.Equal(t, "transactions", msg.Headers[HeaderDLQOriginalTopic])
+	assert.Equal(t, "2", msg.Headers[HeaderDLQOriginalPartition])
+	assert.Equal(t, "41", msg.Headers[HeaderDLQOriginalOffset])
+	assert.Equal(t, "boom", msg.Headers[HeaderDLQError])
+	assert.Equal(t, "2", msg.Headers[HeaderAttempts])
+	assert.Equal(t, EventTransactionCreated, msg.Headers[HeaderEventType])
+	assert.Equal(t, "1", original.Headers[HeaderAttempts], "the consumed record is left untouched")
+
+	d := newDeadLetter(&Record{
+		TopicPartition: TopicPartition{Topic: msg.Topic, Partition: 0},
+		Offset:         7,
+		Key:            []byte(msg.Key),
+		Value:          msg.Value,
+		Headers:        msg.Headers,
+	})
+	assert.Equal(t, int32(0), d.Partition)
+	assert.Equal(t, int64(7), d.Offset)
+	assert.Equal(t, "transactions", d.OriginalTopic)
+	assert.Equal(t, int32(2), d.OriginalPartition)
+	assert.Equal(t, int64(41), d.OriginalOffset)
+	assert.Equal(t, "boom", d.Error)
+	assert.Equal(t, 2, d.Attempts)
+	assert.True(t, failedAt.Equal(d.FailedAt))
+
+	redriven := d.RedriveMessage()
+	assert.Equal(t, "transactions", redriven.Topic)
+	assert.Equal(t, "key-1", redriven.Key)
+	assert.Equal(t, map[string]string{
+		HeaderEventType: EventTransactionCreated,
+		HeaderAttempts:  "2",
+	}, redriven.Headers)
+}
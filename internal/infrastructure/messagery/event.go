@@ -0,0 +1,188 @@
+package messagery
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// CloudEventsSpecVersion is the CloudEvents version the envelope follows.
+	CloudEventsSpecVersion = "1.0"
+	// EventSource identifies this service as the producer of the events.
+	EventSource = "/vr-software-challenge/transactions"
+	// EventContentType is sent in the content-type header of enveloped messages.
+	EventContentType = "application/cloudevents+json"
+
+	HeaderContentType = "content-type"
+)
+
+// eventVersions is the current data schema version of each event type.
+// Bump a version together with an upcaster from the previous one.
+var eventVersions = map[string]int{
+	EventTransactionCreated:       2,
+	EventTransactionStatusChanged: 1,
+	EventTransactionVoided:        1,
+	EventTransactionConverted:     1,
+}
+
+// Upcaster rewrites the data of an event from one schema version to the next.
+type Upcaster func(data json.RawMessage) (json.RawMessage, error)
+
+// upcasters holds, per event type, the upcaster from each outdated version.
+var upcasters = map[string]map[int]Upcaster{
+	EventTransactionCreated: {1: upcastTransactionCreatedV1},
+}
+
+// Event is a CloudEvents-style envelope around a domain event. DataVersion is
+// the schema version of Data, which decoding upcasts to the current one.
+type Event struct {
+	SpecVersion     string          `json:"specversion"`
+	ID              string          `json:"id"`
+	Type            string          `json:"type"`
+	Source          string          `json:"source"`
+	Time            time.Time       `json:"time"`
+	Subject         string          `json:"subject"`
+	DataContentType string          `json:"datacontenttype"`
+	DataVersion     int             `json:"dataversion"`
+	Data            json.RawMessage `json:"data"`
+}
+
+// NewEvent wraps data as the current version of an event of the given type
+// about subject (the transaction ID).
+func NewEvent(eventType, subject string, data any) (*Event, error) {
+	version, ok := eventVersions[eventType]
+	if !ok {
+		return nil, fmt.Errorf("unknown event type %q", eventType)
+	}
+
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Event{
+		SpecVersion:     CloudEventsSpecVersion,
+		ID:              uuid.NewString(),
+		Type:            eventType,
+		Source:          EventSource,
+		Time:            time.Now().UTC(),
+		Subject:         subject,
+		DataContentType: "application/json",
+		DataVersion:     version,
+		Data:            payload,
+	}, nil
+}
+
+// Message encodes the event for publishing, keyed by its subject so every
+// event of a transaction lands on the same partition.
+func (e *Event) Message() (*Message, error) {
+	value, err := json.Marshal(e)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Message{
+		Key:     e.Subject,
+		Value:   value,
+		Headers: e.headers(),
+	}, nil
+}
+
+func (e *Event) headers() map[string]string {
+	return map[string]string{
+		HeaderVersion:     fmt.Sprint(e.DataVersion),
+		HeaderEventType:   e.Type,
+		HeaderContentType: EventContentType,
+	}
+}
+
+// DecodeData unmarshals the event data into v.
+func (e *Event) DecodeData(v any) error {
+	if err := json.Unmarshal(e.Data, v); err != nil {
+		return fmt.Errorf("decode %s v%d data: %w", e.Type, e.DataVersion, err)
+	}
+	return nil
+}
+
+// DecodeEvent reads the event carried by a record and upcasts its data to the
+// current version. Records published before the envelope existed hold a bare
+// v1 payload; they are wrapped using their event_type header, defaulting to
+// transaction.created, and an ID derived from their position so redeliveries
+// keep the same ID.
+func DecodeEvent(r *Record) (*Event, error) {
+	var event Event
+	if err := json.Unmarshal(r.Value, &event); err != nil {
+		return nil, fmt.Errorf("decode event: %w", err)
+	}
+
+	if event.SpecVersion == "" {
+		event = Event{
+			SpecVersion:     CloudEventsSpecVersion,
+			ID:              uuid.NewSHA1(uuid.NameSpaceURL, []byte(r.String())).String(),
+			Type:            r.Headers[HeaderEventType],
+			Source:          EventSource,
+			Time:            r.Timestamp,
+			Subject:         string(r.Key),
+			DataContentType: "application/json",
+			DataVersion:     1,
+			Data:            r.Value,
+		}
+		if event.Type == "" {
+			event.Type = EventTransactionCreated
+		}
+	}
+
+	if err := upcast(&event); err != nil {
+		return nil, err
+	}
+
+	return &event, nil
+}
+
+// upcast brings the event data to the current version of its type, one version
+// at a time. Unknown types are left untouched for the dispatcher to skip.
+func upcast(e *Event) error {
+	current, ok := eventVersions[e.Type]
+	if !ok {
+		return nil
+	}
+	if e.DataVersion > current {
+		return fmt.Errorf("%s v%d is newer than the supported v%d", e.Type, e.DataVersion, current)
+	}
+
+	for e.DataVersion < current {
+		up, ok := upcasters[e.Type][e.DataVersion]
+		if !ok {
+			return fmt.Errorf("no upcaster for %s v%d", e.Type, e.DataVersion)
+		}
+
+		data, err := up(e.Data)
+		if err != nil {
+			return fmt.Errorf("upcast %s v%d: %w", e.Type, e.DataVersion, err)
+		}
+		e.Data = data
+		e.DataVersion++
+	}
+
+	return nil
+}
+
+// upcastTransactionCreatedV1 converts the original TransactionMessage payload
+// into TransactionCreated.
+func upcastTransactionCreatedV1(data json.RawMessage) (json.RawMessage, error) {
+	var v1 TransactionMessage
+	if err := json.Unmarshal(data, &v1); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(TransactionCreated{
+		ID:              v1.ID,
+		Description:     v1.Description,
+		TransactionDate: v1.TransactionDate,
+		AmountUSD:       v1.AmountUSD,
+		CreatedAt:       v1.CreatedAt,
+	})
+}
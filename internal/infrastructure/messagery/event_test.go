@@ -0,0 +1,120 @@
+package messagery
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/Athla/vr-software-challenge/internal/domain/models"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventRoundTrip(t *testing.T) {
+	id := uuid.New()
+	event, err := NewEvent(EventTransactionStatusChanged, id.String(), TransactionStatusChanged{
+		ID:   id,
+		From: models.StatusPending,
+		To:   models.StatusProcessing,
+	})
+	require.NoError(t, err)
+
+	msg, err := event.Message()
+	require.NoError(t, err)
+	assert.Equal(t, id.String(), msg.Key)
+	assert.Equal(t, EventTransactionStatusChanged, msg.Headers[HeaderEventType])
+	assert.Equal(t, "1", msg.Headers[HeaderVersion])
+	assert.Equal(t, EventContentType, msg.Headers[HeaderContentType])
+
+	decoded, err := DecodeEvent(&Record{Key: []byte(msg.Key), Value: msg.Value, Headers: msg.Headers})
+	require.NoError(t, err)
+	assert.Equal(t, event.ID, decoded.ID)
+	assert.Equal(t, EventSource, decoded.Source)
+	assert.Equal(t, id.String(), decoded.Subject)
+
+	var data TransactionStatusChanged
+	require.NoError(t, decoded.DecodeData(&data))
+	assert.Equal(t, models.StatusProcessing, data.To)
+
+	_, err = NewEvent("transaction.unknown", id.String(), nil)
+	assert.Error(t, err)
+}
+
+func TestDecodeEventUpcastsLegacyPayloads(t *testing.T) {
+	id := uuid.New()
+	date := time.Date(2024, 1, 20, 0, 0, 0, 0, time.UTC)
+	v1, _ := json.Marshal(TransactionMessage{
+		ID:              id,
+		Description:     "Office Supplies",
+		TransactionDate: date,
+		AmountUSD:       decimal.NewFromFloat(123.45),
+	})
+	record := &Record{
+		TopicPartition: TopicPartition{Topic: "transactions", Partition: 1},
+		Offset:         9,
+		Key:            []byte(id.String()),
+		Value:          v1,
+		Headers:        map[string]string{HeaderVersion: "1"},
+	}
+
+	event, err := DecodeEvent(record)
+	require.NoError(t, err)
+	assert.Equal(t, EventTransactionCreated, event.Type, "bare payloads without a type header are creations")
+	assert.Equal(t, 2, event.DataVersion)
+	assert.Equal(t, id.String(), event.Subject)
+
+	var created TransactionCreated
+	require.NoError(t, event.DecodeData(&created))
+	assert.Equal(t, id, created.ID)
+	assert.Equal(t, "Office Supplies", created.Description)
+	assert.True(t, date.Equal(created.TransactionDate))
+	assert.True(t, decimal.NewFromFloat(123.45).Equal(created.AmountUSD))
+
+	again, _ := DecodeEvent(record)
+	assert.Equal(t, event.ID, again.ID, "a redelivered legacy record keeps its event ID")
+
+	voided, _ := json.Marshal(TransactionVoided{ID: id, Reason: "Duplicate"})
+	event, err = DecodeEvent(&Record{
+		Value:   voided,
+		Headers: map[string]string{HeaderVersion: "1", HeaderEventType: EventTransactionVoided},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, EventTransactionVoided, event.Type)
+	assert.Equal(t, 1, event.DataVersion)
+}
+
+func TestDecodeEventRejectsNewerVersions(t *testing.T) {
+	event, _ := NewEvent(EventTransactionCreated, uuid.NewString(), TransactionCreated{})
+	event.DataVersion = 3
+	msg, _ := event.Message()
+
+	_, err := DecodeEvent(&Record{Value: msg.Value})
+	assert.Error(t, err)
+
+	_, err = DecodeEvent(&Record{Value: []byte("not json")})
+	assert.Error(t, err)
+}
+
+func TestDispatcher(t *testing.T) {
+	var got []string
+	dispatcher := NewDispatcher()
+	dispatcher.Register(EventTransactionVoided, HandleData(func(ctx context.Context, e *Event, data *TransactionVoided) error {
+		got = append(got, data.Reason)
+		return nil
+	}))
+
+	voided, _ := NewEvent(EventTransactionVoided, "id", TransactionVoided{Reason: "Duplicate"})
+	converted, _ := NewEvent(EventTransactionConverted, "id", TransactionConverted{})
+
+	assert.NoError(t, dispatcher.Dispatch(context.Background(), voided))
+	assert.NoError(t, dispatcher.Dispatch(context.Background(), converted), "unhandled types are skipped")
+	assert.Equal(t, []string{"Duplicate"}, got)
+	assert.True(t, dispatcher.Handles(EventTransactionVoided))
+	assert.False(t, dispatcher.Handles(EventTransactionConverted))
+
+	voided.Data = json.RawMessage(`"not an object"`)
+	assert.True(t, IsPermanent(dispatcher.Dispatch(context.Background(), voided)))
+}
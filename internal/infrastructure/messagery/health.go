@@ -2,34 +2,85 @@ package messagery
 
 import (
 	"context"
-	"time"
+	"fmt"
 
 	"github.com/charmbracelet/log"
-	"github.com/google/uuid"
 )
 
+// Health statuses, from best to worst.
+const (
+	HealthOK       = "ok"
+	HealthDegraded = "degraded"
+	HealthDown     = "down"
+)
+
+// HealthReport is the state of the consumed topic and of the consumer group
+// reading it. Lag is only reported when the topic metadata could be read.
+type HealthReport struct {
+	Status     string          `json:"status"`
+	Topic      string          `json:"topic"`
+	Partitions []PartitionInfo `json:"partitions,omitempty"`
+	Lag        []PartitionLag  `json:"lag,omitempty"`
+	TotalLag   int64           `json:"total_lag"`
+	Issues     []string        `json:"issues,omitempty"`
+}
+
+// HealthCheck inspects the broker without publishing or consuming anything.
+// Kafka is down when the topic metadata cannot be read or the topic does not
+// exist, and degraded when a partition has no leader or the consumer group
+// lags more than maxLag messages behind on a partition.
 type HealthCheck struct {
-	producer *Producer
-	consumer *Consumer
+	inspector Inspector
+	topic     string
+	maxLag    int64
 }
 
-func NewHealthCheck(producer *Producer, consumer *Consumer) *HealthCheck {
+func NewHealthCheck(inspector Inspector, topic string, maxLag int64) *HealthCheck {
 	return &HealthCheck{
-		producer: producer,
-		consumer: consumer,
+		inspector: inspector,
+		topic:     topic,
+		maxLag:    maxLag,
 	}
 }
 
-func (h *HealthCheck) Check(ctx context.Context) error {
-	testMsg := &TransactionMessage{
-		ID:        uuid.New(),
-		CreatedAt: time.Now(),
+func (h *HealthCheck) Check(ctx context.Context) *HealthReport {
+	report := &HealthReport{
+		Status: HealthOK,
+		Topic:  h.topic,
+	}
+
+	partitions, err := h.inspector.Partitions(ctx, h.topic)
+	if err != nil {
+		log.Errorf("Unable to read metadata of %s due: %s", h.topic, err)
+		report.Status = HealthDown
+		report.Issues = append(report.Issues, err.Error())
+		return report
+	}
+	report.Partitions = partitions
+
+	for _, p := range partitions {
+		if p.Leader < 0 {
+			report.Status = HealthDegraded
+			report.Issues = append(report.Issues, fmt.Sprintf("partition %d has no leader", p.Partition))
+		}
+	}
+
+	lags, err := h.inspector.Lag(ctx, h.topic)
+	if err != nil {
+		log.Warnf("Unable to read consumer lag of %s due: %s", h.topic, err)
+		report.Status = HealthDegraded
+		report.Issues = append(report.Issues, fmt.Sprintf("consumer lag unavailable: %s", err))
+		return report
 	}
+	report.Lag = lags
 
-	if err := h.producer.PublishTransaction(ctx, testMsg); err != nil {
-		log.Errorf("Unable to publish health transaction: %s", err)
-		return err
+	for _, l := range lags {
+		report.TotalLag += l.Lag
+		if l.Lag > h.maxLag {
+			report.Status = HealthDegraded
+			report.Issues = append(report.Issues, fmt.Sprintf("partition %d lags %d messages behind", l.Partition, l.Lag))
+		}
 	}
 
-	return nil
+	return report
 }
@@ -0,0 +1,66 @@
+package messagery
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type leaderlessInspector struct {
+	Inspector
+}
+
+func (i leaderlessInspector) Partitions(ctx context.Context, topic string) ([]PartitionInfo, error) {
+	return []PartitionInfo{{Partition: 0, Leader: 1}, {Partition: 1, Leader: -1}}, nil
+}
+
+func TestHealthCheck(t *testing.T) {
+	ctx := context.Background()
+	broker := NewMemoryBroker(2)
+	inspector, _ := broker.NewInspector("group")
+	health := NewHealthCheck(inspector, "transactions", 2)
+
+	report := health.Check(ctx)
+	assert.Equal(t, HealthDown, report.Status, "the topic does not exist yet")
+	assert.NotEmpty(t, report.Issues)
+
+	producer, _ := broker.NewProducer("transactions")
+	for _, key := range []string{"a", "b", "c", "d", "e", "f"} {
+		require.NoError(t, producer.Publish(ctx, &Message{Key: key}))
+	}
+	assert.Len(t, broker.Messages("transactions"), 6, "the check itself publishes nothing")
+
+	report = health.Check(ctx)
+	assert.Equal(t, HealthDegraded, report.Status, "nothing has been consumed")
+	assert.Equal(t, int64(6), report.TotalLag)
+	assert.Equal(t, []PartitionInfo{{Partition: 0, Leader: 0}, {Partition: 1, Leader: 0}}, report.Partitions)
+
+	subscriber, _ := broker.NewSubscriber("group")
+	require.NoError(t, subscriber.Subscribe([]string{"transactions"}, nil))
+	for {
+		r, _ := subscriber.Poll(0)
+		if r == nil {
+			break
+		}
+		require.NoError(t, subscriber.Commit(r.TopicPartition, r.Offset+1))
+	}
+
+	report = health.Check(ctx)
+	assert.Equal(t, HealthOK, report.Status)
+	assert.Equal(t, int64(0), report.TotalLag)
+	for _, l := range report.Lag {
+		assert.Equal(t, l.HighWatermark, l.Committed)
+	}
+
+	report = NewHealthCheck(leaderlessInspector{inspector}, "transactions", 2).Check(ctx)
+	assert.Equal(t, HealthDegraded, report.Status)
+	assert.Equal(t, []string{"partition 1 has no leader"}, report.Issues)
+}
+
+func TestLag(t *testing.T) {
+	assert.Equal(t, PartitionLag{Partition: 1, Committed: -1, HighWatermark: 10, Lag: 7}, lag(1, -1001, 3, 10))
+	assert.Equal(t, PartitionLag{Partition: 1, Committed: 8, HighWatermark: 10, Lag: 2}, lag(1, 8, 3, 10))
+	assert.Equal(t, int64(8), lag(1, 1, 2, 10).Lag, "offsets removed by retention are not lag")
+}
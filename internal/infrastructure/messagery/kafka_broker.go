@@ -0,0 +1,275 @@
+package messagery
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+)
+
+// KafkaBroker creates producers and subscribers backed by a Kafka cluster.
+type KafkaBroker struct {
+	brokers []string
+	opts    []KafkaOption
+}
+
+// NewKafkaBroker creates a broker whose clients all apply opts, such as
+// WithKafkaConfig.
+func NewKafkaBroker(brokers []string, opts ...KafkaOption) *KafkaBroker {
+	return &KafkaBroker{
+		brokers: brokers,
+		opts:    opts,
+	}
+}
+
+// CreateTopics creates the missing topics with the broker's default partition
+// count and replication factor, as auto-created topics would have. Consumers
+// do not create the topics they subscribe to, and polling a missing one fails.
+func (b *KafkaBroker) CreateTopics(ctx context.Context, topics ...string) error {
+	admin, err := kafka.NewAdminClient(newKafkaConfigMap(b.brokers, kafka.ConfigMap{}, b.opts))
+	if err != nil {
+		log.Errorf("Unable to create admin client due: %s", err)
+		return err
+	}
+	defer admin.Close()
+
+	specs := make([]kafka.TopicSpecification, 0, len(topics))
+	for _, topic := range topics {
+		specs = append(specs, kafka.TopicSpecification{Topic: topic, NumPartitions: -1})
+	}
+
+	results, err := admin.CreateTopics(ctx, specs)
+	if err != nil {
+		return err
+	}
+	for _, result := range results {
+		switch result.Error.Code() {
+		case kafka.ErrNoError:
+			log.Infof("Created topic %s", result.Topic)
+		case kafka.ErrTopicAlreadyExists:
+		default:
+			return fmt.Errorf("create topic %s: %w", result.Topic, result.Error)
+		}
+	}
+
+	return nil
+}
+
+func (b *KafkaBroker) NewProducer(topic string) (Producerer, error) {
+	return NewProducer(b.brokers, topic, b.opts...)
+}
+
+func (b *KafkaBroker) NewSubscriber(groupID string) (Subscriber, error) {
+	c, err := kafka.NewConsumer(newKafkaConfigMap(b.brokers, kafka.ConfigMap{
+		"group.id":             groupID,
+		"auto.offset.reset":    "earliest",
+		"enable.auto.commit":   false,
+		"max.poll.interval.ms": 300000,
+		"session.timeout.ms":   45000,
+	}, b.opts))
+	if err != nil {
+		log.Errorf("Unable to create consumer due: %s", err)
+		return nil, err
+	}
+
+	return &kafkaSubscriber{
+		consumer: c,
+	}, nil
+}
+
+// kafkaSubscriber implements Subscriber on top of a confluent-kafka-go consumer.
+type kafkaSubscriber struct {
+	consumer *kafka.Consumer
+	callback RebalanceCallback
+}
+
+func (s *kafkaSubscriber) Subscribe(topics []string, callback RebalanceCallback) error {
+	s.callback = callback
+	return s.consumer.SubscribeTopics(topics, s.rebalance)
+}
+
+// rebalance forwards assignment changes. Not calling Assign/Unassign here lets
+// the client apply the change itself once the callback returns.
+func (s *kafkaSubscriber) rebalance(_ *kafka.Consumer, event kafka.Event) error {
+	if s.callback == nil {
+		return nil
+	}
+
+	switch e := event.(type) {
+	case kafka.AssignedPartitions:
+		s.callback(RebalanceEvent{Assigned: fromKafkaPartitions(e.Partitions)})
+	case kafka.RevokedPartitions:
+		s.callback(RebalanceEvent{Revoked: fromKafkaPartitions(e.Partitions)})
+	}
+	return nil
+}
+
+// Poll returns an error only when the client hit a fatal error. Others, such
+// as a subscribed topic missing or a broker being unreachable, are logged and
+// reported as no record, since the client recovers from them by itself.
+func (s *kafkaSubscriber) Poll(timeout time.Duration) (*Record, error) {
+	msg, err := s.consumer.ReadMessage(timeout)
+	if err != nil {
+		kerr, ok := err.(kafka.Error)
+		if !ok || kerr.IsFatal() {
+			return nil, Fatal(err)
+		}
+		if kerr.Code() != kafka.ErrTimedOut {
+			log.Warnf("Unable to read message due: %s", err)
+		}
+		return nil, nil
+	}
+
+	return newRecord(msg), nil
+}
+
+func (s *kafkaSubscriber) Commit(tp TopicPartition, offset int64) error {
+	_, err := s.consumer.CommitOffsets([]kafka.TopicPartition{toKafkaPartition(tp, offset)})
+	return err
+}
+
+func (s *kafkaSubscriber) Pause(tp TopicPartition) error {
+	return s.consumer.Pause([]kafka.TopicPartition{toKafkaPartition(tp, 0)})
+}
+
+func (s *kafkaSubscriber) Resume(tp TopicPartition) error {
+	return s.consumer.Resume([]kafka.TopicPartition{toKafkaPartition(tp, 0)})
+}
+
+func (s *kafkaSubscriber) Seek(tp TopicPartition, offset int64) error {
+	return s.consumer.Seek(toKafkaPartition(tp, offset), 0)
+}
+
+func (s *kafkaSubscriber) Close() error {
+	return s.consumer.Close()
+}
+
+// newRecord converts a consumed Kafka message.
+func newRecord(msg *kafka.Message) *Record {
+	headers := make(map[string]string, len(msg.Headers))
+	for _, h := range msg.Headers {
+		headers[h.Key] = string(h.Value)
+	}
+
+	return &Record{
+		TopicPartition: TopicPartition{Topic: *msg.TopicPartition.Topic, Partition: msg.TopicPartition.Partition},
+		Offset:         int64(msg.TopicPartition.Offset),
+		Key:            msg.Key,
+		Value:          msg.Value,
+		Headers:        headers,
+		Timestamp:      msg.Timestamp,
+	}
+}
+
+func toKafkaPartition(tp TopicPartition, offset int64) kafka.TopicPartition {
+	topic := tp.Topic
+	return kafka.TopicPartition{Topic: &topic, Partition: tp.Partition, Offset: kafka.Offset(offset)}
+}
+
+func fromKafkaPartitions(partitions []kafka.TopicPartition) []TopicPartition {
+	converted := make([]TopicPartition, 0, len(partitions))
+	for _, p := range partitions {
+		converted = append(converted, TopicPartition{Topic: *p.Topic, Partition: p.Partition})
+	}
+	return converted
+}
+
+func (b *KafkaBroker) NewInspector(groupID string) (Inspector, error) {
+	c, err := kafka.NewConsumer(newKafkaConfigMap(b.brokers, kafka.ConfigMap{
+		"group.id":           groupID,
+		"enable.auto.commit": false,
+	}, b.opts))
+	if err != nil {
+		log.Errorf("Unable to create inspector due: %s", err)
+		return nil, err
+	}
+
+	return &kafkaInspector{
+		consumer: c,
+	}, nil
+}
+
+// kafkaInspector reads metadata, watermarks and committed offsets through a
+// consumer that never subscribes, so it does not take part in the group.
+type kafkaInspector struct {
+	consumer *kafka.Consumer
+}
+
+func (i *kafkaInspector) Partitions(ctx context.Context, topic string) ([]PartitionInfo, error) {
+	metadata, err := i.consumer.GetMetadata(&topic, false, timeoutMs(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	t, ok := metadata.Topics[topic]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrTopicNotFound, topic)
+	}
+	switch t.Error.Code() {
+	case kafka.ErrNoError:
+	case kafka.ErrUnknownTopicOrPart, kafka.ErrUnknownTopic:
+		return nil, fmt.Errorf("%w: %s", ErrTopicNotFound, topic)
+	default:
+		return nil, t.Error
+	}
+
+	partitions := make([]PartitionInfo, 0, len(t.Partitions))
+	for _, p := range t.Partitions {
+		leader := p.Leader
+		if p.Error.Code() == kafka.ErrLeaderNotAvailable {
+			leader = -1
+		}
+		partitions = append(partitions, PartitionInfo{Partition: p.ID, Leader: leader})
+	}
+	sort.Slice(partitions, func(a, b int) bool { return partitions[a].Partition < partitions[b].Partition })
+
+	return partitions, nil
+}
+
+func (i *kafkaInspector) Lag(ctx context.Context, topic string) ([]PartitionLag, error) {
+	partitions, err := i.Partitions(ctx, topic)
+	if err != nil {
+		return nil, err
+	}
+
+	tps := make([]kafka.TopicPartition, 0, len(partitions))
+	for _, p := range partitions {
+		tps = append(tps, toKafkaPartition(TopicPartition{Topic: topic, Partition: p.Partition}, 0))
+	}
+	committed, err := i.consumer.Committed(tps, timeoutMs(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	lags := make([]PartitionLag, 0, len(committed))
+	for _, tp := range committed {
+		low, high, err := i.consumer.QueryWatermarkOffsets(topic, tp.Partition, timeoutMs(ctx))
+		if err != nil {
+			return nil, err
+		}
+		lags = append(lags, lag(tp.Partition, int64(tp.Offset), low, high))
+	}
+	sort.Slice(lags, func(a, b int) bool { return lags[a].Partition < lags[b].Partition })
+
+	return lags, nil
+}
+
+func (i *kafkaInspector) Close() error {
+	return i.consumer.Close()
+}
+
+// timeoutMs returns the time left until the deadline of ctx in milliseconds,
+// or metadataTimeoutMs when it has none.
+func timeoutMs(ctx context.Context) int {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return metadataTimeoutMs
+	}
+	if ms := int(time.Until(deadline).Milliseconds()); ms > 0 {
+		return ms
+	}
+	return 1
+}
@@ -0,0 +1,49 @@
+package messagery
+
+import (
+	"strings"
+
+	"github.com/Athla/vr-software-challenge/config"
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+)
+
+// KafkaOption adjusts the librdkafka settings of a Kafka client.
+type KafkaOption func(kafka.ConfigMap)
+
+// WithKafkaConfig applies the client ID and the security settings of cfg:
+// the security protocol, the SASL mechanism and credentials, and the CA,
+// certificate and key files used for TLS. Empty settings are left to the
+// librdkafka defaults.
+func WithKafkaConfig(cfg config.KafkaConfig) KafkaOption {
+	return func(cm kafka.ConfigMap) {
+		settings := map[string]string{
+			"client.id":                cfg.ClientID,
+			"security.protocol":        strings.ToLower(cfg.SecurityProtocol),
+			"sasl.mechanisms":          cfg.SASLMechanism,
+			"sasl.username":            cfg.SASLUsername,
+			"sasl.password":            cfg.SASLPassword,
+			"ssl.ca.location":          cfg.TLSCAFile,
+			"ssl.certificate.location": cfg.TLSCertFile,
+			"ssl.key.location":         cfg.TLSKeyFile,
+			"ssl.key.password":         cfg.TLSKeyPassword,
+		}
+		for key, value := range settings {
+			if value != "" {
+				cm[key] = value
+			}
+		}
+	}
+}
+
+// newKafkaConfigMap builds the settings of a client of brokers: the given
+// client-specific settings, then opts.
+func newKafkaConfigMap(brokers []string, settings kafka.ConfigMap, opts []KafkaOption) *kafka.ConfigMap {
+	cm := kafka.ConfigMap{"bootstrap.servers": strings.Join(brokers, ",")}
+	for key, value := range settings {
+		cm[key] = value
+	}
+	for _, opt := range opts {
+		opt(cm)
+	}
+	return &cm
+}
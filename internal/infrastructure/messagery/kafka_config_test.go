@@ -0,0 +1,33 @@
+package messagery
+
+import (
+	"testing"
+
+	"github.com/Athla/vr-software-challenge/config"
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithKafkaConfig(t *testing.T) {
+	cm := newKafkaConfigMap([]string{"a:9092", "b:9092"}, kafka.ConfigMap{"group.id": "group"}, []KafkaOption{
+		WithKafkaConfig(config.KafkaConfig{
+			ClientID:         "checkout_client",
+			SecurityProtocol: "SASL_SSL",
+			SASLMechanism:    "SCRAM-SHA-256",
+			SASLUsername:     "user",
+			SASLPassword:     "secret",
+			TLSCAFile:        "/etc/kafka/ca.pem",
+		}),
+	})
+
+	assert.Equal(t, kafka.ConfigMap{
+		"bootstrap.servers": "a:9092,b:9092",
+		"group.id":          "group",
+		"client.id":         "checkout_client",
+		"security.protocol": "sasl_ssl",
+		"sasl.mechanisms":   "SCRAM-SHA-256",
+		"sasl.username":     "user",
+		"sasl.password":     "secret",
+		"ssl.ca.location":   "/etc/kafka/ca.pem",
+	}, *cm)
+}
@@ -2,6 +2,8 @@ package messagery_test
 
 import (
 	"context"
+	"errors"
+	"net"
 	"testing"
 	"time"
 
@@ -12,9 +14,16 @@ import (
 )
 
 func TestPublishTransaction(t *testing.T) {
+	conn, err := net.DialTimeout("tcp", "localhost:9092", time.Second)
+	if err != nil {
+		t.Skip("Kafka is not running on localhost:9092")
+	}
+	conn.Close()
+
 	producer, err := messagery.NewProducer([]string{"localhost:9092"}, "transactions")
 	assert.NoError(t, err)
 	assert.NotNil(t, producer)
+	defer producer.Close()
 
 	msg := &messagery.TransactionMessage{
 		ID:              uuid.New(),
@@ -24,6 +33,52 @@ func TestPublishTransaction(t *testing.T) {
 		CreatedAt:       time.Now(),
 	}
 
-	err = producer.PublishTransaction(context.Background(), msg)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	err = producer.PublishTransaction(ctx, msg)
+	assert.NoError(t, err)
+}
+
+func TestPublishTransactionWaitsForDelivery(t *testing.T) {
+	producer, err := messagery.NewProducer([]string{"127.0.0.1:1"}, "transactions")
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	err = producer.PublishTransaction(ctx, &messagery.TransactionMessage{ID: uuid.New()})
+	assert.ErrorIs(t, err, context.DeadlineExceeded, "an unreachable broker never acknowledges")
+
+	var delivery *messagery.DeliveryError
+	if assert.True(t, errors.As(err, &delivery)) {
+		assert.Equal(t, "transactions", delivery.Topic)
+		assert.Equal(t, int32(-1), delivery.Partition)
+	}
+}
+
+func TestKafkaBrokerCreateTopics(t *testing.T) {
+	conn, err := net.DialTimeout("tcp", "localhost:9092", time.Second)
+	if err != nil {
+		t.Skip("Kafka is not running on localhost:9092")
+	}
+	conn.Close()
+
+	broker := messagery.NewKafkaBroker([]string{"localhost:9092"})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	prefix := "create-topics-" + uuid.NewString()
+	topics := []string{prefix, prefix + ".retry-5s", prefix + ".dlq"}
+	assert.NoError(t, broker.CreateTopics(ctx, topics...))
+	assert.NoError(t, broker.CreateTopics(ctx, topics...), "existing topics are left alone")
+
+	inspector, err := broker.NewInspector("group")
 	assert.NoError(t, err)
+	defer inspector.Close()
+	for _, topic := range topics {
+		partitions, err := inspector.Partitions(ctx, topic)
+		assert.NoError(t, err, topic)
+		assert.NotEmpty(t, partitions, topic)
+	}
 }
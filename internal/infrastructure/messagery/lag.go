@@ -0,0 +1,58 @@
+package messagery
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/charmbracelet/log"
+)
+
+// LagReporter periodically exports the consumer group lag of each partition of
+// topics as the kafka_consumer_lag gauge.
+type LagReporter struct {
+	inspector Inspector
+	topics    []string
+	interval  time.Duration
+}
+
+func NewLagReporter(inspector Inspector, interval time.Duration, topics ...string) *LagReporter {
+	return &LagReporter{
+		inspector: inspector,
+		topics:    topics,
+		interval:  interval,
+	}
+}
+
+// Start reports the lag right away and then every interval until ctx is cancelled.
+func (r *LagReporter) Start(ctx context.Context) error {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		r.report(ctx)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *LagReporter) report(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, r.interval)
+	defer cancel()
+
+	for _, topic := range r.topics {
+		lags, err := r.inspector.Lag(ctx, topic)
+		if err != nil {
+			log.Warnf("Unable to read consumer lag of %s due: %s", topic, err)
+			continue
+		}
+
+		for _, l := range lags {
+			consumerLag.WithLabelValues(topic, strconv.Itoa(int(l.Partition))).Set(float64(l.Lag))
+		}
+	}
+}
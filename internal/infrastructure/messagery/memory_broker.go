@@ -0,0 +1,586 @@
+package messagery
+
+import (
+	"context"
+	go_errors "errors"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultMemoryPartitions is the partition count of topics created by NewMemoryBroker.
+const DefaultMemoryPartitions = 3
+
+// ErrSubscriberClosed is returned when polling a closed subscriber.
+var ErrSubscriberClosed = go_errors.New("subscriber closed")
+
+// MemoryBroker is an in-process broker with Kafka's semantics: topics are split
+// into partitions by message key, consumer groups share partitions between
+// their members and resume from committed offsets. It backs BROKER=memory and
+// lets tests inspect what was published and consumed.
+type MemoryBroker struct {
+	mu         sync.Mutex
+	partitions int
+	topics     map[string]*memoryTopic
+	groups     map[string]*memoryGroup
+	changed    chan struct{}
+}
+
+type memoryTopic struct {
+	partitions [][]*Record
+	// published holds every record in publish order, across partitions.
+	published []*Record
+	next      uint32
+}
+
+type memoryGroup struct {
+	committed map[TopicPartition]int64
+	members   []*memorySubscriber
+	owners    map[TopicPartition]*memorySubscriber
+}
+
+// NewMemoryBroker creates an empty broker whose topics have the given number
+// of partitions (DefaultMemoryPartitions when not positive).
+func NewMemoryBroker(partitions int) *MemoryBroker {
+	if partitions <= 0 {
+		partitions = DefaultMemoryPartitions
+	}
+
+	return &MemoryBroker{
+		partitions: partitions,
+		topics:     map[string]*memoryTopic{},
+		groups:     map[string]*memoryGroup{},
+		changed:    make(chan struct{}),
+	}
+}
+
+func (b *MemoryBroker) CreateTopics(ctx context.Context, topics ...string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, name := range topics {
+		b.topic(name)
+	}
+	return nil
+}
+
+func (b *MemoryBroker) NewProducer(topic string) (Producerer, error) {
+	return &memoryProducer{
+		broker: b,
+		topic:  topic,
+	}, nil
+}
+
+func (b *MemoryBroker) NewSubscriber(groupID string) (Subscriber, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return &memorySubscriber{
+		broker:   b,
+		group:    b.group(groupID),
+		assigned: map[TopicPartition]int64{},
+		paused:   map[TopicPartition]bool{},
+		revoking: map[TopicPartition]bool{},
+	}, nil
+}
+
+func (b *MemoryBroker) NewInspector(groupID string) (Inspector, error) {
+	return &memoryInspector{
+		broker:  b,
+		groupID: groupID,
+	}, nil
+}
+
+// Messages returns every record published to topic, in publish order.
+func (b *MemoryBroker) Messages(topic string) []Record {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	t, ok := b.topics[topic]
+	if !ok {
+		return nil
+	}
+
+	records := make([]Record, 0, len(t.published))
+	for _, r := range t.published {
+		records = append(records, *r)
+	}
+	return records
+}
+
+// Consumed returns the records of topic that groupID has committed, in publish order.
+func (b *MemoryBroker) Consumed(groupID, topic string) []Record {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	t, ok := b.topics[topic]
+	g, found := b.groups[groupID]
+	if !ok || !found {
+		return nil
+	}
+
+	records := []Record{}
+	for _, r := range t.published {
+		if committed, ok := g.committed[r.TopicPartition]; ok && r.Offset < committed {
+			records = append(records, *r)
+		}
+	}
+	return records
+}
+
+// Committed returns the committed offset of groupID on tp, or -1 when nothing was committed.
+func (b *MemoryBroker) Committed(groupID string, tp TopicPartition) int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if g, ok := b.groups[groupID]; ok {
+		if offset, ok := g.committed[tp]; ok {
+			return offset
+		}
+	}
+	return -1
+}
+
+// publish appends a message to its partition. Callers hold b.mu.
+func (b *MemoryBroker) publish(topic string, msg *Message) {
+	t := b.topic(topic)
+
+	var partition int
+	if msg.Key == "" {
+		partition = int(t.next % uint32(len(t.partitions)))
+		t.next++
+	} else {
+		h := fnv.New32a()
+		h.Write([]byte(msg.Key))
+		partition = int(h.Sum32() % uint32(len(t.partitions)))
+	}
+
+	headers := make(map[string]string, len(msg.Headers))
+	for k, v := range msg.Headers {
+		headers[k] = v
+	}
+
+	record := &Record{
+		TopicPartition: TopicPartition{Topic: topic, Partition: int32(partition)},
+		Offset:         int64(len(t.partitions[partition])),
+		Key:            []byte(msg.Key),
+		Value:          append([]byte(nil), msg.Value...),
+		Headers:        headers,
+		Timestamp:      time.Now(),
+	}
+	t.partitions[partition] = append(t.partitions[partition], record)
+	t.published = append(t.published, record)
+
+	b.notify()
+}
+
+// topic returns the named topic, creating it on first use. Callers hold b.mu.
+func (b *MemoryBroker) topic(name string) *memoryTopic {
+	t, ok := b.topics[name]
+	if !ok {
+		t = &memoryTopic{partitions: make([][]*Record, b.partitions)}
+		b.topics[name] = t
+	}
+	return t
+}
+
+// group returns the named consumer group, creating it on first use. Callers hold b.mu.
+func (b *MemoryBroker) group(groupID string) *memoryGroup {
+	g, ok := b.groups[groupID]
+	if !ok {
+		g = &memoryGroup{
+			committed: map[TopicPartition]int64{},
+			owners:    map[TopicPartition]*memorySubscriber{},
+		}
+		b.groups[groupID] = g
+	}
+	return g
+}
+
+// notify wakes every subscriber waiting in Poll. Callers hold b.mu.
+func (b *MemoryBroker) notify() {
+	close(b.changed)
+	b.changed = make(chan struct{})
+}
+
+// rebalance spreads the partitions of the subscribed topics round-robin over
+// the group members. A partition changing owner is first revoked from its
+// current owner and only handed over once that owner has seen the
+// revocation, so two members never read the same partition at once. Callers
+// hold b.mu.
+func (b *MemoryBroker) rebalance(g *memoryGroup) {
+	var partitions []TopicPartition
+	subscribed := map[string][]*memorySubscriber{}
+	for _, m := range g.members {
+		for _, topic := range m.topics {
+			if _, ok := subscribed[topic]; !ok {
+				for p := range b.topic(topic).partitions {
+					partitions = append(partitions, TopicPartition{Topic: topic, Partition: int32(p)})
+				}
+			}
+			subscribed[topic] = append(subscribed[topic], m)
+		}
+	}
+	sortPartitions(partitions)
+
+	target := map[TopicPartition]*memorySubscriber{}
+	for i, tp := range partitions {
+		members := subscribed[tp.Topic]
+		target[tp] = members[i%len(members)]
+	}
+
+	for tp, owner := range g.owners {
+		if target[tp] != owner {
+			owner.revoke(tp)
+		}
+	}
+	for tp, member := range target {
+		if _, owned := g.owners[tp]; owned {
+			continue
+		}
+		g.owners[tp] = member
+		member.assign(tp, g.committed[tp])
+	}
+
+	b.notify()
+}
+
+func sortPartitions(partitions []TopicPartition) {
+	sort.Slice(partitions, func(i, j int) bool {
+		if partitions[i].Topic != partitions[j].Topic {
+			return partitions[i].Topic < partitions[j].Topic
+		}
+		return partitions[i].Partition < partitions[j].Partition
+	})
+}
+
+// memorySubscriber is a consumer group member of a MemoryBroker.
+type memorySubscriber struct {
+	broker   *MemoryBroker
+	group    *memoryGroup
+	topics   []string
+	callback RebalanceCallback
+	closed   bool
+
+	// assigned maps owned partitions to the next offset to read.
+	assigned map[TopicPartition]int64
+	paused   map[TopicPartition]bool
+	// newly assigned partitions and revocations not yet reported to callback.
+	announce []TopicPartition
+	revoking map[TopicPartition]bool
+	cursor   int
+}
+
+// assign hands tp to the subscriber, reading from offset. Callers hold b.mu.
+func (s *memorySubscriber) assign(tp TopicPartition, offset int64) {
+	s.assigned[tp] = offset
+	s.announce = append(s.announce, tp)
+}
+
+// revoke asks the subscriber to give up tp. A partition whose assignment was
+// never reported is released right away. Callers hold b.mu.
+func (s *memorySubscriber) revoke(tp TopicPartition) {
+	for i, announced := range s.announce {
+		if announced == tp {
+			s.announce = append(s.announce[:i], s.announce[i+1:]...)
+			s.release(tp)
+			return
+		}
+	}
+	s.revoking[tp] = true
+}
+
+// release drops tp from the subscriber and the group ownership. Callers hold b.mu.
+func (s *memorySubscriber) release(tp TopicPartition) {
+	delete(s.assigned, tp)
+	delete(s.paused, tp)
+	delete(s.revoking, tp)
+	if s.group.owners[tp] == s {
+		delete(s.group.owners, tp)
+	}
+}
+
+func (s *memorySubscriber) Subscribe(topics []string, callback RebalanceCallback) error {
+	b := s.broker
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if s.closed {
+		return ErrSubscriberClosed
+	}
+	if s.topics == nil {
+		s.group.members = append(s.group.members, s)
+	}
+	s.topics = append([]string(nil), topics...)
+	s.callback = callback
+
+	b.rebalance(s.group)
+	return nil
+}
+
+func (s *memorySubscriber) Poll(timeout time.Duration) (*Record, error) {
+	b := s.broker
+	deadline := time.Now().Add(timeout)
+
+	for {
+		b.mu.Lock()
+		if s.closed {
+			b.mu.Unlock()
+			return nil, Fatal(ErrSubscriberClosed)
+		}
+
+		if len(s.revoking) > 0 || len(s.announce) > 0 {
+			event := RebalanceEvent{Assigned: s.announce}
+			for tp := range s.revoking {
+				event.Revoked = append(event.Revoked, tp)
+			}
+			sortPartitions(event.Revoked)
+			s.announce = nil
+			callback := s.callback
+			b.mu.Unlock()
+
+			if callback != nil {
+				callback(event)
+			}
+
+			b.mu.Lock()
+			for _, tp := range event.Revoked {
+				s.release(tp)
+			}
+			if len(event.Revoked) > 0 {
+				b.rebalance(s.group)
+			}
+			b.mu.Unlock()
+			continue
+		}
+
+		if record := s.next(); record != nil {
+			b.mu.Unlock()
+			return record, nil
+		}
+
+		changed := b.changed
+		b.mu.Unlock()
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return nil, nil
+		}
+		timer := time.NewTimer(remaining)
+		select {
+		case <-changed:
+			timer.Stop()
+		case <-timer.C:
+			return nil, nil
+		}
+	}
+}
+
+// next returns the next readable record, rotating over the owned partitions so
+// a busy partition does not starve the others. Callers hold b.mu.
+func (s *memorySubscriber) next() *Record {
+	partitions := make([]TopicPartition, 0, len(s.assigned))
+	for tp := range s.assigned {
+		if !s.paused[tp] && !s.revoking[tp] {
+			partitions = append(partitions, tp)
+		}
+	}
+	sortPartitions(partitions)
+
+	for i := range partitions {
+		tp := partitions[(s.cursor+i)%len(partitions)]
+		log := s.broker.topics[tp.Topic].partitions[tp.Partition]
+		offset := s.assigned[tp]
+		if offset >= int64(len(log)) {
+			continue
+		}
+
+		s.assigned[tp] = offset + 1
+		s.cursor = (s.cursor + i + 1) % len(partitions)
+		record := *log[offset]
+		return &record
+	}
+
+	return nil
+}
+
+func (s *memorySubscriber) Commit(tp TopicPartition, offset int64) error {
+	b := s.broker
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if s.group.owners[tp] != s {
+		return fmt.Errorf("%s is not assigned to this subscriber", tp)
+	}
+	s.group.committed[tp] = offset
+	return nil
+}
+
+func (s *memorySubscriber) Pause(tp TopicPartition) error {
+	return s.update(tp, func() { s.paused[tp] = true })
+}
+
+func (s *memorySubscriber) Resume(tp TopicPartition) error {
+	return s.update(tp, func() { delete(s.paused, tp) })
+}
+
+func (s *memorySubscriber) Seek(tp TopicPartition, offset int64) error {
+	return s.update(tp, func() { s.assigned[tp] = offset })
+}
+
+// update applies fn to an owned partition and wakes pollers.
+func (s *memorySubscriber) update(tp TopicPartition, fn func()) error {
+	b := s.broker
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := s.assigned[tp]; !ok {
+		return fmt.Errorf("%s is not assigned to this subscriber", tp)
+	}
+	fn()
+	b.notify()
+	return nil
+}
+
+// Close leaves the group. Like a Kafka consumer, the owned partitions are
+// reported as revoked first so they can be committed.
+func (s *memorySubscriber) Close() error {
+	b := s.broker
+	b.mu.Lock()
+	if s.closed {
+		b.mu.Unlock()
+		return nil
+	}
+
+	var revoked []TopicPartition
+	for tp := range s.assigned {
+		revoked = append(revoked, tp)
+	}
+	sortPartitions(revoked)
+	callback := s.callback
+	b.mu.Unlock()
+
+	if callback != nil && len(revoked) > 0 {
+		callback(RebalanceEvent{Revoked: revoked})
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	s.closed = true
+	for tp := range s.assigned {
+		s.release(tp)
+	}
+	s.announce = nil
+	for i, m := range s.group.members {
+		if m == s {
+			s.group.members = append(s.group.members[:i], s.group.members[i+1:]...)
+			break
+		}
+	}
+	b.rebalance(s.group)
+	return nil
+}
+
+// memoryProducer publishes to a MemoryBroker. Delivery is synchronous.
+type memoryProducer struct {
+	broker *MemoryBroker
+	topic  string
+}
+
+func (p *memoryProducer) PublishTransaction(ctx context.Context, msg *TransactionMessage) error {
+	message, err := newTransactionCreatedMessage(msg)
+	if err != nil {
+		return err
+	}
+	return p.Publish(ctx, message)
+}
+
+func (p *memoryProducer) Publish(ctx context.Context, msg *Message) error {
+	return p.PublishBatch(ctx, []*Message{msg})
+}
+
+func (p *memoryProducer) PublishBatch(ctx context.Context, msgs []*Message) error {
+	start := time.Now()
+	if err := ctx.Err(); err != nil {
+		for _, msg := range msgs {
+			recordPublish(p.topicOf(msg), start, "timeout")
+		}
+		return err
+	}
+
+	p.broker.mu.Lock()
+	defer p.broker.mu.Unlock()
+
+	for _, msg := range msgs {
+		topic := p.topicOf(msg)
+		p.broker.publish(topic, msg)
+		recordPublish(topic, start, "")
+	}
+	return nil
+}
+
+// topicOf is the topic msg is published to: its own, or the producer's.
+func (p *memoryProducer) topicOf(msg *Message) string {
+	if msg.Topic != "" {
+		return msg.Topic
+	}
+	return p.topic
+}
+
+func (p *memoryProducer) Close() {
+	// Nothing to flush: delivery is synchronous.
+}
+
+// memoryInspector reports a MemoryBroker as a single broker, ID 0, leading
+// every partition.
+type memoryInspector struct {
+	broker  *MemoryBroker
+	groupID string
+}
+
+func (i *memoryInspector) Partitions(ctx context.Context, topic string) ([]PartitionInfo, error) {
+	b := i.broker
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	t, ok := b.topics[topic]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrTopicNotFound, topic)
+	}
+
+	partitions := make([]PartitionInfo, 0, len(t.partitions))
+	for p := range t.partitions {
+		partitions = append(partitions, PartitionInfo{Partition: int32(p), Leader: 0})
+	}
+	return partitions, nil
+}
+
+func (i *memoryInspector) Lag(ctx context.Context, topic string) ([]PartitionLag, error) {
+	b := i.broker
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	t, ok := b.topics[topic]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrTopicNotFound, topic)
+	}
+
+	lags := make([]PartitionLag, 0, len(t.partitions))
+	for p, records := range t.partitions {
+		committed := int64(-1)
+		if g, ok := b.groups[i.groupID]; ok {
+			if offset, ok := g.committed[TopicPartition{Topic: topic, Partition: int32(p)}]; ok {
+				committed = offset
+			}
+		}
+		lags = append(lags, lag(int32(p), committed, 0, int64(len(records))))
+	}
+	return lags, nil
+}
+
+func (i *memoryInspector) Close() error {
+	return nil
+}
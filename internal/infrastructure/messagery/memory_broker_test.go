@@ -0,0 +1,214 @@
+package messagery
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryBrokerPartitionsByKey(t *testing.T) {
+	broker := NewMemoryBroker(4)
+	producer, _ := broker.NewProducer("transactions")
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, producer.Publish(ctx, &Message{Key: "a", Value: []byte{byte(i)}}))
+		require.NoError(t, producer.Publish(ctx, &Message{Key: "b", Value: []byte{byte(i)}}))
+	}
+
+	messages := broker.Messages("transactions")
+	require.Len(t, messages, 6)
+
+	byKey := map[string][]Record{}
+	for _, m := range messages {
+		byKey[string(m.Key)] = append(byKey[string(m.Key)], m)
+	}
+	for key, records := range byKey {
+		for i, r := range records {
+			assert.Equal(t, records[0].Partition, r.Partition, "key %s stays on one partition", key)
+			assert.Equal(t, []byte{byte(i)}, r.Value, "key %s keeps publish order", key)
+		}
+	}
+}
+
+func TestMemoryProducerMetrics(t *testing.T) {
+	broker := NewMemoryBroker(1)
+	producer, _ := broker.NewProducer("memory-metrics")
+
+	published := messagePublished.WithLabelValues("memory-metrics")
+	redirected := messagePublished.WithLabelValues("memory-metrics.other")
+	timedOut := messagePublishErrors.WithLabelValues("memory-metrics", "timeout")
+
+	require.NoError(t, producer.PublishBatch(context.Background(), []*Message{
+		{Key: "a"},
+		{Key: "b", Topic: "memory-metrics.other"},
+	}))
+	assert.Equal(t, float64(1), testutil.ToFloat64(published))
+	assert.Equal(t, float64(1), testutil.ToFloat64(redirected))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Error(t, producer.Publish(ctx, &Message{Key: "c"}))
+	assert.Equal(t, float64(1), testutil.ToFloat64(timedOut))
+	assert.Equal(t, float64(1), testutil.ToFloat64(published))
+}
+
+func TestMemoryBrokerCommitAndResume(t *testing.T) {
+	broker := NewMemoryBroker(1)
+	producer, _ := broker.NewProducer("transactions")
+	ctx := context.Background()
+	tp := TopicPartition{Topic: "transactions", Partition: 0}
+
+	for _, key := range []string{"a", "b", "c"} {
+		producer.Publish(ctx, &Message{Key: key})
+	}
+
+	first, _ := broker.NewSubscriber("group")
+	require.NoError(t, first.Subscribe([]string{"transactions"}, nil))
+
+	r, err := first.Poll(time.Second)
+	require.NoError(t, err)
+	require.NotNil(t, r)
+	assert.Equal(t, int64(0), r.Offset)
+	require.NoError(t, first.Commit(tp, r.Offset+1))
+
+	r, _ = first.Poll(time.Second)
+	assert.Equal(t, int64(1), r.Offset, "read but not committed")
+	require.NoError(t, first.Close())
+
+	assert.Equal(t, int64(1), broker.Committed("group", tp))
+	assert.Len(t, broker.Consumed("group", "transactions"), 1)
+
+	second, _ := broker.NewSubscriber("group")
+	require.NoError(t, second.Subscribe([]string{"transactions"}, nil))
+	r, _ = second.Poll(time.Second)
+	require.NotNil(t, r)
+	assert.Equal(t, int64(1), r.Offset, "a new member resumes from the committed offset")
+
+	other, _ := broker.NewSubscriber("other")
+	require.NoError(t, other.Subscribe([]string{"transactions"}, nil))
+	r, _ = other.Poll(time.Second)
+	assert.Equal(t, int64(0), r.Offset, "groups are independent")
+
+	r, err = other.Poll(10 * time.Millisecond)
+	require.NoError(t, err)
+	require.NotNil(t, r)
+	r, _ = other.Poll(10 * time.Millisecond)
+	require.NotNil(t, r)
+	r, _ = other.Poll(10 * time.Millisecond)
+	assert.Nil(t, r, "nothing left to read")
+}
+
+func TestMemoryBrokerRebalance(t *testing.T) {
+	broker := NewMemoryBroker(4)
+	topics := []string{"transactions"}
+
+	owned := func(events *[]RebalanceEvent) map[int32]bool {
+		partitions := map[int32]bool{}
+		for _, e := range *events {
+			for _, tp := range e.Assigned {
+				partitions[tp.Partition] = true
+			}
+			for _, tp := range e.Revoked {
+				delete(partitions, tp.Partition)
+			}
+		}
+		return partitions
+	}
+
+	var firstEvents, secondEvents []RebalanceEvent
+	first, _ := broker.NewSubscriber("group")
+	first.Subscribe(topics, func(e RebalanceEvent) { firstEvents = append(firstEvents, e) })
+	first.Poll(10 * time.Millisecond)
+	assert.Len(t, owned(&firstEvents), 4)
+
+	second, _ := broker.NewSubscriber("group")
+	second.Subscribe(topics, func(e RebalanceEvent) { secondEvents = append(secondEvents, e) })
+
+	second.Poll(10 * time.Millisecond)
+	assert.Empty(t, owned(&secondEvents), "partitions move only after the old owner revoked them")
+
+	first.Poll(10 * time.Millisecond)
+	second.Poll(10 * time.Millisecond)
+	assert.Len(t, owned(&firstEvents), 2)
+	assert.Len(t, owned(&secondEvents), 2)
+
+	second.Close()
+	assert.Empty(t, owned(&secondEvents), "closing revokes every partition")
+	first.Poll(10 * time.Millisecond)
+	assert.Len(t, owned(&firstEvents), 4)
+}
+
+func TestConsumerOnMemoryBroker(t *testing.T) {
+	broker := NewMemoryBroker(2)
+	producer, _ := broker.NewProducer("transactions")
+	subscriber, _ := broker.NewSubscriber("group")
+
+	good, flaky, missing := uuid.New(), uuid.New(), uuid.New()
+
+	var mu sync.Mutex
+	handled := map[uuid.UUID]int{}
+	dispatcher := NewDispatcher()
+	dispatcher.Register(EventTransactionCreated, HandleData(func(ctx context.Context, _ *Event, msg *TransactionCreated) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		handled[msg.ID]++
+		switch msg.ID {
+		case flaky:
+			if handled[msg.ID] == 1 {
+				return errors.New("database hiccup")
+			}
+		case missing:
+			return Permanent(errors.New("transaction not found"))
+		}
+		return nil
+	}))
+
+	consumer := NewConsumer(subscriber, "transactions", dispatcher,
+		WithDeadLetterTopic(producer, "transactions.dlq"),
+		WithRetry(producer, RetryPolicy{
+			Tiers:       []RetryTier{{Topic: "transactions.retry", Delay: 50 * time.Millisecond}},
+			MaxAttempts: 3,
+		}),
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- consumer.Start(ctx) }()
+
+	for _, id := range []uuid.UUID{good, flaky, missing} {
+		require.NoError(t, producer.PublishTransaction(ctx, &TransactionMessage{ID: id}))
+	}
+	producer.Publish(ctx, &Message{Key: "garbage", Value: []byte("not json")})
+
+	assert.Eventually(t, func() bool {
+		return len(broker.Consumed("group", "transactions")) == 4 &&
+			len(broker.Consumed("group", "transactions.retry")) == 1
+	}, 2*time.Second, 10*time.Millisecond)
+
+	cancel()
+	require.NoError(t, <-done)
+	consumer.Close()
+
+	mu.Lock()
+	assert.Equal(t, map[uuid.UUID]int{good: 1, flaky: 2, missing: 1}, handled)
+	mu.Unlock()
+
+	retried := broker.Messages("transactions.retry")
+	require.Len(t, retried, 1)
+	assert.Equal(t, flaky.String(), string(retried[0].Key))
+	assert.Equal(t, "1", retried[0].Headers[HeaderAttempts])
+
+	dead := broker.Messages("transactions.dlq")
+	require.Len(t, dead, 2)
+	keys := []string{string(dead[0].Key), string(dead[1].Key)}
+	assert.ElementsMatch(t, []string{missing.String(), "garbage"}, keys)
+}
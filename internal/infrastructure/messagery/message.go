@@ -3,10 +3,32 @@ package messagery
 import (
 	"time"
 
+	"github.com/Athla/vr-software-challenge/internal/domain/models"
 	"github.com/google/uuid"
 	"github.com/shopspring/decimal"
 )
 
+const (
+	// EventTransactionCreated is emitted once a transaction has been stored.
+	EventTransactionCreated = "transaction.created"
+	// EventTransactionStatusChanged is emitted whenever a transaction moves
+	// through its lifecycle, except to VOIDED.
+	EventTransactionStatusChanged = "transaction.status_changed"
+	// EventTransactionVoided is emitted when a transaction is voided so consumers
+	// can reverse whatever they did with it.
+	EventTransactionVoided = "transaction.voided"
+	// EventTransactionConverted is emitted when a transaction amount is converted
+	// to another currency.
+	EventTransactionConverted = "transaction.converted"
+
+	HeaderVersion   = "version"
+	HeaderEventType = "event_type"
+	// HeaderAttempts counts how many times consumers have failed to process the message.
+	HeaderAttempts = "attempts"
+)
+
+// TransactionMessage is the v1 payload of transaction.created, published bare
+// before events were enveloped. It is only read back through the upcaster.
 type TransactionMessage struct {
 	ID              uuid.UUID       `db:"id" json:"id"`
 	Description     string          `db:"description" json:"description"`
@@ -14,3 +36,74 @@ type TransactionMessage struct {
 	AmountUSD       decimal.Decimal `db:"amountusd" json:"amountusd"`
 	CreatedAt       time.Time       `db:"createdat" json:"createdat"`
 }
+
+// TransactionCreated is the data of transaction.created (v2).
+type TransactionCreated struct {
+	ID              uuid.UUID       `json:"id"`
+	Description     string          `json:"description"`
+	TransactionDate time.Time       `json:"transaction_date"`
+	AmountUSD       decimal.Decimal `json:"amount_usd"`
+	CreatedAt       time.Time       `json:"created_at"`
+}
+
+// NewTransactionCreated builds the event data published when a transaction is created.
+func NewTransactionCreated(tx *models.Transaction) *TransactionCreated {
+	return &TransactionCreated{
+		ID:              tx.ID,
+		Description:     tx.Description,
+		TransactionDate: tx.TransactionDate,
+		AmountUSD:       tx.AmountUSD,
+		CreatedAt:       tx.CreatedAt,
+	}
+}
+
+// TransactionStatusChanged is the data of transaction.status_changed. A move
+// to FAILED carries the processing step that failed and why.
+type TransactionStatusChanged struct {
+	ID            uuid.UUID                `json:"id"`
+	From          models.TransactionStatus `json:"from"`
+	To            models.TransactionStatus `json:"to"`
+	ChangedAt     time.Time                `json:"changed_at"`
+	FailedStep    string                   `json:"failed_step,omitempty"`
+	FailureReason string                   `json:"failure_reason,omitempty"`
+}
+
+// TransactionVoided is the data of transaction.voided, the compensating event
+// published when a transaction is voided.
+type TransactionVoided struct {
+	ID             uuid.UUID                `json:"id"`
+	PreviousStatus models.TransactionStatus `json:"previous_status"`
+	Reason         string                   `json:"reason"`
+	VoidedAt       time.Time                `json:"voided_at"`
+}
+
+// TransactionConverted is the data of transaction.converted.
+type TransactionConverted struct {
+	ID              uuid.UUID       `json:"id"`
+	TargetCurrency  string          `json:"target_currency"`
+	ExchangeRate    decimal.Decimal `json:"exchange_rate"`
+	ExchangeDate    time.Time       `json:"exchange_date"`
+	OriginalAmount  decimal.Decimal `json:"original_amount_usd"`
+	ConvertedAmount decimal.Decimal `json:"converted_amount"`
+}
+
+// NewTransactionConverted builds the event data for a currency conversion.
+func NewTransactionConverted(c *models.CurrencyConversion) *TransactionConverted {
+	return &TransactionConverted{
+		ID:              c.TransactionID,
+		TargetCurrency:  c.TargetCurrency,
+		ExchangeRate:    c.ExchangeRate,
+		ExchangeDate:    c.ExchangeDate,
+		OriginalAmount:  c.OriginalAmount,
+		ConvertedAmount: c.ConvertedAmount,
+	}
+}
+
+// Message is an already encoded record ready to be written to the broker.
+// An empty Topic means the producer's default topic.
+type Message struct {
+	Topic   string
+	Key     string
+	Value   []byte
+	Headers map[string]string
+}
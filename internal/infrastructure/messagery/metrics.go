@@ -1,23 +1,72 @@
 package messagery
 
 import (
+	"time"
+
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 )
 
 var (
-	messagePublished = promauto.NewCounter(prometheus.CounterOpts{
+	messagePublished = promauto.NewCounterVec(prometheus.CounterOpts{
 		Name: "kafka_messages_published_total",
 		Help: "The total number of published messages",
-	})
+	}, []string{"topic"})
 
-	messagePublishErrors = promauto.NewCounter(prometheus.CounterOpts{
+	// messagePublishErrors counts messages the broker did not acknowledge, by
+	// reason: enqueue (rejected by the client), delivery (failed delivery
+	// report) or timeout (no report before the context expired).
+	messagePublishErrors = promauto.NewCounterVec(prometheus.CounterOpts{
 		Name: "kafka_messages_publish_errors_total",
 		Help: "The total number of message publish errors",
-	})
+	}, []string{"topic", "reason"})
+
+	messagePublishDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "kafka_messages_publish_duration_seconds",
+		Help:    "Time from producing a message to its delivery report",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"topic"})
 
-	messageProcessed = promauto.NewCounter(prometheus.CounterOpts{
+	// messageProcessed counts consumed messages by outcome: handled, retried
+	// (moved to a retry tier), dead_lettered or unhandled (left to be read again).
+	messageProcessed = promauto.NewCounterVec(prometheus.CounterOpts{
 		Name: "kafka_messages_processed_total",
 		Help: "The total number of processed messages",
-	})
+	}, []string{"topic", "event_type", "outcome"})
+
+	messageHandlerDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "kafka_message_handler_duration_seconds",
+		Help:    "Time spent handling an event, including failed attempts",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"event_type"})
+
+	messageEndToEndLatency = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "kafka_message_end_to_end_latency_seconds",
+		Help:    "Time from an event being created to it being handled",
+		Buckets: []float64{.05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60, 300, 900},
+	}, []string{"event_type"})
+
+	consumerLag = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "kafka_consumer_lag",
+		Help: "Messages between the committed offset of the consumer group and the end of the partition",
+	}, []string{"topic", "partition"})
+)
+
+// Outcomes of a consumed message.
+const (
+	outcomeHandled      = "handled"
+	outcomeRetried      = "retried"
+	outcomeDeadLettered = "dead_lettered"
+	outcomeUnhandled    = "unhandled"
 )
+
+// recordPublish records the outcome of publishing a message to topic; an
+// empty reason means it was delivered.
+func recordPublish(topic string, start time.Time, reason string) {
+	messagePublishDuration.WithLabelValues(topic).Observe(time.Since(start).Seconds())
+	if reason != "" {
+		messagePublishErrors.WithLabelValues(topic, reason).Inc()
+		return
+	}
+	messagePublished.WithLabelValues(topic).Inc()
+}
@@ -8,6 +8,7 @@ import (
 type MockProducer struct {
 	mu       sync.Mutex
 	messages []TransactionMessage
+	raw      []Message
 }
 
 func NewMockProducer() Producerer {
@@ -24,6 +25,24 @@ func (m *MockProducer) PublishTransaction(ctx context.Context, msg *TransactionM
 	return nil
 }
 
+func (m *MockProducer) Publish(ctx context.Context, msg *Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.raw = append(m.raw, *msg)
+	return nil
+}
+
+func (m *MockProducer) PublishBatch(ctx context.Context, msgs []*Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, msg := range msgs {
+		m.raw = append(m.raw, *msg)
+	}
+	return nil
+}
+
 func (m *MockProducer) Close() {
 	// No-op for mock
 }
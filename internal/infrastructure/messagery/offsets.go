@@ -0,0 +1,65 @@
+package messagery
+
+type offsetState int
+
+const (
+	offsetInFlight offsetState = iota
+	offsetDone
+	offsetFailed
+)
+
+// offsetTracker follows the records of one partition that were handed to
+// workers. Workers finish out of order, so the committable offset only
+// advances over a contiguous run of done records. A record that failed
+// without being handed on stops the partition from committing past it, so it
+// is read again after a restart or rebalance.
+type offsetTracker struct {
+	offsets  []int64
+	states   map[int64]offsetState
+	inFlight int
+}
+
+func newOffsetTracker() *offsetTracker {
+	return &offsetTracker{
+		states: map[int64]offsetState{},
+	}
+}
+
+// add records that offset, read after every offset added so far, is in flight.
+func (t *offsetTracker) add(offset int64) {
+	t.offsets = append(t.offsets, offset)
+	t.states[offset] = offsetInFlight
+	t.inFlight++
+}
+
+// complete marks offset as done, or failed when handled is false, and returns
+// the offset to commit when the contiguous run of done records grew.
+func (t *offsetTracker) complete(offset int64, handled bool) (int64, bool) {
+	if state, ok := t.states[offset]; !ok || state != offsetInFlight {
+		return 0, false
+	}
+	t.inFlight--
+	if !handled {
+		t.states[offset] = offsetFailed
+		return 0, false
+	}
+	t.states[offset] = offsetDone
+
+	n := 0
+	for n < len(t.offsets) && t.states[t.offsets[n]] == offsetDone {
+		delete(t.states, t.offsets[n])
+		n++
+	}
+	if n == 0 {
+		return 0, false
+	}
+
+	next := t.offsets[n-1] + 1
+	t.offsets = t.offsets[n:]
+	return next, true
+}
+
+// busy reports whether a worker is still handling a record of the partition.
+func (t *offsetTracker) busy() bool {
+	return t.inFlight > 0
+}
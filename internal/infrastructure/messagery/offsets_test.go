@@ -0,0 +1,32 @@
+package messagery
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOffsetTracker(t *testing.T) {
+	tracker := newOffsetTracker()
+	for _, offset := range []int64{4, 5, 6, 9} {
+		tracker.add(offset)
+	}
+
+	_, ok := tracker.complete(5, true)
+	assert.False(t, ok, "offset 4 is still in flight")
+
+	next, ok := tracker.complete(4, true)
+	assert.True(t, ok)
+	assert.Equal(t, int64(6), next, "4 and 5 are done")
+
+	_, ok = tracker.complete(4, true)
+	assert.False(t, ok, "completing twice is ignored")
+
+	_, ok = tracker.complete(6, false)
+	assert.False(t, ok)
+	assert.True(t, tracker.busy())
+
+	_, ok = tracker.complete(9, true)
+	assert.False(t, ok, "a failed record blocks the offsets after it")
+	assert.False(t, tracker.busy())
+}
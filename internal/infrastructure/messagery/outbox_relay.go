@@ -0,0 +1,121 @@
+package messagery
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/Athla/vr-software-challenge/internal/domain/models"
+	"github.com/charmbracelet/log"
+)
+
+// OutboxStore is the source of messages the relay forwards to the broker.
+type OutboxStore interface {
+	ProcessPending(ctx context.Context, limit int, fn func(context.Context, []models.OutboxMessage) error) (int, error)
+}
+
+// OutboxRelay publishes messages written to the transactional outbox. A message
+// is marked sent only after it has been handed to the producer, so a crash in
+// between results in a redelivery rather than a lost message (at-least-once).
+type OutboxRelay struct {
+	store     OutboxStore
+	producer  Producerer
+	interval  time.Duration
+	batchSize int
+}
+
+type RelayOption func(*OutboxRelay)
+
+func WithRelayInterval(interval time.Duration) RelayOption {
+	return func(r *OutboxRelay) {
+		r.interval = interval
+	}
+}
+
+func WithRelayBatchSize(size int) RelayOption {
+	return func(r *OutboxRelay) {
+		r.batchSize = size
+	}
+}
+
+func NewOutboxRelay(store OutboxStore, producer Producerer, opts ...RelayOption) *OutboxRelay {
+	r := &OutboxRelay{
+		store:     store,
+		producer:  producer,
+		interval:  time.Second,
+		batchSize: 100,
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r
+}
+
+// Start relays outbox messages until the context is cancelled. Full batches are
+// followed immediately by another one so a backlog drains without waiting for
+// the next tick.
+func (r *OutboxRelay) Start(ctx context.Context) error {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		sent, err := r.RelayBatch(ctx)
+		if err != nil {
+			log.Warnf("Unable to relay outbox messages due: %s", err)
+		}
+
+		if err == nil && sent == r.batchSize {
+			if ctx.Err() != nil {
+				return nil
+			}
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// RelayBatch publishes a single batch of pending outbox messages and returns
+// how many were sent.
+func (r *OutboxRelay) RelayBatch(ctx context.Context) (int, error) {
+	return r.store.ProcessPending(ctx, r.batchSize, r.publish)
+}
+
+// publish sends a whole outbox batch to the broker in one go.
+func (r *OutboxRelay) publish(ctx context.Context, messages []models.OutboxMessage) error {
+	batch := make([]*Message, 0, len(messages))
+	for _, msg := range messages {
+		batch = append(batch, &Message{
+			Key:     msg.AggregateID.String(),
+			Value:   msg.Payload,
+			Headers: outboxHeaders(msg),
+		})
+	}
+
+	if err := r.producer.PublishBatch(ctx, batch); err != nil {
+		return fmt.Errorf("publish %d outbox messages: %w", len(batch), err)
+	}
+
+	return nil
+}
+
+// outboxHeaders derives the message headers from the stored envelope. Rows
+// written before events were enveloped hold a bare v1 payload.
+func outboxHeaders(msg models.OutboxMessage) map[string]string {
+	var event Event
+	if err := json.Unmarshal(msg.Payload, &event); err == nil && event.SpecVersion != "" {
+		return event.headers()
+	}
+
+	return map[string]string{
+		HeaderVersion:   "1",
+		HeaderEventType: msg.EventType,
+	}
+}
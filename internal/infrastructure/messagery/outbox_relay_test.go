@@ -0,0 +1,81 @@
+package messagery
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Athla/vr-software-challenge/internal/domain/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeOutboxStore struct {
+	pending []models.OutboxMessage
+	sent    []models.OutboxMessage
+}
+
+func (s *fakeOutboxStore) ProcessPending(ctx context.Context, limit int, fn func(context.Context, []models.OutboxMessage) error) (int, error) {
+	batch := s.pending
+	if len(batch) > limit {
+		batch = batch[:limit]
+	}
+	if len(batch) == 0 {
+		return 0, nil
+	}
+	if err := fn(ctx, batch); err != nil {
+		return 0, err
+	}
+	s.sent = append(s.sent, batch...)
+	s.pending = s.pending[len(batch):]
+	return len(batch), nil
+}
+
+type failingProducer struct {
+	MockProducer
+}
+
+func (p *failingProducer) PublishBatch(ctx context.Context, msgs []*Message) error {
+	return errors.New("broker unavailable")
+}
+
+func TestOutboxRelay_RelayBatch(t *testing.T) {
+	store := &fakeOutboxStore{}
+	for i := 0; i < 3; i++ {
+		store.pending = append(store.pending, models.OutboxMessage{
+			ID:          int64(i + 1),
+			AggregateID: uuid.New(),
+			EventType:   EventTransactionCreated,
+			Payload:     []byte(`{}`),
+		})
+	}
+
+	producer := &MockProducer{}
+	relay := NewOutboxRelay(store, producer, WithRelayBatchSize(2))
+
+	sent, err := relay.RelayBatch(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 2, sent)
+
+	sent, err = relay.RelayBatch(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, sent)
+
+	assert.Len(t, producer.raw, 3)
+	assert.Equal(t, store.sent[0].AggregateID.String(), producer.raw[0].Key)
+	assert.Equal(t, EventTransactionCreated, producer.raw[0].Headers[HeaderEventType])
+}
+
+func TestOutboxRelay_RelayBatchKeepsUnsentOnFailure(t *testing.T) {
+	store := &fakeOutboxStore{
+		pending: []models.OutboxMessage{{ID: 1, AggregateID: uuid.New(), EventType: EventTransactionCreated}},
+	}
+
+	relay := NewOutboxRelay(store, &failingProducer{})
+
+	sent, err := relay.RelayBatch(context.Background())
+	assert.Error(t, err)
+	assert.Equal(t, 0, sent)
+	assert.Len(t, store.pending, 1)
+	assert.Empty(t, store.sent)
+}
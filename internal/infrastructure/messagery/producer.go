@@ -2,8 +2,8 @@ package messagery
 
 import (
 	"context"
-	"encoding/json"
-	"strings"
+	"fmt"
+	"time"
 
 	"github.com/charmbracelet/log"
 	"github.com/confluentinc/confluent-kafka-go/kafka"
@@ -11,6 +11,8 @@ import (
 
 type Producerer interface {
 	PublishTransaction(ctx context.Context, msg *TransactionMessage) error
+	Publish(ctx context.Context, msg *Message) error
+	PublishBatch(ctx context.Context, msgs []*Message) error
 	Close()
 }
 type Producer struct {
@@ -18,28 +20,25 @@ type Producer struct {
 	topic    string
 }
 
-func NewProducer(brokers []string, topic string) (*Producer, error) {
-	p, err := kafka.NewProducer(&kafka.ConfigMap{
-		"bootstrap.servers":  strings.Join(brokers, ","),
+func NewProducer(brokers []string, topic string, opts ...KafkaOption) (*Producer, error) {
+	p, err := kafka.NewProducer(newKafkaConfigMap(brokers, kafka.ConfigMap{
 		"acks":               "all",
 		"retries":            5,
 		"retry.backoff.ms":   500,
 		"linger.ms":          10,
 		"compression.type":   "snappy",
 		"enable.idempotence": true,
-	})
+	}, opts))
 	if err != nil {
 		log.Errorf("Unable to create Kafka producer due: %v", err)
 		return nil, err
 	}
+	// Every Produce call passes its own delivery channel, so only client-level
+	// events such as broker errors end up here.
 	go func() {
 		for e := range p.Events() {
-			switch ev := e.(type) {
-			case *kafka.Message:
-				if ev.TopicPartition.Error != nil {
-
-					log.Errorf("Delivery in the queue failed due: %v", ev.TopicPartition.Error)
-				}
+			if err, ok := e.(kafka.Error); ok {
+				log.Errorf("Kafka producer error: %v", err)
 			}
 		}
 	}()
@@ -51,25 +50,162 @@ func NewProducer(brokers []string, topic string) (*Producer, error) {
 }
 
 func (p *Producer) PublishTransaction(ctx context.Context, msg *TransactionMessage) error {
-	payload, err := json.Marshal(msg)
+	message, err := newTransactionCreatedMessage(msg)
 	if err != nil {
-		log.Errorf("Unable to marshal message due: %v", err)
 		return err
 	}
 
-	if err = p.producer.Produce(&kafka.Message{
-		TopicPartition: kafka.TopicPartition{Topic: &p.topic, Partition: kafka.PartitionAny},
-		Key:            []byte(msg.ID.String()),
-		Value:          payload,
-		Headers:        []kafka.Header{{Key: "version", Value: []byte("1")}},
-	}, nil); err != nil {
-		log.Errorf("Unable to produce message due: %s", err)
-		return err
+	return p.Publish(ctx, message)
+}
+
+// newTransactionCreatedMessage encodes a transaction as its creation event.
+func newTransactionCreatedMessage(msg *TransactionMessage) (*Message, error) {
+	event, err := NewEvent(EventTransactionCreated, msg.ID.String(), TransactionCreated{
+		ID:              msg.ID,
+		Description:     msg.Description,
+		TransactionDate: msg.TransactionDate,
+		AmountUSD:       msg.AmountUSD,
+		CreatedAt:       msg.CreatedAt,
+	})
+	if err != nil {
+		log.Errorf("Unable to build event due: %v", err)
+		return nil, err
+	}
+
+	message, err := event.Message()
+	if err != nil {
+		log.Errorf("Unable to marshal message due: %v", err)
+		return nil, err
+	}
+
+	return message, nil
+}
+
+// DeliveryError reports a message the broker did not acknowledge. Partition
+// and Offset are those of the delivery report, or -1 when there was none.
+type DeliveryError struct {
+	Topic     string
+	Partition int32
+	Offset    int64
+	Err       error
+}
+
+func (e *DeliveryError) Error() string {
+	return fmt.Sprintf("delivery to %s[%d]@%d failed: %v", e.Topic, e.Partition, e.Offset, e.Err)
+}
+
+func (e *DeliveryError) Unwrap() error { return e.Err }
+
+// Publish writes an already encoded message to its topic, or to the producer
+// topic when the message does not name one, and waits for the broker to
+// acknowledge it. The wait is bounded by ctx; a message whose delivery was not
+// confirmed in time may still be delivered later.
+func (p *Producer) Publish(ctx context.Context, msg *Message) error {
+	return p.PublishBatch(ctx, []*Message{msg})
+}
+
+// PublishBatch enqueues every message at once and waits for the broker to
+// acknowledge all of them, returning the first delivery error.
+func (p *Producer) PublishBatch(ctx context.Context, msgs []*Message) error {
+	if len(msgs) == 0 {
+		return nil
+	}
+
+	// Each message carries its index as opaque, which the delivery report
+	// echoes back, so the messages still unconfirmed are known on timeout.
+	start := time.Now()
+	deliveries := make(chan kafka.Event, len(msgs))
+	pending := make(map[int]string, len(msgs))
+	for i, msg := range msgs {
+		km := p.kafkaMessage(msg)
+		km.Opaque = i
+		topic := *km.TopicPartition.Topic
+		if err := p.producer.Produce(km, deliveries); err != nil {
+			log.Errorf("Unable to produce message due: %s", err)
+			recordPublish(topic, start, "enqueue")
+			return &DeliveryError{Topic: topic, Partition: -1, Offset: -1, Err: err}
+		}
+		pending[i] = topic
+	}
+
+	var firstErr error
+	for len(pending) > 0 {
+		select {
+		case <-ctx.Done():
+			for _, topic := range pending {
+				recordPublish(topic, start, "timeout")
+				if firstErr == nil {
+					firstErr = &DeliveryError{Topic: topic, Partition: -1, Offset: -1, Err: ctx.Err()}
+				}
+			}
+			log.Errorf("Unable to confirm delivery of %d messages due: %v", len(pending), ctx.Err())
+			return firstErr
+		case e := <-deliveries:
+			m, ok := e.(*kafka.Message)
+			if !ok {
+				continue
+			}
+			if i, ok := m.Opaque.(int); ok {
+				delete(pending, i)
+			}
+
+			if err := deliveryResult(m); err != nil {
+				recordPublish(*m.TopicPartition.Topic, start, "delivery")
+				if firstErr == nil {
+					firstErr = err
+				}
+				continue
+			}
+			recordPublish(*m.TopicPartition.Topic, start, "")
+			log.Debugf("Delivered message to %s[%d]@%d", *m.TopicPartition.Topic, m.TopicPartition.Partition, m.TopicPartition.Offset)
+		}
+	}
+	if firstErr != nil {
+		log.Errorf("Delivery in the queue failed due: %v", firstErr)
+		return firstErr
 	}
 
 	return nil
 }
 
+// deliveryResult converts a delivery report into a DeliveryError, or nil when
+// the message was acknowledged.
+func deliveryResult(m *kafka.Message) error {
+	if m.TopicPartition.Error == nil {
+		return nil
+	}
+
+	topic := ""
+	if m.TopicPartition.Topic != nil {
+		topic = *m.TopicPartition.Topic
+	}
+	return &DeliveryError{
+		Topic:     topic,
+		Partition: m.TopicPartition.Partition,
+		Offset:    int64(m.TopicPartition.Offset),
+		Err:       m.TopicPartition.Error,
+	}
+}
+
+func (p *Producer) kafkaMessage(msg *Message) *kafka.Message {
+	headers := make([]kafka.Header, 0, len(msg.Headers))
+	for k, v := range msg.Headers {
+		headers = append(headers, kafka.Header{Key: k, Value: []byte(v)})
+	}
+
+	topic := p.topic
+	if msg.Topic != "" {
+		topic = msg.Topic
+	}
+
+	return &kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: kafka.PartitionAny},
+		Key:            []byte(msg.Key),
+		Value:          msg.Value,
+		Headers:        headers,
+	}
+}
+
 func (p *Producer) Close() {
 	p.producer.Flush(5 * 1000)
 	p.producer.Close()
@@ -0,0 +1,23 @@
+package messagery
+
+import (
+	"testing"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeliveryResult(t *testing.T) {
+	topic := "transactions"
+
+	assert.NoError(t, deliveryResult(&kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: 2, Offset: 41},
+	}))
+
+	cause := kafka.NewError(kafka.ErrMsgTimedOut, "Local: Message timed out", false)
+	err := deliveryResult(&kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: 2, Offset: kafka.OffsetInvalid, Error: cause},
+	})
+	assert.EqualError(t, err, "delivery to transactions[2]@-1001 failed: Local: Message timed out")
+	assert.ErrorIs(t, err, cause)
+}
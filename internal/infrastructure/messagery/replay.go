@@ -0,0 +1,45 @@
+package messagery
+
+import (
+	"context"
+	"time"
+)
+
+// Headers stamped on messages republished by the replay command, so consumers
+// can tell them apart from live traffic.
+const (
+	HeaderReplayID   = "replay_id"
+	HeaderReplayedAt = "replayed_at"
+)
+
+// NewReplayMessage encodes a transaction as a new creation event marked as
+// part of the replay run replayID.
+func NewReplayMessage(msg *TransactionMessage, replayID string, replayedAt time.Time) (*Message, error) {
+	message, err := newTransactionCreatedMessage(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	message.Headers[HeaderReplayID] = replayID
+	message.Headers[HeaderReplayedAt] = replayedAt.UTC().Format(time.RFC3339Nano)
+	return message, nil
+}
+
+// IsReplay reports whether a record was republished by the replay command.
+func IsReplay(r *Record) bool {
+	return r.Headers[HeaderReplayID] != ""
+}
+
+type replayKey struct{}
+
+// withReplay marks ctx as handling a replayed record.
+func withReplay(ctx context.Context) context.Context {
+	return context.WithValue(ctx, replayKey{}, true)
+}
+
+// IsReplayContext reports whether ctx is handling a record republished by the
+// replay command. The consumer marks the context it passes to event handlers.
+func IsReplayContext(ctx context.Context) bool {
+	replay, _ := ctx.Value(replayKey{}).(bool)
+	return replay
+}
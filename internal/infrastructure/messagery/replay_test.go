@@ -0,0 +1,73 @@
+package messagery
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewReplayMessage(t *testing.T) {
+	id := uuid.New()
+	at := time.Date(2024, 2, 1, 12, 0, 0, 0, time.UTC)
+
+	msg, err := NewReplayMessage(&TransactionMessage{ID: id, Description: "Office Supplies"}, "run-1", at)
+	require.NoError(t, err)
+	assert.Equal(t, id.String(), msg.Key)
+	assert.Equal(t, "run-1", msg.Headers[HeaderReplayID])
+	assert.Equal(t, "2024-02-01T12:00:00Z", msg.Headers[HeaderReplayedAt])
+	assert.Equal(t, EventTransactionCreated, msg.Headers[HeaderEventType])
+
+	record := &Record{Key: []byte(msg.Key), Value: msg.Value, Headers: msg.Headers}
+	assert.True(t, IsReplay(record))
+
+	event, err := DecodeEvent(record)
+	require.NoError(t, err)
+	var created TransactionCreated
+	require.NoError(t, event.DecodeData(&created))
+	assert.Equal(t, "Office Supplies", created.Description)
+
+	live, _ := newTransactionCreatedMessage(&TransactionMessage{ID: id})
+	assert.False(t, IsReplay(&Record{Headers: live.Headers}))
+}
+
+func TestConsumerMarksReplayedRecords(t *testing.T) {
+	broker := NewMemoryBroker(1)
+	producer, _ := broker.NewProducer("transactions")
+	subscriber, _ := broker.NewSubscriber("group")
+
+	var mu sync.Mutex
+	replayed := map[string]bool{}
+	dispatcher := NewDispatcher()
+	dispatcher.Register(EventTransactionCreated, HandleData(func(ctx context.Context, _ *Event, msg *TransactionCreated) error {
+		mu.Lock()
+		defer mu.Unlock()
+		replayed[msg.Description] = IsReplayContext(ctx)
+		return nil
+	}))
+
+	publishCreated(t, producer, uuid.New(), "live")
+	msg, err := NewReplayMessage(&TransactionMessage{ID: uuid.New(), Description: "replayed"}, "run-1", time.Now())
+	require.NoError(t, err)
+	require.NoError(t, producer.Publish(context.Background(), msg))
+
+	consumer := NewConsumer(subscriber, "transactions", dispatcher)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- consumer.Start(ctx) }()
+
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(replayed) == 2
+	}, 2*time.Second, 10*time.Millisecond)
+	cancel()
+	require.NoError(t, <-done)
+	consumer.Close()
+
+	assert.Equal(t, map[string]bool{"live": false, "replayed": true}, replayed)
+}
@@ -0,0 +1,103 @@
+package messagery
+
+import (
+	go_errors "errors"
+	"strconv"
+	"time"
+)
+
+// HeaderNotBefore holds the earliest time (RFC 3339) a retried message may be processed.
+const HeaderNotBefore = "not_before"
+
+// RetryTier is a retry topic whose messages are processed Delay after they failed.
+type RetryTier struct {
+	Topic string
+	Delay time.Duration
+}
+
+// RetryPolicy decides where a failed message goes next. A message is attempted
+// at most MaxAttempts times in total; the n-th retry goes to Tiers[n-1], and
+// once the tiers are exhausted the last tier is reused. Past MaxAttempts the
+// message is parked on the dead-letter topic.
+type RetryPolicy struct {
+	Tiers       []RetryTier
+	MaxAttempts int
+}
+
+// DefaultRetryTiers returns the retry-5s, retry-1m and retry-10m tiers of topic.
+func DefaultRetryTiers(topic string) []RetryTier {
+	return []RetryTier{
+		{Topic: topic + ".retry-5s", Delay: 5 * time.Second},
+		{Topic: topic + ".retry-1m", Delay: time.Minute},
+		{Topic: topic + ".retry-10m", Delay: 10 * time.Minute},
+	}
+}
+
+// Topics returns the retry topic names.
+func (p RetryPolicy) Topics() []string {
+	topics := make([]string, 0, len(p.Tiers))
+	for _, tier := range p.Tiers {
+		topics = append(topics, tier.Topic)
+	}
+	return topics
+}
+
+// next returns the tier for a message that has now failed `attempts` times,
+// or false when it must be parked.
+func (p RetryPolicy) next(attempts int) (RetryTier, bool) {
+	if len(p.Tiers) == 0 || attempts >= p.MaxAttempts {
+		return RetryTier{}, false
+	}
+
+	i := attempts - 1
+	if i >= len(p.Tiers) {
+		i = len(p.Tiers) - 1
+	}
+	return p.Tiers[i], true
+}
+
+// newRetryMessage copies a failed message onto a retry tier, counting the
+// failed attempt and stamping the time before which it must not be processed.
+func newRetryMessage(r *Record, tier RetryTier, attempts int, failedAt time.Time) *Message {
+	headers := copyHeaders(r.Headers)
+	headers[HeaderAttempts] = strconv.Itoa(attempts)
+	headers[HeaderNotBefore] = failedAt.Add(tier.Delay).UTC().Format(time.RFC3339Nano)
+
+	return &Message{
+		Topic:   tier.Topic,
+		Key:     string(r.Key),
+		Value:   r.Value,
+		Headers: headers,
+	}
+}
+
+// notBefore returns the not-before time of a message, or the zero time when it has none.
+func notBefore(r *Record) time.Time {
+	t, err := time.Parse(time.RFC3339Nano, r.Headers[HeaderNotBefore])
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marks a handler error as not worth retrying: the message goes
+// straight to the dead-letter topic.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// IsPermanent reports whether err was marked with Permanent.
+func IsPermanent(err error) bool {
+	var p *permanentError
+	return go_errors.As(err, &p)
+}
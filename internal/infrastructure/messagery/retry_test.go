@@ -0,0 +1,70 @@
+package messagery
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryPolicyNext(t *testing.T) {
+	policy := RetryPolicy{Tiers: DefaultRetryTiers("transactions"), MaxAttempts: 5}
+
+	tests := []struct {
+		failures int
+		topic    string
+		parked   bool
+	}{
+		{failures: 1, topic: "transactions.retry-5s"},
+		{failures: 2, topic: "transactions.retry-1m"},
+		{failures: 3, topic: "transactions.retry-10m"},
+		{failures: 4, topic: "transactions.retry-10m"},
+		{failures: 5, parked: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("after %d failures", tt.failures), func(t *testing.T) {
+			tier, ok := policy.next(tt.failures)
+			assert.Equal(t, !tt.parked, ok)
+			assert.Equal(t, tt.topic, tier.Topic)
+		})
+	}
+
+	_, ok := RetryPolicy{MaxAttempts: 5}.next(1)
+	assert.False(t, ok, "no tiers means parking right away")
+}
+
+func TestNewRetryMessage(t *testing.T) {
+	msg := &Record{
+		TopicPartition: TopicPartition{Topic: "transactions", Partition: 1},
+		Offset:         3,
+		Key:            []byte("key-1"),
+		Value:          []byte(`{}`),
+		Headers:        map[string]string{HeaderEventType: EventTransactionCreated},
+	}
+	failedAt := time.Date(2024, 1, 20, 15, 30, 0, 0, time.UTC)
+	tier := RetryTier{Topic: "transactions.retry-1m", Delay: time.Minute}
+
+	retry := newRetryMessage(msg, tier, 2, failedAt)
+
+	assert.Equal(t, "transactions.retry-1m", retry.Topic)
+	assert.Equal(t, "key-1", retry.Key)
+	assert.Equal(t, "2", retry.Headers[HeaderAttempts])
+	assert.Equal(t, EventTransactionCreated, retry.Headers[HeaderEventType])
+
+	retried := &Record{Headers: retry.Headers}
+	assert.True(t, failedAt.Add(time.Minute).Equal(notBefore(retried)))
+	assert.True(t, notBefore(msg).IsZero())
+}
+
+func TestPermanent(t *testing.T) {
+	cause := errors.New("not found")
+
+	assert.Nil(t, Permanent(nil))
+	assert.False(t, IsPermanent(cause))
+	assert.True(t, IsPermanent(Permanent(cause)))
+	assert.True(t, IsPermanent(fmt.Errorf("handling: %w", Permanent(cause))))
+	assert.ErrorIs(t, Permanent(cause), cause)
+}
@@ -0,0 +1,204 @@
+package messagery
+
+import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	go_errors "errors"
+	"fmt"
+	"slices"
+)
+
+// HeaderSchemaID carries the registry ID of the schema the event data was
+// written with.
+const HeaderSchemaID = "schema_id"
+
+var (
+	// ErrSchemaNotFound is returned when looking up an ID the registry does not have.
+	ErrSchemaNotFound = go_errors.New("schema not found")
+	// ErrIncompatibleSchema is returned when registering a schema that cannot
+	// read data written with an earlier version of its subject.
+	ErrIncompatibleSchema = go_errors.New("incompatible schema")
+)
+
+// FieldType is the type of a schema field. Decimals, UUIDs and statuses are
+// strings; timestamps are google.protobuf.Timestamp in Protobuf and RFC 3339
+// strings in JSON.
+type FieldType string
+
+const (
+	FieldString    FieldType = "string"
+	FieldInt64     FieldType = "int64"
+	FieldBool      FieldType = "bool"
+	FieldTimestamp FieldType = "timestamp"
+)
+
+// SchemaField is a field of the event data. Number is its Protobuf field
+// number and Name its JSON name, so one schema describes both encodings.
+type SchemaField struct {
+	Number   int       `json:"number"`
+	Name     string    `json:"name"`
+	Type     FieldType `json:"type"`
+	Required bool      `json:"required,omitempty"`
+}
+
+// Schema describes the data of one version of an event type. Its subject is
+// the event type and data version, e.g. "transaction.created.v2"; changes
+// that cannot stay backward compatible bump the data version, and so the
+// subject, and come with an upcaster.
+type Schema struct {
+	Subject string        `json:"subject"`
+	Fields  []SchemaField `json:"fields"`
+}
+
+// SchemaSubject names the subject of a data version of an event type.
+func SchemaSubject(eventType string, version int) string {
+	return fmt.Sprintf("%s.v%d", eventType, version)
+}
+
+// eventSchemas describes the current data version of each event type. Adding
+// an optional field is the only change allowed without bumping the version in
+// eventVersions.
+var eventSchemas = map[string]*Schema{
+	EventTransactionCreated: {
+		Subject: SchemaSubject(EventTransactionCreated, eventVersions[EventTransactionCreated]),
+		Fields: []SchemaField{
+			{Number: 1, Name: "id", Type: FieldString, Required: true},
+			{Number: 2, Name: "description", Type: FieldString},
+			{Number: 3, Name: "transaction_date", Type: FieldTimestamp},
+			{Number: 4, Name: "amount_usd", Type: FieldString},
+			{Number: 5, Name: "created_at", Type: FieldTimestamp},
+		},
+	},
+	EventTransactionStatusChanged: {
+		Subject: SchemaSubject(EventTransactionStatusChanged, eventVersions[EventTransactionStatusChanged]),
+		Fields: []SchemaField{
+			{Number: 1, Name: "id", Type: FieldString, Required: true},
+			{Number: 2, Name: "from", Type: FieldString},
+			{Number: 3, Name: "to", Type: FieldString},
+			{Number: 4, Name: "changed_at", Type: FieldTimestamp},
+			{Number: 5, Name: "failed_step", Type: FieldString},
+			{Number: 6, Name: "failure_reason", Type: FieldString},
+		},
+	},
+	EventTransactionVoided: {
+		Subject: SchemaSubject(EventTransactionVoided, eventVersions[EventTransactionVoided]),
+		Fields: []SchemaField{
+			{Number: 1, Name: "id", Type: FieldString, Required: true},
+			{Number: 2, Name: "previous_status", Type: FieldString},
+			{Number: 3, Name: "reason", Type: FieldString},
+			{Number: 4, Name: "voided_at", Type: FieldTimestamp},
+		},
+	},
+	EventTransactionConverted: {
+		Subject: SchemaSubject(EventTransactionConverted, eventVersions[EventTransactionConverted]),
+		Fields: []SchemaField{
+			{Number: 1, Name: "id", Type: FieldString, Required: true},
+			{Number: 2, Name: "target_currency", Type: FieldString},
+			{Number: 3, Name: "exchange_rate", Type: FieldString},
+			{Number: 4, Name: "exchange_date", Type: FieldTimestamp},
+			{Number: 5, Name: "original_amount_usd", Type: FieldString},
+			{Number: 6, Name: "converted_amount", Type: FieldString},
+		},
+	},
+}
+
+// EventSchemas returns the schemas of the current data version of every event type.
+func EventSchemas() []*Schema {
+	schemas := make([]*Schema, 0, len(eventSchemas))
+	for _, s := range eventSchemas {
+		schemas = append(schemas, s)
+	}
+	slices.SortFunc(schemas, func(a, b *Schema) int { return cmp.Compare(a.Subject, b.Subject) })
+	return schemas
+}
+
+// Validate checks that field numbers and names are unique and types known.
+func (s *Schema) Validate() error {
+	if s.Subject == "" {
+		return fmt.Errorf("schema subject is required")
+	}
+
+	numbers := map[int]bool{}
+	names := map[string]bool{}
+	for _, f := range s.Fields {
+		if f.Number < 1 || f.Number > 536870911 {
+			return fmt.Errorf("%s: field %q has invalid number %d", s.Subject, f.Name, f.Number)
+		}
+		if f.Name == "" {
+			return fmt.Errorf("%s: field %d has no name", s.Subject, f.Number)
+		}
+		if numbers[f.Number] {
+			return fmt.Errorf("%s: field number %d is used twice", s.Subject, f.Number)
+		}
+		if names[f.Name] {
+			return fmt.Errorf("%s: field name %q is used twice", s.Subject, f.Name)
+		}
+		switch f.Type {
+		case FieldString, FieldInt64, FieldBool, FieldTimestamp:
+		default:
+			return fmt.Errorf("%s: field %q has unknown type %q", s.Subject, f.Name, f.Type)
+		}
+		numbers[f.Number] = true
+		names[f.Name] = true
+	}
+
+	return nil
+}
+
+// Fingerprint identifies the schema content regardless of field order.
+func (s *Schema) Fingerprint() string {
+	fields := slices.Clone(s.Fields)
+	slices.SortFunc(fields, func(a, b SchemaField) int { return a.Number - b.Number })
+
+	canonical, _ := json.Marshal(Schema{Subject: s.Subject, Fields: fields})
+	sum := sha256.Sum256(canonical)
+	return hex.EncodeToString(sum[:])
+}
+
+func (s *Schema) fieldByNumber(number int) (SchemaField, bool) {
+	for _, f := range s.Fields {
+		if f.Number == number {
+			return f, true
+		}
+	}
+	return SchemaField{}, false
+}
+
+func (s *Schema) fieldByName(name string) (SchemaField, bool) {
+	for _, f := range s.Fields {
+		if f.Name == name {
+			return f, true
+		}
+	}
+	return SchemaField{}, false
+}
+
+// CheckCompatibility reports whether next can read data written with every
+// previous version of its subject:
+//   - a field number keeps its name and type, even after it was removed;
+//   - a field name keeps its number;
+//   - a required field must have been required in every previous version.
+//
+// Removing a field or adding an optional one is compatible.
+func CheckCompatibility(next *Schema, previous ...*Schema) error {
+	for _, prev := range previous {
+		for _, f := range next.Fields {
+			if old, ok := prev.fieldByNumber(f.Number); ok && (old.Name != f.Name || old.Type != f.Type) {
+				return fmt.Errorf("%w: field %d was %s %q and is now %s %q",
+					ErrIncompatibleSchema, f.Number, old.Type, old.Name, f.Type, f.Name)
+			}
+			if old, ok := prev.fieldByName(f.Name); ok && old.Number != f.Number {
+				return fmt.Errorf("%w: field %q moved from number %d to %d",
+					ErrIncompatibleSchema, f.Name, old.Number, f.Number)
+			}
+			if old, _ := prev.fieldByNumber(f.Number); f.Required && !old.Required {
+				return fmt.Errorf("%w: field %q is required but may be missing from earlier data",
+					ErrIncompatibleSchema, f.Name)
+			}
+		}
+	}
+
+	return nil
+}
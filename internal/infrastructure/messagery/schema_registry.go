@@ -0,0 +1,75 @@
+package messagery
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// SchemaRegistry assigns IDs to event data schemas so a message only needs to
+// carry the ID of the schema it was written with.
+type SchemaRegistry interface {
+	// Register stores schema as the next version of its subject and returns
+	// its ID. A schema identical to a registered version of the subject gets
+	// that version's ID back; one that cannot read data written with every
+	// earlier version fails with ErrIncompatibleSchema.
+	Register(ctx context.Context, schema *Schema) (int, error)
+	// Schema returns the schema registered under id, or ErrSchemaNotFound.
+	Schema(ctx context.Context, id int) (*Schema, error)
+}
+
+// MemorySchemaRegistry keeps schemas in process. It backs tests and the
+// in-memory broker, whose messages do not outlive the process either.
+type MemorySchemaRegistry struct {
+	mu       sync.Mutex
+	schemas  []*Schema
+	subjects map[string][]int
+}
+
+func NewMemorySchemaRegistry() *MemorySchemaRegistry {
+	return &MemorySchemaRegistry{
+		subjects: map[string][]int{},
+	}
+}
+
+func (r *MemorySchemaRegistry) Register(ctx context.Context, schema *Schema) (int, error) {
+	if err := schema.Validate(); err != nil {
+		return 0, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	fingerprint := schema.Fingerprint()
+	versions := r.subjects[schema.Subject]
+	previous := make([]*Schema, 0, len(versions))
+	for _, id := range versions {
+		registered := r.schemas[id-1]
+		if registered.Fingerprint() == fingerprint {
+			return id, nil
+		}
+		previous = append(previous, registered)
+	}
+
+	if err := CheckCompatibility(schema, previous...); err != nil {
+		return 0, fmt.Errorf("register %s: %w", schema.Subject, err)
+	}
+
+	stored := &Schema{Subject: schema.Subject, Fields: slices.Clone(schema.Fields)}
+	r.schemas = append(r.schemas, stored)
+	id := len(r.schemas)
+	r.subjects[schema.Subject] = append(versions, id)
+
+	return id, nil
+}
+
+func (r *MemorySchemaRegistry) Schema(ctx context.Context, id int) (*Schema, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if id < 1 || id > len(r.schemas) {
+		return nil, fmt.Errorf("schema %d: %w", id, ErrSchemaNotFound)
+	}
+	return r.schemas[id-1], nil
+}
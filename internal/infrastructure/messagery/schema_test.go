@@ -0,0 +1,133 @@
+package messagery
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventSchemasMatchDataTypes(t *testing.T) {
+	types := map[string]any{
+		EventTransactionCreated:       TransactionCreated{},
+		EventTransactionStatusChanged: TransactionStatusChanged{},
+		EventTransactionVoided:        TransactionVoided{},
+		EventTransactionConverted:     TransactionConverted{},
+	}
+	require.Len(t, eventSchemas, len(types))
+
+	for eventType, data := range types {
+		schema := eventSchemas[eventType]
+		require.NoError(t, schema.Validate())
+		assert.Equal(t, SchemaSubject(eventType, eventVersions[eventType]), schema.Subject)
+
+		var names []string
+		typ := reflect.TypeOf(data)
+		for i := 0; i < typ.NumField(); i++ {
+			name, _, _ := strings.Cut(typ.Field(i).Tag.Get("json"), ",")
+			names = append(names, name)
+		}
+		var fields []string
+		for _, f := range schema.Fields {
+			fields = append(fields, f.Name)
+		}
+		assert.ElementsMatch(t, names, fields, "schema of %s", eventType)
+	}
+}
+
+func TestCheckCompatibility(t *testing.T) {
+	v1 := &Schema{Subject: "thing.v1", Fields: []SchemaField{
+		{Number: 1, Name: "id", Type: FieldString, Required: true},
+		{Number: 2, Name: "note", Type: FieldString},
+		{Number: 3, Name: "at", Type: FieldTimestamp},
+	}}
+
+	tests := []struct {
+		name    string
+		fields  []SchemaField
+		wantErr string
+	}{
+		{
+			name: "optional field added",
+			fields: append(v1.Fields[:3:3],
+				SchemaField{Number: 4, Name: "count", Type: FieldInt64}),
+		},
+		{
+			name:   "optional field removed",
+			fields: []SchemaField{v1.Fields[0], v1.Fields[2]},
+		},
+		{
+			name: "type changed",
+			fields: []SchemaField{v1.Fields[0], v1.Fields[1],
+				{Number: 3, Name: "at", Type: FieldString}},
+			wantErr: `field 3 was timestamp "at" and is now string "at"`,
+		},
+		{
+			name:    "removed number reused",
+			fields:  []SchemaField{v1.Fields[0], {Number: 2, Name: "flag", Type: FieldBool}},
+			wantErr: `field 2 was string "note"`,
+		},
+		{
+			name:    "field renumbered",
+			fields:  []SchemaField{v1.Fields[0], {Number: 5, Name: "note", Type: FieldString}},
+			wantErr: `field "note" moved from number 2 to 5`,
+		},
+		{
+			name: "required field added",
+			fields: append(v1.Fields[:3:3],
+				SchemaField{Number: 4, Name: "count", Type: FieldInt64, Required: true}),
+			wantErr: `field "count" is required`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckCompatibility(&Schema{Subject: v1.Subject, Fields: tt.fields}, v1)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, ErrIncompatibleSchema)
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestMemorySchemaRegistry(t *testing.T) {
+	ctx := context.Background()
+	registry := NewMemorySchemaRegistry()
+
+	created := eventSchemas[EventTransactionCreated]
+	id, err := registry.Register(ctx, created)
+	require.NoError(t, err)
+
+	reordered := &Schema{Subject: created.Subject, Fields: append([]SchemaField(nil), created.Fields...)}
+	reordered.Fields[0], reordered.Fields[1] = reordered.Fields[1], reordered.Fields[0]
+	again, err := registry.Register(ctx, reordered)
+	require.NoError(t, err)
+	assert.Equal(t, id, again, "registering the same fields again returns the existing ID")
+
+	extended := &Schema{Subject: created.Subject, Fields: append(append([]SchemaField(nil), created.Fields...),
+		SchemaField{Number: 6, Name: "currency", Type: FieldString})}
+	next, err := registry.Register(ctx, extended)
+	require.NoError(t, err)
+	assert.NotEqual(t, id, next)
+
+	// Dropping description is fine, but currency cannot change type.
+	retyped := &Schema{Subject: created.Subject, Fields: []SchemaField{
+		created.Fields[0],
+		{Number: 6, Name: "currency", Type: FieldInt64},
+	}}
+	_, err = registry.Register(ctx, retyped)
+	assert.ErrorIs(t, err, ErrIncompatibleSchema)
+
+	stored, err := registry.Schema(ctx, next)
+	require.NoError(t, err)
+	assert.Equal(t, extended.Fingerprint(), stored.Fingerprint())
+
+	_, err = registry.Schema(ctx, 99)
+	assert.ErrorIs(t, err, ErrSchemaNotFound)
+}
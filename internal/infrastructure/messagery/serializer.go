@@ -0,0 +1,348 @@
+package messagery
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Serializer names, as used in configuration.
+const (
+	SerializerJSON     = "json"
+	SerializerProtobuf = "protobuf"
+)
+
+// ProtobufContentType is sent in the content-type header of events encoded
+// with the Protobuf serializer.
+const ProtobufContentType = "application/cloudevents+protobuf"
+
+// Serializer encodes event envelopes for the wire. Event.Data always holds
+// JSON in memory; a serializer may encode it differently following the
+// schema of the event data.
+type Serializer interface {
+	Name() string
+	ContentType() string
+	Marshal(e *Event, schema *Schema) ([]byte, error)
+	Unmarshal(data []byte, schema *Schema) (*Event, error)
+}
+
+// NewSerializer returns the serializer with the given name.
+func NewSerializer(name string) (Serializer, error) {
+	switch name {
+	case SerializerJSON:
+		return JSONSerializer{}, nil
+	case SerializerProtobuf:
+		return ProtobufSerializer{}, nil
+	}
+	return nil, fmt.Errorf("unknown serializer %q", name)
+}
+
+// JSONSerializer writes the envelope as JSON, the format used before
+// serializers were pluggable. It does not need the schema.
+type JSONSerializer struct{}
+
+func (JSONSerializer) Name() string        { return SerializerJSON }
+func (JSONSerializer) ContentType() string { return EventContentType }
+
+func (JSONSerializer) Marshal(e *Event, _ *Schema) ([]byte, error) {
+	return json.Marshal(e)
+}
+
+func (JSONSerializer) Unmarshal(data []byte, _ *Schema) (*Event, error) {
+	var event Event
+	if err := json.Unmarshal(data, &event); err != nil {
+		return nil, fmt.Errorf("decode event: %w", err)
+	}
+	return &event, nil
+}
+
+// ProtobufSerializer writes the envelope as the Event message of events.proto
+// and the data as the message its schema describes, so field names never
+// travel on the wire. Data fields missing from the schema cannot be encoded;
+// fields of the wire data missing from the schema are skipped on decoding.
+type ProtobufSerializer struct{}
+
+func (ProtobufSerializer) Name() string        { return SerializerProtobuf }
+func (ProtobufSerializer) ContentType() string { return ProtobufContentType }
+
+// Field numbers of the Event envelope message.
+const (
+	envelopeSpecVersion     protowire.Number = 1
+	envelopeID              protowire.Number = 2
+	envelopeType            protowire.Number = 3
+	envelopeSource          protowire.Number = 4
+	envelopeTime            protowire.Number = 5
+	envelopeSubject         protowire.Number = 6
+	envelopeDataContentType protowire.Number = 7
+	envelopeDataVersion     protowire.Number = 8
+	envelopeData            protowire.Number = 9
+)
+
+// protobufDataContentType is the datacontenttype of Protobuf encoded data.
+// Decoding turns the data back into JSON, and the content type with it.
+const protobufDataContentType = "application/protobuf"
+
+func (ProtobufSerializer) Marshal(e *Event, schema *Schema) ([]byte, error) {
+	data, err := marshalData(e.Data, schema)
+	if err != nil {
+		return nil, fmt.Errorf("encode %s v%d data: %w", e.Type, e.DataVersion, err)
+	}
+
+	var b []byte
+	b = appendString(b, envelopeSpecVersion, e.SpecVersion)
+	b = appendString(b, envelopeID, e.ID)
+	b = appendString(b, envelopeType, e.Type)
+	b = appendString(b, envelopeSource, e.Source)
+	b = appendTimestamp(b, envelopeTime, e.Time)
+	b = appendString(b, envelopeSubject, e.Subject)
+	b = appendString(b, envelopeDataContentType, protobufDataContentType)
+	b = protowire.AppendTag(b, envelopeDataVersion, protowire.VarintType)
+	b = protowire.AppendVarint(b, uint64(e.DataVersion))
+	b = protowire.AppendTag(b, envelopeData, protowire.BytesType)
+	b = protowire.AppendBytes(b, data)
+
+	return b, nil
+}
+
+func (ProtobufSerializer) Unmarshal(data []byte, schema *Schema) (*Event, error) {
+	var (
+		event   Event
+		payload []byte
+	)
+	err := consumeFields(data, func(num protowire.Number, typ protowire.Type, value []byte, varint uint64) error {
+		var err error
+		switch {
+		case num == envelopeTime && typ == protowire.BytesType:
+			event.Time, err = consumeTimestamp(value)
+		case num == envelopeDataVersion && typ == protowire.VarintType:
+			event.DataVersion = int(varint)
+		case num == envelopeData && typ == protowire.BytesType:
+			payload = value
+		case typ == protowire.BytesType:
+			switch num {
+			case envelopeSpecVersion:
+				event.SpecVersion = string(value)
+			case envelopeID:
+				event.ID = string(value)
+			case envelopeType:
+				event.Type = string(value)
+			case envelopeSource:
+				event.Source = string(value)
+			case envelopeSubject:
+				event.Subject = string(value)
+			}
+		}
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("decode event: %w", err)
+	}
+
+	event.Data, err = unmarshalData(payload, schema)
+	if err != nil {
+		return nil, fmt.Errorf("decode %s v%d data: %w", event.Type, event.DataVersion, err)
+	}
+	event.DataContentType = "application/json"
+
+	return &event, nil
+}
+
+// marshalData encodes a JSON object as the Protobuf message schema describes,
+// fields in number order.
+func marshalData(data json.RawMessage, schema *Schema) ([]byte, error) {
+	values := map[string]json.RawMessage{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&values); err != nil {
+		return nil, err
+	}
+
+	for name := range values {
+		if _, ok := schema.fieldByName(name); !ok {
+			return nil, fmt.Errorf("field %q is not in schema %s", name, schema.Subject)
+		}
+	}
+
+	fields := slices.Clone(schema.Fields)
+	slices.SortFunc(fields, func(a, b SchemaField) int { return a.Number - b.Number })
+
+	var b []byte
+	for _, f := range fields {
+		raw, ok := values[f.Name]
+		if !ok || string(raw) == "null" {
+			if f.Required {
+				return nil, fmt.Errorf("required field %q is missing", f.Name)
+			}
+			continue
+		}
+
+		num := protowire.Number(f.Number)
+		switch f.Type {
+		case FieldString:
+			var v string
+			if err := json.Unmarshal(raw, &v); err != nil {
+				return nil, fmt.Errorf("field %q: %w", f.Name, err)
+			}
+			b = appendString(b, num, v)
+		case FieldInt64:
+			var v int64
+			if err := json.Unmarshal(raw, &v); err != nil {
+				return nil, fmt.Errorf("field %q: %w", f.Name, err)
+			}
+			b = protowire.AppendTag(b, num, protowire.VarintType)
+			b = protowire.AppendVarint(b, uint64(v))
+		case FieldBool:
+			var v bool
+			if err := json.Unmarshal(raw, &v); err != nil {
+				return nil, fmt.Errorf("field %q: %w", f.Name, err)
+			}
+			b = protowire.AppendTag(b, num, protowire.VarintType)
+			b = protowire.AppendVarint(b, protowire.EncodeBool(v))
+		case FieldTimestamp:
+			var v time.Time
+			if err := json.Unmarshal(raw, &v); err != nil {
+				return nil, fmt.Errorf("field %q: %w", f.Name, err)
+			}
+			b = appendTimestamp(b, num, v)
+		}
+	}
+
+	return b, nil
+}
+
+// unmarshalData decodes a Protobuf message written with schema back into the
+// JSON object the event data types read.
+func unmarshalData(data []byte, schema *Schema) (json.RawMessage, error) {
+	values := map[string]any{}
+	err := consumeFields(data, func(num protowire.Number, typ protowire.Type, value []byte, varint uint64) error {
+		f, ok := schema.fieldByNumber(int(num))
+		if !ok {
+			return nil
+		}
+
+		switch {
+		case f.Type == FieldString && typ == protowire.BytesType:
+			values[f.Name] = string(value)
+		case f.Type == FieldInt64 && typ == protowire.VarintType:
+			values[f.Name] = int64(varint)
+		case f.Type == FieldBool && typ == protowire.VarintType:
+			values[f.Name] = protowire.DecodeBool(varint)
+		case f.Type == FieldTimestamp && typ == protowire.BytesType:
+			t, err := consumeTimestamp(value)
+			if err != nil {
+				return fmt.Errorf("field %q: %w", f.Name, err)
+			}
+			values[f.Name] = t
+		default:
+			return fmt.Errorf("field %q: unexpected wire type %d for %s", f.Name, typ, f.Type)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, f := range schema.Fields {
+		if _, ok := values[f.Name]; !ok && f.Required {
+			return nil, fmt.Errorf("required field %q is missing", f.Name)
+		}
+	}
+
+	return json.Marshal(values)
+}
+
+// consumeFields calls fn with every field of a message: the content of
+// length-delimited fields as value, the number of varint fields as varint.
+// Other wire types are skipped.
+func consumeFields(b []byte, fn func(num protowire.Number, typ protowire.Type, value []byte, varint uint64) error) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		var (
+			value  []byte
+			varint uint64
+		)
+		switch typ {
+		case protowire.BytesType:
+			value, n = protowire.ConsumeBytes(b)
+		case protowire.VarintType:
+			varint, n = protowire.ConsumeVarint(b)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		}
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		if typ != protowire.BytesType && typ != protowire.VarintType {
+			continue
+		}
+		if err := fn(num, typ, value, varint); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func appendString(b []byte, num protowire.Number, v string) []byte {
+	if v == "" {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, v)
+}
+
+// appendTimestamp encodes t as a google.protobuf.Timestamp field. The zero
+// time is left out, like any default value.
+func appendTimestamp(b []byte, num protowire.Number, t time.Time) []byte {
+	if t.IsZero() {
+		return b
+	}
+
+	var ts []byte
+	if secs := t.Unix(); secs != 0 {
+		ts = protowire.AppendTag(ts, 1, protowire.VarintType)
+		ts = protowire.AppendVarint(ts, uint64(secs))
+	}
+	if nanos := t.Nanosecond(); nanos != 0 {
+		ts = protowire.AppendTag(ts, 2, protowire.VarintType)
+		ts = protowire.AppendVarint(ts, uint64(nanos))
+	}
+
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, ts)
+}
+
+func consumeTimestamp(b []byte) (time.Time, error) {
+	var secs, nanos int64
+	err := consumeFields(b, func(num protowire.Number, typ protowire.Type, _ []byte, varint uint64) error {
+		if typ != protowire.VarintType {
+			return nil
+		}
+		switch num {
+		case 1:
+			secs = int64(varint)
+		case 2:
+			nanos = int64(varint)
+		}
+		return nil
+	})
+	if err != nil {
+		return time.Time{}, err
+	}
+	if nanos < 0 || nanos >= int64(time.Second) {
+		return time.Time{}, fmt.Errorf("invalid timestamp nanos %d", nanos)
+	}
+
+	return time.Unix(secs, nanos).UTC(), nil
+}
@@ -0,0 +1,83 @@
+package messagery
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Athla/vr-software-challenge/internal/domain/models"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func TestProtobufSerializerRoundTrip(t *testing.T) {
+	id := uuid.New()
+	date := time.Date(1969, 12, 31, 0, 0, 0, 0, time.UTC)
+	created := time.Date(2024, 3, 1, 12, 30, 0, 123456789, time.UTC)
+
+	event, err := NewEvent(EventTransactionCreated, id.String(), TransactionCreated{
+		ID:              id,
+		Description:     "Office Supplies",
+		TransactionDate: date,
+		AmountUSD:       decimal.RequireFromString("123.45"),
+		CreatedAt:       created,
+	})
+	require.NoError(t, err)
+
+	schema := eventSchemas[EventTransactionCreated]
+	value, err := ProtobufSerializer{}.Marshal(event, schema)
+	require.NoError(t, err)
+	assert.NotContains(t, string(value), "transaction_date", "field names are not sent")
+
+	decoded, err := ProtobufSerializer{}.Unmarshal(value, schema)
+	require.NoError(t, err)
+	assert.Equal(t, event.ID, decoded.ID)
+	assert.Equal(t, event.Type, decoded.Type)
+	assert.Equal(t, event.Subject, decoded.Subject)
+	assert.Equal(t, event.DataVersion, decoded.DataVersion)
+	assert.True(t, event.Time.Equal(decoded.Time))
+	assert.Equal(t, "application/json", decoded.DataContentType)
+
+	var data TransactionCreated
+	require.NoError(t, decoded.DecodeData(&data))
+	assert.Equal(t, id, data.ID)
+	assert.Equal(t, "Office Supplies", data.Description)
+	assert.True(t, date.Equal(data.TransactionDate), "dates before 1970 survive")
+	assert.True(t, created.Equal(data.CreatedAt), "nanoseconds survive")
+	assert.Equal(t, "123.45", data.AmountUSD.String())
+}
+
+func TestProtobufSerializerSchemaEvolution(t *testing.T) {
+	id := uuid.New()
+	event, err := NewEvent(EventTransactionVoided, id.String(), TransactionVoided{
+		ID:             id,
+		PreviousStatus: models.StatusPending,
+		Reason:         "Duplicate",
+	})
+	require.NoError(t, err)
+
+	writer := eventSchemas[EventTransactionVoided]
+	value, err := ProtobufSerializer{}.Marshal(event, writer)
+	require.NoError(t, err)
+
+	// A reader that dropped reason skips it; one missing a required field fails.
+	reader := &Schema{Subject: writer.Subject, Fields: []SchemaField{writer.Fields[0], writer.Fields[1]}}
+	decoded, err := ProtobufSerializer{}.Unmarshal(value, reader)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"id": "`+id.String()+`", "previous_status": "PENDING"}`, string(decoded.Data))
+
+	withoutID := &Schema{Subject: writer.Subject, Fields: writer.Fields[1:]}
+	value, err = ProtobufSerializer{}.Marshal(&Event{Data: []byte(`{"reason": "x"}`)}, withoutID)
+	require.NoError(t, err)
+	_, err = ProtobufSerializer{}.Unmarshal(value, writer)
+	assert.ErrorContains(t, err, `required field "id" is missing`)
+
+	_, err = ProtobufSerializer{}.Marshal(&Event{Data: []byte(`{"id": "1", "extra": true}`)}, writer)
+	assert.ErrorContains(t, err, `field "extra" is not in schema`)
+
+	bad := protowire.AppendTag(nil, envelopeData, protowire.BytesType)
+	_, err = ProtobufSerializer{}.Unmarshal(bad, writer)
+	assert.Error(t, err)
+}
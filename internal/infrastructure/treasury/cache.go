@@ -0,0 +1,215 @@
+package treasury
+
+import (
+	"context"
+	go_errors "errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Athla/vr-software-challenge/internal/domain/errors"
+	"github.com/charmbracelet/log"
+	"golang.org/x/sync/singleflight"
+)
+
+// RateSource tells where a rate handed out by a client came from.
+type RateSource string
+
+const (
+	// SourceLive rates were fetched from the Treasury API for this request.
+	SourceLive RateSource = "live"
+	// SourceCache rates were fetched earlier and are still fresh.
+	SourceCache RateSource = "cache"
+	// SourceStale rates are past their TTL and were served because the
+	// Treasury API failed.
+	SourceStale RateSource = "stale"
+)
+
+const (
+	DefaultCacheTTL        = time.Hour
+	DefaultCacheStaleTTL   = 24 * time.Hour
+	DefaultCacheMaxEntries = 10000
+)
+
+// CachingClient caches the rate lookups of another client by currency and
+// date window. Concurrent identical lookups share a single request, and an
+// expired entry is still served, marked stale, when the Treasury API fails.
+// GetExchangeRatesSince is not cached: the sync job wants fresh data.
+type CachingClient struct {
+	Clienter
+	ttl        time.Duration
+	staleTTL   time.Duration
+	maxEntries int
+	now        func() time.Time
+
+	group   singleflight.Group
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	rates     []ExchangeRate
+	fetchedAt time.Time
+}
+
+type CacheOption func(*CachingClient)
+
+// WithCacheTTL sets how long an entry is served without calling the API.
+func WithCacheTTL(ttl time.Duration) CacheOption {
+	return func(c *CachingClient) {
+		c.ttl = ttl
+	}
+}
+
+// WithCacheStaleTTL sets how long past its TTL an entry may still be served
+// when the API fails.
+func WithCacheStaleTTL(ttl time.Duration) CacheOption {
+	return func(c *CachingClient) {
+		c.staleTTL = ttl
+	}
+}
+
+// WithCacheMaxEntries bounds the number of cached lookups.
+func WithCacheMaxEntries(n int) CacheOption {
+	return func(c *CachingClient) {
+		c.maxEntries = n
+	}
+}
+
+func NewCachingClient(client Clienter, opts ...CacheOption) *CachingClient {
+	c := &CachingClient{
+		Clienter:   client,
+		ttl:        DefaultCacheTTL,
+		staleTTL:   DefaultCacheStaleTTL,
+		maxEntries: DefaultCacheMaxEntries,
+		now:        time.Now,
+		entries:    map[string]cacheEntry{},
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+func (c *CachingClient) GetExchangeRate(ctx context.Context, currency string, date time.Time) (*ExchangeRate, error) {
+	key := fmt.Sprintf("rate|%s|%s", currency, date.Format("2006-01-02"))
+
+	rates, err := c.lookup(ctx, key, func(ctx context.Context) ([]ExchangeRate, error) {
+		rate, err := c.Clienter.GetExchangeRate(ctx, currency, date)
+		if err != nil {
+			return nil, err
+		}
+		return []ExchangeRate{*rate}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &rates[0], nil
+}
+
+func (c *CachingClient) GetExchangeRatesInRange(ctx context.Context, currency string, startDate, endDate time.Time) ([]ExchangeRate, error) {
+	key := fmt.Sprintf("range|%s|%s|%s", currency, startDate.Format("2006-01-02"), endDate.Format("2006-01-02"))
+
+	return c.lookup(ctx, key, func(ctx context.Context) ([]ExchangeRate, error) {
+		return c.Clienter.GetExchangeRatesInRange(ctx, currency, startDate, endDate)
+	})
+}
+
+// lookup serves key from the cache while fresh, and otherwise fetches it once
+// for all concurrent callers. The returned rates are copies marked with their
+// source.
+func (c *CachingClient) lookup(ctx context.Context, key string, fetch func(context.Context) ([]ExchangeRate, error)) ([]ExchangeRate, error) {
+	entry, cached := c.get(key)
+	if cached && c.now().Sub(entry.fetchedAt) < c.ttl {
+		rateLookups.WithLabelValues(string(SourceCache)).Inc()
+		return withSource(entry.rates, SourceCache), nil
+	}
+
+	// The shared request must not be cancelled with the first caller; each
+	// caller still stops waiting when its own context is done.
+	result := c.group.DoChan(key, func() (any, error) {
+		rates, err := fetch(context.WithoutCancel(ctx))
+		if err != nil {
+			return nil, err
+		}
+		c.set(key, rates)
+		return rates, nil
+	})
+
+	var res singleflight.Result
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case res = <-result:
+	}
+
+	if res.Err != nil {
+		if cached && go_errors.Is(res.Err, errors.ErrTreasuryAPIError) && c.now().Sub(entry.fetchedAt) < c.ttl+c.staleTTL {
+			log.Warnf("Serving stale exchange rates for %s due: %v", key, res.Err)
+			rateLookups.WithLabelValues(string(SourceStale)).Inc()
+			return withSource(entry.rates, SourceStale), nil
+		}
+		return nil, res.Err
+	}
+
+	// Callers that joined an identical request in flight get its fresh
+	// result, so they are live too.
+	rateLookups.WithLabelValues(string(SourceLive)).Inc()
+
+	return withSource(res.Val.([]ExchangeRate), SourceLive), nil
+}
+
+func (c *CachingClient) get(key string) (cacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	return entry, ok
+}
+
+func (c *CachingClient) set(key string, rates []ExchangeRate) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	if _, exists := c.entries[key]; !exists && len(c.entries) >= c.maxEntries {
+		c.evict(now)
+	}
+	c.entries[key] = cacheEntry{rates: rates, fetchedAt: now}
+}
+
+// evict drops the entries too old to be served even stale and, when the cache
+// is still full, the oldest one.
+func (c *CachingClient) evict(now time.Time) {
+	var (
+		oldestKey string
+		oldest    time.Time
+	)
+	for key, entry := range c.entries {
+		if now.Sub(entry.fetchedAt) >= c.ttl+c.staleTTL {
+			delete(c.entries, key)
+			continue
+		}
+		if oldestKey == "" || entry.fetchedAt.Before(oldest) {
+			oldestKey, oldest = key, entry.fetchedAt
+		}
+	}
+
+	if len(c.entries) >= c.maxEntries {
+		delete(c.entries, oldestKey)
+	}
+}
+
+// withSource copies rates, so callers cannot modify the cached ones, and
+// marks them with source.
+func withSource(rates []ExchangeRate, source RateSource) []ExchangeRate {
+	copied := make([]ExchangeRate, len(rates))
+	for i, rate := range rates {
+		rate.Source = source
+		copied[i] = rate
+	}
+	return copied
+}
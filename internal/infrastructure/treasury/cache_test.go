@@ -0,0 +1,163 @@
+package treasury
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Athla/vr-software-challenge/internal/domain/errors"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubClient answers range lookups with fn, counting the calls.
+type stubClient struct {
+	Clienter
+	calls atomic.Int32
+	fn    func() ([]ExchangeRate, error)
+}
+
+func (s *stubClient) GetExchangeRatesInRange(ctx context.Context, currency string, startDate, endDate time.Time) ([]ExchangeRate, error) {
+	s.calls.Add(1)
+	return s.fn()
+}
+
+func euroRates(rate string) []ExchangeRate {
+	return []ExchangeRate{{
+		Currency:      "Euro Zone-Euro",
+		Rate:          decimal.RequireFromString(rate),
+		EffectiveDate: time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC),
+	}}
+}
+
+func TestCachingClient(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2023, 10, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)
+
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	stub := &stubClient{fn: func() ([]ExchangeRate, error) { return euroRates("0.92"), nil }}
+	client := NewCachingClient(stub, WithCacheTTL(time.Hour), WithCacheStaleTTL(24*time.Hour))
+	client.now = func() time.Time { return now }
+
+	rates, err := client.GetExchangeRatesInRange(ctx, "Euro Zone-Euro", start, end)
+	require.NoError(t, err)
+	assert.Equal(t, SourceLive, rates[0].Source)
+
+	rates, err = client.GetExchangeRatesInRange(ctx, "Euro Zone-Euro", start, end)
+	require.NoError(t, err)
+	assert.Equal(t, SourceCache, rates[0].Source)
+	assert.EqualValues(t, 1, stub.calls.Load())
+
+	// Another window is another entry.
+	_, err = client.GetExchangeRatesInRange(ctx, "Euro Zone-Euro", start, end.AddDate(0, 0, 1))
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, stub.calls.Load())
+
+	// Past the TTL, a failing API gets the entry served stale.
+	now = now.Add(2 * time.Hour)
+	stub.fn = func() ([]ExchangeRate, error) {
+		return nil, fmt.Errorf("%w: unexpected status code 503", errors.ErrTreasuryAPIError)
+	}
+	rates, err = client.GetExchangeRatesInRange(ctx, "Euro Zone-Euro", start, end)
+	require.NoError(t, err)
+	assert.Equal(t, SourceStale, rates[0].Source)
+	assert.Equal(t, "0.92", rates[0].Rate.String())
+
+	// Errors other than the API failing are not hidden.
+	stub.fn = func() ([]ExchangeRate, error) { return nil, errors.ErrInvalidCurrency }
+	_, err = client.GetExchangeRatesInRange(ctx, "Euro Zone-Euro", start, end)
+	assert.ErrorIs(t, err, errors.ErrInvalidCurrency)
+
+	// Past the stale TTL too, the API error is returned.
+	now = now.Add(24 * time.Hour)
+	stub.fn = func() ([]ExchangeRate, error) { return nil, errors.ErrTreasuryAPIError }
+	_, err = client.GetExchangeRatesInRange(ctx, "Euro Zone-Euro", start, end)
+	assert.ErrorIs(t, err, errors.ErrTreasuryAPIError)
+
+	// A fresh fetch replaces the entry.
+	stub.fn = func() ([]ExchangeRate, error) { return euroRates("0.93"), nil }
+	rates, err = client.GetExchangeRatesInRange(ctx, "Euro Zone-Euro", start, end)
+	require.NoError(t, err)
+	assert.Equal(t, SourceLive, rates[0].Source)
+	assert.Equal(t, "0.93", rates[0].Rate.String())
+}
+
+func TestCachingClientCoalescesRequests(t *testing.T) {
+	release := make(chan struct{})
+	stub := &stubClient{fn: func() ([]ExchangeRate, error) {
+		<-release
+		return euroRates("0.92"), nil
+	}}
+	client := NewCachingClient(stub)
+
+	start := time.Date(2023, 10, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rates, err := client.GetExchangeRatesInRange(context.Background(), "Euro Zone-Euro", start, end)
+			assert.NoError(t, err)
+			assert.Len(t, rates, 1)
+		}()
+	}
+
+	// Let every caller join the request in flight before it completes.
+	assert.Eventually(t, func() bool { return stub.calls.Load() == 1 }, time.Second, time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.EqualValues(t, 1, stub.calls.Load())
+}
+
+func TestCachingClientCallerCancellation(t *testing.T) {
+	release := make(chan struct{})
+	stub := &stubClient{fn: func() ([]ExchangeRate, error) {
+		<-release
+		return euroRates("0.92"), nil
+	}}
+	client := NewCachingClient(stub)
+
+	start := time.Date(2023, 10, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := client.GetExchangeRatesInRange(ctx, "Euro Zone-Euro", start, end)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	// The request carries on for the others and fills the cache.
+	close(release)
+	assert.Eventually(t, func() bool {
+		rates, err := client.GetExchangeRatesInRange(context.Background(), "Euro Zone-Euro", start, end)
+		return err == nil && rates[0].Source == SourceCache
+	}, time.Second, time.Millisecond)
+	assert.EqualValues(t, 1, stub.calls.Load())
+}
+
+func TestCachingClientEviction(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	stub := &stubClient{fn: func() ([]ExchangeRate, error) { return euroRates("0.92"), nil }}
+	client := NewCachingClient(stub, WithCacheMaxEntries(2))
+	client.now = func() time.Time { return now }
+
+	end := time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)
+	for i := range 3 {
+		now = now.Add(time.Minute)
+		_, err := client.GetExchangeRatesInRange(context.Background(), "Euro Zone-Euro", end.AddDate(0, -6, i), end.AddDate(0, 0, i))
+		require.NoError(t, err)
+	}
+
+	assert.Len(t, client.entries, 2)
+	_, err := client.GetExchangeRatesInRange(context.Background(), "Euro Zone-Euro", end.AddDate(0, -6, 0), end)
+	require.NoError(t, err)
+	assert.EqualValues(t, 4, stub.calls.Load(), "the oldest entry was evicted")
+}
@@ -7,6 +7,7 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"strconv"
 	"time"
 
 	"github.com/Athla/vr-software-challenge/internal/domain/errors"
@@ -17,16 +18,25 @@ import (
 const (
 	baseURL      = "https://api.fiscaldata.treasury.gov/services/v1/accounting/od/rates_of_exchange"
 	rateEndpoint = "/rates_of_exchange"
+
+	// maxPageSize is the largest page the Treasury API serves.
+	maxPageSize = 10000
+
+	DefaultPageSize   = 1000
+	DefaultMaxRecords = 50000
 )
 
 type Clienter interface {
 	GetExchangeRate(ctx context.Context, currency string, date time.Time) (*ExchangeRate, error)
 	GetExchangeRatesInRange(ctx context.Context, currency string, startDate, endDate time.Time) ([]ExchangeRate, error)
+	GetExchangeRatesSince(ctx context.Context, since time.Time) ([]ExchangeRate, error)
 }
 
 type Client struct {
 	httpClient *http.Client
 	baseURL    string
+	pageSize   int
+	maxRecords int
 }
 
 type Option func(*Client)
@@ -43,12 +53,31 @@ func WithHttpClient(client *http.Client) Option {
 	}
 }
 
+// WithPageSize sets how many records are requested per page, up to the
+// 10000 the API allows.
+func WithPageSize(size int) Option {
+	return func(c *Client) {
+		c.pageSize = min(max(size, 1), maxPageSize)
+	}
+}
+
+// WithMaxRecords caps the records a single query may return across its
+// pages; a query matching more fails with ErrTooManyExchangeRates rather than
+// being truncated. Zero removes the cap.
+func WithMaxRecords(n int) Option {
+	return func(c *Client) {
+		c.maxRecords = n
+	}
+}
+
 func NewClient(opts ...Option) *Client {
 	c := &Client{
 		httpClient: &http.Client{
 			Timeout: 10 * time.Second,
 		},
-		baseURL: baseURL,
+		baseURL:    baseURL,
+		pageSize:   DefaultPageSize,
+		maxRecords: DefaultMaxRecords,
 	}
 
 	for _, opt := range opts {
@@ -62,6 +91,8 @@ type ExchangeRate struct {
 	Currency      string          `json:"currency"`
 	Rate          decimal.Decimal `json:"exchange_rate"`
 	EffectiveDate time.Time       `json:"effective_date"`
+	// Source is set by CachingClient; rates from other clients are live.
+	Source RateSource `json:"source,omitempty"`
 }
 
 type ExchangeRateResponse struct {
@@ -70,9 +101,38 @@ type ExchangeRateResponse struct {
 		ExchangeRate  string `json:"exchange_rate"`
 		EffectiveDate string `json:"record_date"`
 	} `json:"data"`
-	Meta struct {
-		Count int `json:"count"`
-	} `json:"meta"`
+	Meta  Meta  `json:"meta"`
+	Links Links `json:"links"`
+}
+
+// Meta describes the page of a response. Count is the number of records on
+// the page, TotalCount and TotalPages those of the whole query.
+type Meta struct {
+	Count      int `json:"count"`
+	TotalCount int `json:"total-count"`
+	TotalPages int `json:"total-pages"`
+}
+
+// Links holds the query strings of the neighbouring pages; Prev and Next are
+// null on the first and last page.
+type Links struct {
+	Self  string  `json:"self"`
+	First string  `json:"first"`
+	Prev  *string `json:"prev"`
+	Next  *string `json:"next"`
+	Last  string  `json:"last"`
+}
+
+// hasNextPage reports whether there is a page after page. The metadata is
+// preferred; the next link is used when it is missing.
+func (r *ExchangeRateResponse) hasNextPage(page int) bool {
+	if len(r.Data) == 0 {
+		return false
+	}
+	if r.Meta.TotalPages > 0 {
+		return page < r.Meta.TotalPages
+	}
+	return r.Links.Next != nil && *r.Links.Next != ""
 }
 
 func (c *Client) GetExchangeRate(ctx context.Context, currency string, date time.Time) (*ExchangeRate, error) {
@@ -81,33 +141,11 @@ func (c *Client) GetExchangeRate(ctx context.Context, currency string, date time
 	params.Add("filter", fmt.Sprintf("country_currency_desc:eq:%s,record_date:lte:%s",
 		currency, date.Format("2006-01-02")))
 	params.Add("sort", "-record_date")
-	params.Add("limit", "1")
-
-	reqUrl := fmt.Sprintf("%s?%s", c.baseURL, params.Encode())
-
-	req, err := http.NewRequestWithContext(ctx, "GET", reqUrl, nil)
-	if err != nil {
-		log.Errorf("Failed to create request: %s", err)
-		return nil, errors.ErrTreasuryAPIError
-	}
+	params.Add("page[size]", "1")
 
-	resp, err := c.httpClient.Do(req)
+	apiResp, err := c.fetchPage(ctx, params)
 	if err != nil {
-		log.Errorf("Failed to execute request: %v", err)
-		return nil, fmt.Errorf("%w: failed to execute request", errors.ErrTreasuryAPIError)
-	}
-
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		log.Errorf("Treasure API error: %s - status %d", string(body), resp.StatusCode)
-		return nil, fmt.Errorf("%w: unexpected status code %d", errors.ErrTreasuryAPIError, resp.StatusCode)
-	}
-	var apiResp ExchangeRateResponse
-	if err = json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
-		log.Errorf("Failed to decode response: %s", err)
-		return nil, fmt.Errorf("%w: Failed to decode response.", errors.ErrTreasuryAPIError)
+		return nil, err
 	}
 
 	if len(apiResp.Data) == 0 {
@@ -142,6 +180,74 @@ func (c *Client) GetExchangeRatesInRange(ctx context.Context, currency string, s
 		endDate.Format("2006-01-02")))
 	params.Add("sort", "-record_date")
 
+	return c.fetchRates(ctx, params)
+}
+
+// GetExchangeRatesSince returns the rates of every currency recorded on or
+// after since, oldest first.
+func (c *Client) GetExchangeRatesSince(ctx context.Context, since time.Time) ([]ExchangeRate, error) {
+	params := url.Values{}
+	params.Add("fields", "country_currency_desc,exchange_rate,record_date")
+	params.Add("filter", fmt.Sprintf("record_date:gte:%s", since.Format("2006-01-02")))
+	params.Add("sort", "record_date,country_currency_desc")
+
+	return c.fetchRates(ctx, params)
+}
+
+// fetchRates walks every page of a query, skipping records that cannot be
+// parsed. It stops when ctx is done and fails rather than return more than
+// the record cap.
+func (c *Client) fetchRates(ctx context.Context, params url.Values) ([]ExchangeRate, error) {
+	params.Set("page[size]", strconv.Itoa(c.pageSize))
+
+	var rates []ExchangeRate
+	for page := 1; ; page++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		params.Set("page[number]", strconv.Itoa(page))
+		apiResp, err := c.fetchPage(ctx, params)
+		if err != nil {
+			return nil, err
+		}
+
+		if c.maxRecords > 0 && apiResp.Meta.TotalCount > c.maxRecords {
+			return nil, fmt.Errorf("%w: %d records match, the cap is %d", errors.ErrTooManyExchangeRates, apiResp.Meta.TotalCount, c.maxRecords)
+		}
+
+		for _, data := range apiResp.Data {
+			rate, err := decimal.NewFromString(data.ExchangeRate)
+			if err != nil {
+				log.Warnf("Skipping invalid rate for %s: %v", data.CountryCode, err)
+				continue
+			}
+
+			effectiveDate, err := time.Parse("2006-01-02", data.EffectiveDate)
+			if err != nil {
+				log.Warnf("Skipping invalid date for %s: %v", data.CountryCode, err)
+				continue
+			}
+
+			rates = append(rates, ExchangeRate{
+				Currency:      data.CountryCode,
+				Rate:          rate,
+				EffectiveDate: effectiveDate,
+			})
+		}
+
+		if c.maxRecords > 0 && len(rates) > c.maxRecords {
+			return nil, fmt.Errorf("%w: more than %d records", errors.ErrTooManyExchangeRates, c.maxRecords)
+		}
+
+		if !apiResp.hasNextPage(page) {
+			return rates, nil
+		}
+	}
+}
+
+// fetchPage requests a single page of the rates endpoint.
+func (c *Client) fetchPage(ctx context.Context, params url.Values) (*ExchangeRateResponse, error) {
 	reqURL := fmt.Sprintf("%s?%s", c.baseURL, params.Encode())
 
 	req, err := http.NewRequestWithContext(ctx, "GET", reqURL, nil)
@@ -151,6 +257,9 @@ func (c *Client) GetExchangeRatesInRange(ctx context.Context, currency string, s
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
 		return nil, fmt.Errorf("%w: failed to execute request", errors.ErrTreasuryAPIError)
 	}
 	defer resp.Body.Close()
@@ -166,26 +275,5 @@ func (c *Client) GetExchangeRatesInRange(ctx context.Context, currency string, s
 		return nil, fmt.Errorf("%w: failed to decode response", errors.ErrTreasuryAPIError)
 	}
 
-	rates := make([]ExchangeRate, 0, len(apiResp.Data))
-	for _, data := range apiResp.Data {
-		rate, err := decimal.NewFromString(data.ExchangeRate)
-		if err != nil {
-			log.Warnf("Skipping invalid rate for %s: %v", data.CountryCode, err)
-			continue
-		}
-
-		effectiveDate, err := time.Parse("2006-01-02", data.EffectiveDate)
-		if err != nil {
-			log.Warnf("Skipping invalid date for %s: %v", data.CountryCode, err)
-			continue
-		}
-
-		rates = append(rates, ExchangeRate{
-			Currency:      currency,
-			Rate:          rate,
-			EffectiveDate: effectiveDate,
-		})
-	}
-
-	return rates, nil
+	return &apiResp, nil
 }
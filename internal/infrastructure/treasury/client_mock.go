@@ -2,6 +2,7 @@ package treasury
 
 import (
 	"context"
+	"sort"
 	"sync"
 	"time"
 
@@ -14,31 +15,33 @@ type MockClient struct {
 	mu    sync.Mutex // Add mutex for thread safety
 }
 
+// NewMockClient returns a client with recent rates for the euro, the pound and
+// the yen, keyed by Treasury descriptor like the real API.
 func NewMockClient() *MockClient {
 	return &MockClient{
 		rates: map[string][]ExchangeRate{
-			"EUR": {
+			"Euro Zone-Euro": {
 				{
-					Currency:      "EUR",
+					Currency:      "Euro Zone-Euro",
 					Rate:          decimal.NewFromFloat(0.85),
 					EffectiveDate: time.Now().AddDate(0, 0, -1), // Yesterday
 				},
 				{
-					Currency:      "EUR",
+					Currency:      "Euro Zone-Euro",
 					Rate:          decimal.NewFromFloat(0.84),
 					EffectiveDate: time.Now().AddDate(0, -1, 0), // Last month
 				},
 			},
-			"GBP": {
+			"United Kingdom-Pound": {
 				{
-					Currency:      "GBP",
+					Currency:      "United Kingdom-Pound",
 					Rate:          decimal.NewFromFloat(0.73),
 					EffectiveDate: time.Now().AddDate(0, 0, -1),
 				},
 			},
-			"JPY": {
+			"Japan-Yen": {
 				{
-					Currency:      "JPY",
+					Currency:      "Japan-Yen",
 					Rate:          decimal.NewFromFloat(110.0),
 					EffectiveDate: time.Now().AddDate(0, 0, -1),
 				},
@@ -102,6 +105,33 @@ func (m *MockClient) GetExchangeRatesInRange(ctx context.Context, currency strin
 	return result, nil
 }
 
+func (m *MockClient) GetExchangeRatesSince(ctx context.Context, since time.Time) ([]ExchangeRate, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var result []ExchangeRate
+	for _, rates := range m.rates {
+		for _, rate := range rates {
+			if !rate.EffectiveDate.Before(since) {
+				result = append(result, rate)
+			}
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].EffectiveDate.Equal(result[j].EffectiveDate) {
+			return result[i].EffectiveDate.Before(result[j].EffectiveDate)
+		}
+		return result[i].Currency < result[j].Currency
+	})
+
+	return result, nil
+}
+
 func (m *MockClient) AddMockRate(currency string, rate decimal.Decimal, date time.Time) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
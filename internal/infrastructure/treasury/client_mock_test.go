@@ -15,7 +15,7 @@ func TestMockClient_GetExchangeRate(t *testing.T) {
 	now := time.Now()
 
 	// Add the custom rate before testing
-	client.AddMockRate("CAD", decimal.NewFromFloat(1.25), now.Add(-24*time.Hour))
+	client.AddMockRate("Canada-Dollar", decimal.NewFromFloat(1.25), now.Add(-24*time.Hour))
 
 	tests := []struct {
 		name          string
@@ -25,8 +25,8 @@ func TestMockClient_GetExchangeRate(t *testing.T) {
 		expectedError error
 	}{
 		{
-			name:          "Valid EUR rate",
-			currency:      "EUR",
+			name:          "Valid euro rate",
+			currency:      "Euro Zone-Euro",
 			date:          now,
 			expectedRate:  decimal.NewFromFloat(0.85),
 			expectedError: nil,
@@ -40,14 +40,14 @@ func TestMockClient_GetExchangeRate(t *testing.T) {
 		},
 		{
 			name:          "Rate too old",
-			currency:      "EUR",
+			currency:      "Euro Zone-Euro",
 			date:          now.AddDate(-1, 0, 0),
 			expectedRate:  decimal.Zero,
 			expectedError: errors.ErrNoValidExchangeRate,
 		},
 		{
 			name:          "Custom mock rate",
-			currency:      "CAD",
+			currency:      "Canada-Dollar",
 			date:          now,
 			expectedRate:  decimal.NewFromFloat(1.25),
 			expectedError: nil,
@@ -84,8 +84,8 @@ func TestMockClient_GetExchangeRatesInRange(t *testing.T) {
 		expectedError error
 	}{
 		{
-			name:          "Valid EUR rates",
-			currency:      "EUR",
+			name:          "Valid euro rates",
+			currency:      "Euro Zone-Euro",
 			startDate:     now.AddDate(0, -2, 0),
 			endDate:       now,
 			expectedCount: 2,
@@ -101,7 +101,7 @@ func TestMockClient_GetExchangeRatesInRange(t *testing.T) {
 		},
 		{
 			name:          "No rates in range",
-			currency:      "EUR",
+			currency:      "Euro Zone-Euro",
 			startDate:     now.AddDate(-1, 0, 0),
 			endDate:       now.AddDate(-1, 0, 0).AddDate(0, 1, 0),
 			expectedCount: 0,
@@ -3,13 +3,17 @@ package treasury
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"testing"
 	"time"
 
+	"github.com/Athla/vr-software-challenge/internal/domain/errors"
 	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestGetExchangeRate(t *testing.T) {
@@ -26,11 +30,7 @@ func TestGetExchangeRate(t *testing.T) {
 					EffectiveDate: "2024-01-01",
 				},
 			},
-			Meta: struct {
-				Count int `json:"count"`
-			}{
-				Count: 1,
-			},
+			Meta: Meta{Count: 1, TotalCount: 1, TotalPages: 1},
 		}
 		json.NewEncoder(w).Encode(response)
 	}))
@@ -71,11 +71,7 @@ func TestGetExchangeRatesInRange(t *testing.T) {
 					EffectiveDate: "2023-12-01",
 				},
 			},
-			Meta: struct {
-				Count int `json:"count"`
-			}{
-				Count: 2,
-			},
+			Meta: Meta{Count: 2, TotalCount: 2, TotalPages: 1},
 		}
 		json.NewEncoder(w).Encode(response)
 	}))
@@ -97,3 +93,157 @@ func TestGetExchangeRatesInRange(t *testing.T) {
 	assert.Equal(t, decimal.NewFromFloat(0.85), rates[0].Rate)
 	assert.Equal(t, decimal.NewFromFloat(0.84), rates[1].Rate)
 }
+
+func TestGetExchangeRatesSince(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "record_date:gte:2024-01-01", r.URL.Query().Get("filter"))
+		assert.Equal(t, "record_date,country_currency_desc", r.URL.Query().Get("sort"))
+
+		response := ExchangeRateResponse{
+			Data: []struct {
+				CountryCode   string `json:"country_currency_desc"`
+				ExchangeRate  string `json:"exchange_rate"`
+				EffectiveDate string `json:"record_date"`
+			}{
+				{CountryCode: "Canada-Dollar", ExchangeRate: "1.35", EffectiveDate: "2024-03-31"},
+				{CountryCode: "Euro Zone-Euro", ExchangeRate: "0.926", EffectiveDate: "2024-03-31"},
+			},
+		}
+		json.NewEncoder(w).Encode(response)
+	}))
+	defer server.Close()
+
+	client := NewClient(
+		WithBaseURL(server.URL),
+		WithHttpClient(server.Client()),
+	)
+
+	rates, err := client.GetExchangeRatesSince(context.Background(), time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	assert.NoError(t, err)
+	assert.Len(t, rates, 2)
+	assert.Equal(t, "Canada-Dollar", rates[0].Currency)
+	assert.Equal(t, "Euro Zone-Euro", rates[1].Currency)
+	assert.Equal(t, decimal.RequireFromString("0.926"), rates[1].Rate)
+}
+
+// pagedServer serves records in pages of the requested size, with the
+// pagination metadata and links of the fiscaldata API. Without metadata,
+// only the links tell whether there is a next page.
+func pagedServer(t *testing.T, records int, withMeta bool, requests *int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*requests++
+		size, _ := strconv.Atoi(r.URL.Query().Get("page[size]"))
+		number, _ := strconv.Atoi(r.URL.Query().Get("page[number]"))
+		require.Positive(t, size)
+		require.Positive(t, number)
+
+		var response ExchangeRateResponse
+		for i := (number - 1) * size; i < min(number*size, records); i++ {
+			response.Data = append(response.Data, struct {
+				CountryCode   string `json:"country_currency_desc"`
+				ExchangeRate  string `json:"exchange_rate"`
+				EffectiveDate string `json:"record_date"`
+			}{
+				CountryCode:   fmt.Sprintf("Currency-%03d", i),
+				ExchangeRate:  "1.5",
+				EffectiveDate: "2024-03-31",
+			})
+		}
+
+		pages := (records + size - 1) / size
+		if withMeta {
+			response.Meta = Meta{Count: len(response.Data), TotalCount: records, TotalPages: pages}
+		}
+		if number < pages {
+			next := fmt.Sprintf("&page%%5Bnumber%%5D=%d&page%%5Bsize%%5D=%d", number+1, size)
+			response.Links.Next = &next
+		}
+		json.NewEncoder(w).Encode(response)
+	}))
+}
+
+func TestGetExchangeRatesSincePaginates(t *testing.T) {
+	for _, withMeta := range []bool{true, false} {
+		t.Run(fmt.Sprintf("metadata %v", withMeta), func(t *testing.T) {
+			requests := 0
+			server := pagedServer(t, 250, withMeta, &requests)
+			defer server.Close()
+
+			client := NewClient(
+				WithBaseURL(server.URL),
+				WithHttpClient(server.Client()),
+				WithPageSize(100),
+			)
+
+			rates, err := client.GetExchangeRatesSince(context.Background(), time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+
+			require.NoError(t, err)
+			assert.Equal(t, 3, requests)
+			require.Len(t, rates, 250)
+			assert.Equal(t, "Currency-000", rates[0].Currency)
+			assert.Equal(t, "Currency-249", rates[249].Currency)
+		})
+	}
+}
+
+func TestGetExchangeRatesRecordCap(t *testing.T) {
+	for _, withMeta := range []bool{true, false} {
+		t.Run(fmt.Sprintf("metadata %v", withMeta), func(t *testing.T) {
+			requests := 0
+			server := pagedServer(t, 250, withMeta, &requests)
+			defer server.Close()
+
+			client := NewClient(
+				WithBaseURL(server.URL),
+				WithHttpClient(server.Client()),
+				WithPageSize(100),
+				WithMaxRecords(150),
+			)
+
+			_, err := client.GetExchangeRatesSince(context.Background(), time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+
+			assert.ErrorIs(t, err, errors.ErrTooManyExchangeRates)
+			if withMeta {
+				assert.Equal(t, 1, requests, "the total count is checked on the first page")
+			} else {
+				assert.Equal(t, 2, requests)
+			}
+		})
+	}
+}
+
+func TestGetExchangeRatesStopsWithContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	requests := 0
+	server := pagedServer(t, 250, true, &requests)
+	defer server.Close()
+
+	client := NewClient(
+		WithBaseURL(server.URL),
+		WithHttpClient(&http.Client{Transport: cancelAfter(server.Client().Transport, 1, cancel)}),
+		WithPageSize(100),
+	)
+
+	_, err := client.GetExchangeRatesSince(ctx, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 1, requests)
+}
+
+// cancelAfter cancels the context once n requests went through transport.
+func cancelAfter(transport http.RoundTripper, n int, cancel context.CancelFunc) http.RoundTripper {
+	return roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		resp, err := transport.RoundTrip(r)
+		if n--; n == 0 {
+			cancel()
+		}
+		return resp, err
+	})
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
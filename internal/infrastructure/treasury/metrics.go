@@ -0,0 +1,13 @@
+package treasury
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// rateLookups counts the lookups answered by CachingClient, by the source of
+// the rates served.
+var rateLookups = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "treasury_rate_lookups_total",
+	Help: "The total number of exchange rate lookups, by source",
+}, []string{"source"})
@@ -0,0 +1,29 @@
+package processing
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Outcomes of processing a transaction.
+const (
+	outcomeCompleted = "completed"
+	outcomeFailed    = "failed"
+	outcomeSkipped   = "skipped"
+)
+
+var (
+	// transactionsProcessed counts pipeline runs that settled a transaction,
+	// or skipped one that was already settled. Runs that hit a transient
+	// error are not counted until they settle.
+	transactionsProcessed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "transactions_processed_total",
+		Help: "The total number of transactions processed, by outcome",
+	}, []string{"outcome"})
+
+	stepDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "transaction_processing_step_duration_seconds",
+		Help:    "Time spent in each processing step",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"step"})
+)
@@ -0,0 +1,128 @@
+// Package processing moves a newly created transaction through an ordered
+// chain of steps (validation, enrichment, rules and persistence) and settles
+// it as COMPLETED or FAILED.
+package processing
+
+import (
+	"context"
+	go_errors "errors"
+	"fmt"
+	"time"
+
+	"github.com/Athla/vr-software-challenge/internal/domain/models"
+	"github.com/Athla/vr-software-challenge/internal/repository"
+	"github.com/charmbracelet/log"
+	"github.com/google/uuid"
+)
+
+// Job is a transaction going through the pipeline. Steps pass what they learn
+// about it to later steps through Attributes.
+type Job struct {
+	Transaction *models.Transaction
+	Attributes  map[string]string
+}
+
+// Step is one stage of the pipeline. A step that returns a RejectionError
+// fails the transaction; any other error is treated as transient and the
+// whole pipeline is run again later.
+type Step interface {
+	Name() string
+	Run(ctx context.Context, job *Job) error
+}
+
+// RejectionError is a business failure: running the step again would give
+// the same result.
+type RejectionError struct {
+	Reason string
+}
+
+func (e *RejectionError) Error() string {
+	return e.Reason
+}
+
+// Reject returns a RejectionError with a formatted reason.
+func Reject(format string, args ...any) error {
+	return &RejectionError{Reason: fmt.Sprintf(format, args...)}
+}
+
+// Pipeline runs the steps in order for a transaction.
+type Pipeline struct {
+	txRepo repository.TransactionRepository
+	steps  []Step
+}
+
+// NewPipeline creates a pipeline of steps. The last step is expected to
+// persist the outcome, as PersistenceStep does.
+func NewPipeline(txRepo repository.TransactionRepository, steps ...Step) *Pipeline {
+	return &Pipeline{
+		txRepo: txRepo,
+		steps:  steps,
+	}
+}
+
+// Process moves the transaction to PROCESSING and runs every step. A rejected
+// transaction is moved to FAILED with the step and reason, and Process returns
+// nil. Other step errors are returned with the transaction left in
+// PROCESSING, which a later call resumes from.
+//
+// Only PENDING transactions are processed; COMPLETED, VOIDED and FAILED ones
+// are already settled and skipped, so a duplicated event is harmless. Use
+// Reprocess to run a FAILED transaction again.
+func (p *Pipeline) Process(ctx context.Context, id uuid.UUID) error {
+	return p.process(ctx, id, false)
+}
+
+// Reprocess is Process for an explicit request to run the transaction again,
+// such as a replayed event: FAILED transactions are processed as well.
+func (p *Pipeline) Reprocess(ctx context.Context, id uuid.UUID) error {
+	return p.process(ctx, id, true)
+}
+
+func (p *Pipeline) process(ctx context.Context, id uuid.UUID, retryFailed bool) error {
+	tx, err := p.txRepo.GetById(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case tx.Status == models.StatusCompleted, tx.Status == models.StatusVoided,
+		tx.Status == models.StatusFailed && !retryFailed:
+		log.Infof("Transaction %s is already %s, skipping processing", id, tx.Status)
+		transactionsProcessed.WithLabelValues(outcomeSkipped).Inc()
+		return nil
+	case tx.Status == models.StatusPending, tx.Status == models.StatusFailed:
+		if err := p.txRepo.UpdateStatus(ctx, id, tx.Status, models.StatusProcessing); err != nil {
+			return err
+		}
+		tx.Status = models.StatusProcessing
+	case tx.Status == models.StatusProcessing:
+		log.Infof("Resuming processing of transaction %s", id)
+	}
+
+	job := &Job{
+		Transaction: tx,
+		Attributes:  map[string]string{},
+	}
+
+	for _, step := range p.steps {
+		start := time.Now()
+		err := step.Run(ctx, job)
+		stepDuration.WithLabelValues(step.Name()).Observe(time.Since(start).Seconds())
+
+		var rejection *RejectionError
+		if go_errors.As(err, &rejection) {
+			log.Warnf("Transaction %s rejected by %s step: %s", id, step.Name(), rejection.Reason)
+			if err := p.txRepo.Fail(ctx, id, step.Name(), rejection.Reason); err != nil {
+				return err
+			}
+			transactionsProcessed.WithLabelValues(outcomeFailed).Inc()
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%s step: %w", step.Name(), err)
+		}
+	}
+
+	transactionsProcessed.WithLabelValues(outcomeCompleted).Inc()
+	return nil
+}
@@ -0,0 +1,185 @@
+package processing
+
+import (
+	"context"
+	go_errors "errors"
+	"testing"
+	"time"
+
+	"github.com/Athla/vr-software-challenge/internal/domain/errors"
+	"github.com/Athla/vr-software-challenge/internal/domain/models"
+	"github.com/Athla/vr-software-challenge/internal/repository"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// funcStep is a step running fn.
+type funcStep struct {
+	name string
+	fn   func(ctx context.Context, job *Job) error
+}
+
+func (s funcStep) Name() string                            { return s.name }
+func (s funcStep) Run(ctx context.Context, job *Job) error { return s.fn(ctx, job) }
+
+func newTransaction(t *testing.T, repo *repository.MockTransactionRepository, amount string) *models.Transaction {
+	tx := &models.Transaction{
+		ID:              uuid.New(),
+		Description:     "Office Supplies",
+		TransactionDate: time.Now().UTC().AddDate(0, 0, -3),
+		AmountUSD:       decimal.RequireFromString(amount),
+		Status:          models.StatusPending,
+	}
+	require.NoError(t, repo.Create(context.Background(), tx))
+	return tx
+}
+
+func TestPipelineCompletes(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewMockTransactionRepository()
+	tx := newTransaction(t, repo, "100.00")
+
+	var statuses []models.TransactionStatus
+	pipeline := NewPipeline(repo,
+		NewValidationStep(),
+		NewEnrichmentStep(func(ctx context.Context, job *Job) error {
+			statuses = append(statuses, job.Transaction.Status)
+			job.Attributes["category"] = "office"
+			return nil
+		}),
+		NewRulesStep(Rule{Name: "category", Check: func(ctx context.Context, job *Job) error {
+			if job.Attributes["category"] != "office" {
+				return Reject("not enriched")
+			}
+			return nil
+		}}),
+		NewPersistenceStep(repo),
+	)
+
+	require.NoError(t, pipeline.Process(ctx, tx.ID))
+	assert.Equal(t, []models.TransactionStatus{models.StatusProcessing}, statuses)
+
+	stored, _ := repo.GetById(ctx, tx.ID)
+	assert.Equal(t, models.StatusCompleted, stored.Status)
+	assert.NotNil(t, stored.ProcessedAt)
+
+	// A duplicated event leaves the settled transaction alone.
+	require.NoError(t, pipeline.Process(ctx, tx.ID))
+	assert.Equal(t, models.StatusCompleted, stored.Status)
+}
+
+func TestPipelineRejects(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewMockTransactionRepository()
+	tx := newTransaction(t, repo, "5000.00")
+
+	persisted := false
+	strict := NewPipeline(repo,
+		NewValidationStep(),
+		NewRulesStep(MaxAmountRule(decimal.NewFromInt(1000))),
+		funcStep{name: StepPersistence, fn: func(context.Context, *Job) error {
+			persisted = true
+			return nil
+		}},
+	)
+
+	require.NoError(t, strict.Process(ctx, tx.ID), "a rejection is a handled outcome")
+	assert.False(t, persisted, "steps after the rejection do not run")
+
+	stored, _ := repo.GetById(ctx, tx.ID)
+	assert.Equal(t, models.StatusFailed, stored.Status)
+	require.NotNil(t, stored.FailedStep)
+	assert.Equal(t, StepRules, *stored.FailedStep)
+	assert.Equal(t, "max_amount: amount 5000.00 exceeds 1000.00 USD", *stored.FailureReason)
+
+	// A duplicated event leaves the failed transaction alone.
+	lenient := NewPipeline(repo, NewValidationStep(), NewPersistenceStep(repo))
+	require.NoError(t, lenient.Process(ctx, tx.ID))
+	assert.Equal(t, models.StatusFailed, stored.Status)
+
+	// Reprocessing runs it again, and completing it clears the failure.
+	require.NoError(t, lenient.Reprocess(ctx, tx.ID))
+	assert.Equal(t, models.StatusCompleted, stored.Status)
+	assert.Nil(t, stored.FailedStep)
+	assert.Nil(t, stored.FailureReason)
+}
+
+func TestPipelineSkipsManuallyFailed(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewMockTransactionRepository()
+	tx := newTransaction(t, repo, "10.00")
+	require.NoError(t, repo.UpdateStatus(ctx, tx.ID, models.StatusPending, models.StatusProcessing))
+	require.NoError(t, repo.Fail(ctx, tx.ID, "manual", "Card reported stolen"))
+
+	pipeline := NewPipeline(repo, NewValidationStep(), NewPersistenceStep(repo))
+
+	// A late duplicate of the creation event does not complete it.
+	require.NoError(t, pipeline.Process(ctx, tx.ID))
+	stored, _ := repo.GetById(ctx, tx.ID)
+	assert.Equal(t, models.StatusFailed, stored.Status)
+	require.NotNil(t, stored.FailedStep)
+	assert.Equal(t, "manual", *stored.FailedStep)
+	assert.Equal(t, "Card reported stolen", *stored.FailureReason)
+}
+
+func TestPipelineResumesAfterTransientErrors(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewMockTransactionRepository()
+	tx := newTransaction(t, repo, "10.00")
+
+	unavailable := go_errors.New("rates service unavailable")
+	calls := 0
+	pipeline := NewPipeline(repo,
+		NewEnrichmentStep(func(context.Context, *Job) error {
+			calls++
+			if calls == 1 {
+				return unavailable
+			}
+			return nil
+		}),
+		NewPersistenceStep(repo),
+	)
+
+	err := pipeline.Process(ctx, tx.ID)
+	assert.ErrorIs(t, err, unavailable)
+	assert.ErrorContains(t, err, "enrichment step")
+	stored, _ := repo.GetById(ctx, tx.ID)
+	assert.Equal(t, models.StatusProcessing, stored.Status, "transient errors do not fail the transaction")
+
+	require.NoError(t, pipeline.Process(ctx, tx.ID))
+	assert.Equal(t, models.StatusCompleted, stored.Status)
+}
+
+func TestPipelineSkipsVoided(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewMockTransactionRepository()
+	tx := newTransaction(t, repo, "10.00")
+	require.NoError(t, repo.Void(ctx, tx.ID, models.StatusPending, "Duplicate"))
+
+	pipeline := NewPipeline(repo, funcStep{name: "never", fn: func(context.Context, *Job) error {
+		t.Fatal("a voided transaction must not be processed")
+		return nil
+	}})
+	require.NoError(t, pipeline.Process(ctx, tx.ID))
+
+	assert.ErrorIs(t, pipeline.Process(ctx, uuid.New()), errors.ErrTransactionNotFound)
+}
+
+func TestRules(t *testing.T) {
+	created := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	job := &Job{Transaction: &models.Transaction{
+		AmountUSD:       decimal.RequireFromString("250.50"),
+		TransactionDate: created.AddDate(0, 0, -40),
+		CreatedAt:       created,
+	}}
+
+	assert.NoError(t, MaxAmountRule(decimal.NewFromInt(1000)).Check(context.Background(), job))
+	assert.EqualError(t, MaxAmountRule(decimal.NewFromInt(100)).Check(context.Background(), job),
+		"amount 250.50 exceeds 100.00 USD")
+
+	assert.NoError(t, MaxAgeRule(60*24*time.Hour).Check(context.Background(), job))
+	assert.EqualError(t, MaxAgeRule(30*24*time.Hour).Check(context.Background(), job),
+		"transaction date 2024-04-22 is more than 30 days before its creation")
+}
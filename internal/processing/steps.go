@@ -0,0 +1,147 @@
+package processing
+
+import (
+	"context"
+	go_errors "errors"
+	"time"
+
+	"github.com/Athla/vr-software-challenge/internal/domain/models"
+	"github.com/Athla/vr-software-challenge/internal/repository"
+	"github.com/shopspring/decimal"
+)
+
+// Step names, as stored in failed_step.
+const (
+	StepValidation  = "validation"
+	StepEnrichment  = "enrichment"
+	StepRules       = "rules"
+	StepPersistence = "persistence"
+)
+
+// ValidationStep rejects transactions whose stored data no longer passes the
+// checks made when they were created.
+type ValidationStep struct{}
+
+func NewValidationStep() *ValidationStep {
+	return &ValidationStep{}
+}
+
+func (s *ValidationStep) Name() string { return StepValidation }
+
+func (s *ValidationStep) Run(ctx context.Context, job *Job) error {
+	if err := job.Transaction.Validate(); err != nil {
+		return Reject("%s", err)
+	}
+	return nil
+}
+
+// Enricher adds what later steps need to know about a transaction to
+// Job.Attributes, e.g. from another service.
+type Enricher func(ctx context.Context, job *Job) error
+
+// EnrichmentStep runs its enrichers in order.
+type EnrichmentStep struct {
+	enrichers []Enricher
+}
+
+func NewEnrichmentStep(enrichers ...Enricher) *EnrichmentStep {
+	return &EnrichmentStep{
+		enrichers: enrichers,
+	}
+}
+
+func (s *EnrichmentStep) Name() string { return StepEnrichment }
+
+func (s *EnrichmentStep) Run(ctx context.Context, job *Job) error {
+	for _, enrich := range s.enrichers {
+		if err := enrich(ctx, job); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Rule is a business rule. Check returns a RejectionError, through Reject,
+// when the transaction breaks the rule.
+type Rule struct {
+	Name  string
+	Check func(ctx context.Context, job *Job) error
+}
+
+// RulesStep checks every rule in order and stops at the first rejection.
+type RulesStep struct {
+	rules []Rule
+}
+
+func NewRulesStep(rules ...Rule) *RulesStep {
+	return &RulesStep{
+		rules: rules,
+	}
+}
+
+func (s *RulesStep) Name() string { return StepRules }
+
+func (s *RulesStep) Run(ctx context.Context, job *Job) error {
+	for _, rule := range s.rules {
+		if err := rule.Check(ctx, job); err != nil {
+			var rejection *RejectionError
+			if go_errors.As(err, &rejection) {
+				return Reject("%s: %s", rule.Name, rejection.Reason)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// MaxAmountRule rejects transactions above limit.
+func MaxAmountRule(limit decimal.Decimal) Rule {
+	return Rule{
+		Name: "max_amount",
+		Check: func(ctx context.Context, job *Job) error {
+			if job.Transaction.AmountUSD.GreaterThan(limit) {
+				return Reject("amount %s exceeds %s USD", job.Transaction.AmountUSD.StringFixed(2), limit.StringFixed(2))
+			}
+			return nil
+		},
+	}
+}
+
+// MaxAgeRule rejects transactions dated more than maxAge before they were
+// created.
+func MaxAgeRule(maxAge time.Duration) Rule {
+	return Rule{
+		Name: "max_age",
+		Check: func(ctx context.Context, job *Job) error {
+			tx := job.Transaction
+			if age := tx.CreatedAt.Sub(tx.TransactionDate); age > maxAge {
+				return Reject("transaction date %s is more than %d days before its creation",
+					tx.TransactionDate.Format(time.DateOnly), int(maxAge.Hours()/24))
+			}
+			return nil
+		},
+	}
+}
+
+// PersistenceStep settles the transaction as COMPLETED. The update is a
+// compare-and-set, so a transaction voided meanwhile is not completed.
+type PersistenceStep struct {
+	txRepo repository.TransactionRepository
+}
+
+func NewPersistenceStep(txRepo repository.TransactionRepository) *PersistenceStep {
+	return &PersistenceStep{
+		txRepo: txRepo,
+	}
+}
+
+func (s *PersistenceStep) Name() string { return StepPersistence }
+
+func (s *PersistenceStep) Run(ctx context.Context, job *Job) error {
+	tx := job.Transaction
+	if err := s.txRepo.UpdateStatus(ctx, tx.ID, models.StatusProcessing, models.StatusCompleted); err != nil {
+		return err
+	}
+	tx.Status = models.StatusCompleted
+	return nil
+}
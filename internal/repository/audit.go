@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Athla/vr-software-challenge/internal/domain/models"
+	"github.com/charmbracelet/log"
+	"github.com/google/uuid"
+)
+
+// AuditFilter narrows the global audit feed. Zero values disable a condition.
+type AuditFilter struct {
+	Actions []models.AuditAction
+	From    *time.Time
+	To      *time.Time
+	Limit   int
+	Offset  int
+}
+
+// AuditRepository defines the interface for reading the transaction audit log.
+type AuditRepository interface {
+	ListByTransaction(ctx context.Context, transactionID uuid.UUID, limit, offset int) ([]models.AuditLog, error)
+	List(ctx context.Context, filter AuditFilter) ([]models.AuditLog, error)
+}
+
+// postgresAuditRepo implements the AuditRepository interface for PostgreSQL.
+type postgresAuditRepo struct {
+	db *sql.DB
+}
+
+// NewAuditRepository creates a new instance of postgresAuditRepo.
+func NewAuditRepository(db *sql.DB) AuditRepository {
+	return &postgresAuditRepo{
+		db: db,
+	}
+}
+
+// ListByTransaction fetches the audit entries of a transaction in chronological order.
+func (r *postgresAuditRepo) ListByTransaction(ctx context.Context, transactionID uuid.UUID, limit, offset int) ([]models.AuditLog, error) {
+	query := `
+		SELECT id, transaction_id, action, old_status, new_status, reason, changes, created_at
+		FROM transaction_audit_logs
+		WHERE transaction_id = $1
+		ORDER BY created_at ASC, id ASC
+		LIMIT $2 OFFSET $3`
+
+	return r.query(ctx, query, transactionID, limit, offset)
+}
+
+// List fetches the audit feed across all transactions, newest first.
+func (r *postgresAuditRepo) List(ctx context.Context, filter AuditFilter) ([]models.AuditLog, error) {
+	var conditions []string
+	var args []any
+
+	if len(filter.Actions) > 0 {
+		actions := make([]string, len(filter.Actions))
+		for i, action := range filter.Actions {
+			actions[i] = string(action)
+		}
+		args = append(args, actions)
+		conditions = append(conditions, fmt.Sprintf("action = ANY($%d)", len(args)))
+	}
+	if filter.From != nil {
+		args = append(args, *filter.From)
+		conditions = append(conditions, fmt.Sprintf("created_at >= $%d", len(args)))
+	}
+	if filter.To != nil {
+		args = append(args, *filter.To)
+		conditions = append(conditions, fmt.Sprintf("created_at <= $%d", len(args)))
+	}
+
+	query := `
+		SELECT id, transaction_id, action, old_status, new_status, reason, changes, created_at
+		FROM transaction_audit_logs`
+	if len(conditions) > 0 {
+		query += "\n\t\tWHERE " + strings.Join(conditions, " AND ")
+	}
+	args = append(args, filter.Limit, filter.Offset)
+	query += fmt.Sprintf("\n\t\tORDER BY created_at DESC, id DESC\n\t\tLIMIT $%d OFFSET $%d", len(args)-1, len(args))
+
+	return r.query(ctx, query, args...)
+}
+
+func (r *postgresAuditRepo) query(ctx context.Context, query string, args ...any) ([]models.AuditLog, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		log.Errorf("Unable to query audit logs due: %s", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	logs := []models.AuditLog{}
+	for rows.Next() {
+		var entry models.AuditLog
+		var changes []byte
+		if err := rows.Scan(
+			&entry.ID,
+			&entry.TransactionID,
+			&entry.Action,
+			&entry.OldStatus,
+			&entry.NewStatus,
+			&entry.Reason,
+			&changes,
+			&entry.CreatedAt,
+		); err != nil {
+			log.Errorf("Unable to scan audit log due: %s", err)
+			return nil, err
+		}
+		entry.Changes = changes
+		logs = append(logs, entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Errorf("Unable to iterate over audit logs due: %s", err)
+		return nil, err
+	}
+
+	return logs, nil
+}
@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	go_errors "errors"
+	"time"
+
+	"github.com/Athla/vr-software-challenge/internal/domain/models"
+	"github.com/charmbracelet/log"
+)
+
+// IdempotencyRepository defines the interface for idempotency key storage.
+type IdempotencyRepository interface {
+	// Reserve claims the key for a new request. When the key is already held by
+	// a live record, that record is returned instead and reserved is false.
+	Reserve(ctx context.Context, key, fingerprint string, expiresAt time.Time) (existing *models.IdempotencyRecord, reserved bool, err error)
+	Complete(ctx context.Context, key string, responseCode int, responseBody []byte) error
+	Release(ctx context.Context, key string) error
+	DeleteExpired(ctx context.Context) (int64, error)
+}
+
+// postgresIdempotencyRepo implements the IdempotencyRepository interface for PostgreSQL.
+type postgresIdempotencyRepo struct {
+	db *sql.DB
+}
+
+// NewIdempotencyRepository creates a new instance of postgresIdempotencyRepo.
+func NewIdempotencyRepository(db *sql.DB) IdempotencyRepository {
+	return &postgresIdempotencyRepo{
+		db: db,
+	}
+}
+
+// Reserve inserts an in-progress record for the key. An expired record is
+// overwritten in place, so keys can be reused once their TTL has elapsed.
+func (r *postgresIdempotencyRepo) Reserve(ctx context.Context, key, fingerprint string, expiresAt time.Time) (*models.IdempotencyRecord, bool, error) {
+	query := `
+		INSERT INTO idempotency_keys (key, fingerprint, status, expires_at)
+		VALUES ($1, $2, 'IN_PROGRESS', $3)
+		ON CONFLICT (key) DO UPDATE
+		SET fingerprint = EXCLUDED.fingerprint,
+			status = 'IN_PROGRESS',
+			response_code = NULL,
+			response_body = NULL,
+			created_at = CURRENT_TIMESTAMP,
+			expires_at = EXCLUDED.expires_at
+		WHERE idempotency_keys.expires_at <= CURRENT_TIMESTAMP
+		RETURNING key`
+
+	var reservedKey string
+	err := r.db.QueryRowContext(ctx, query, key, fingerprint, expiresAt).Scan(&reservedKey)
+	if err == nil {
+		return nil, true, nil
+	}
+	if !go_errors.Is(err, sql.ErrNoRows) {
+		log.Errorf("Unable to reserve idempotency key due: %v", err)
+		return nil, false, err
+	}
+
+	record := &models.IdempotencyRecord{}
+	var responseCode sql.NullInt64
+	if err := r.db.QueryRowContext(ctx, `
+		SELECT key, fingerprint, status, response_code, response_body, created_at, expires_at
+		FROM idempotency_keys
+		WHERE key = $1`, key).Scan(
+		&record.Key,
+		&record.Fingerprint,
+		&record.Status,
+		&responseCode,
+		&record.ResponseBody,
+		&record.CreatedAt,
+		&record.ExpiresAt,
+	); err != nil {
+		log.Errorf("Unable to fetch idempotency key due: %v", err)
+		return nil, false, err
+	}
+	record.ResponseCode = int(responseCode.Int64)
+
+	return record, false, nil
+}
+
+// Complete stores the response produced for the key.
+func (r *postgresIdempotencyRepo) Complete(ctx context.Context, key string, responseCode int, responseBody []byte) error {
+	query := `
+		UPDATE idempotency_keys
+		SET status = 'COMPLETED', response_code = $2, response_body = $3
+		WHERE key = $1`
+
+	if _, err := r.db.ExecContext(ctx, query, key, responseCode, responseBody); err != nil {
+		log.Errorf("Unable to complete idempotency key due: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+// Release drops an in-progress key so the request can be retried.
+func (r *postgresIdempotencyRepo) Release(ctx context.Context, key string) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE key = $1 AND status = 'IN_PROGRESS'`, key); err != nil {
+		log.Errorf("Unable to release idempotency key due: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+// DeleteExpired removes every record whose TTL has elapsed.
+func (r *postgresIdempotencyRepo) DeleteExpired(ctx context.Context) (int64, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE expires_at <= CURRENT_TIMESTAMP`)
+	if err != nil {
+		log.Errorf("Unable to delete expired idempotency keys due: %v", err)
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
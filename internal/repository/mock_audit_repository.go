@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Athla/vr-software-challenge/internal/domain/models"
+	"github.com/google/uuid"
+)
+
+type MockAuditRepository struct {
+	mu   sync.Mutex
+	logs []models.AuditLog
+}
+
+func NewMockAuditRepository() *MockAuditRepository {
+	return &MockAuditRepository{}
+}
+
+// Add records an audit entry, standing in for the database trigger.
+func (m *MockAuditRepository) Add(entry models.AuditLog) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if entry.ID == uuid.Nil {
+		entry.ID = uuid.New()
+	}
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now()
+	}
+	m.logs = append(m.logs, entry)
+}
+
+func (m *MockAuditRepository) ListByTransaction(ctx context.Context, transactionID uuid.UUID, limit, offset int) ([]models.AuditLog, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	logs := []models.AuditLog{}
+	for _, entry := range m.logs {
+		if entry.TransactionID == transactionID {
+			logs = append(logs, entry)
+		}
+	}
+	sort.SliceStable(logs, func(i, j int) bool {
+		return logs[i].CreatedAt.Before(logs[j].CreatedAt)
+	})
+
+	return paginate(logs, limit, offset), nil
+}
+
+func (m *MockAuditRepository) List(ctx context.Context, filter AuditFilter) ([]models.AuditLog, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	logs := []models.AuditLog{}
+	for _, entry := range m.logs {
+		if len(filter.Actions) > 0 && !containsAction(filter.Actions, entry.Action) {
+			continue
+		}
+		if filter.From != nil && entry.CreatedAt.Before(*filter.From) {
+			continue
+		}
+		if filter.To != nil && entry.CreatedAt.After(*filter.To) {
+			continue
+		}
+		logs = append(logs, entry)
+	}
+	sort.SliceStable(logs, func(i, j int) bool {
+		return logs[i].CreatedAt.After(logs[j].CreatedAt)
+	})
+
+	return paginate(logs, filter.Limit, filter.Offset), nil
+}
+
+func containsAction(actions []models.AuditAction, action models.AuditAction) bool {
+	for _, a := range actions {
+		if a == action {
+			return true
+		}
+	}
+	return false
+}
+
+func paginate[T any](items []T, limit, offset int) []T {
+	start := offset
+	end := offset + limit
+	if start > len(items) {
+		start = len(items)
+	}
+	if end > len(items) {
+		end = len(items)
+	}
+	return items[start:end]
+}
@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Athla/vr-software-challenge/internal/domain/models"
+)
+
+type MockIdempotencyRepository struct {
+	mu      sync.Mutex
+	records map[string]*models.IdempotencyRecord
+}
+
+func NewMockIdempotencyRepository() *MockIdempotencyRepository {
+	return &MockIdempotencyRepository{
+		records: make(map[string]*models.IdempotencyRecord),
+	}
+}
+
+func (m *MockIdempotencyRepository) Reserve(ctx context.Context, key, fingerprint string, expiresAt time.Time) (*models.IdempotencyRecord, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	if record, exists := m.records[key]; exists && !record.Expired(now) {
+		recordCopy := *record
+		return &recordCopy, false, nil
+	}
+
+	m.records[key] = &models.IdempotencyRecord{
+		Key:         key,
+		Fingerprint: fingerprint,
+		Status:      models.IdempotencyInProgress,
+		CreatedAt:   now,
+		ExpiresAt:   expiresAt,
+	}
+	return nil, true, nil
+}
+
+func (m *MockIdempotencyRepository) Complete(ctx context.Context, key string, responseCode int, responseBody []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if record, exists := m.records[key]; exists {
+		record.Status = models.IdempotencyCompleted
+		record.ResponseCode = responseCode
+		record.ResponseBody = responseBody
+	}
+	return nil
+}
+
+func (m *MockIdempotencyRepository) Release(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if record, exists := m.records[key]; exists && record.Status == models.IdempotencyInProgress {
+		delete(m.records, key)
+	}
+	return nil
+}
+
+func (m *MockIdempotencyRepository) DeleteExpired(ctx context.Context) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var deleted int64
+	now := time.Now()
+	for key, record := range m.records {
+		if record.Expired(now) {
+			delete(m.records, key)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
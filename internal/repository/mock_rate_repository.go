@@ -0,0 +1,177 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Athla/vr-software-challenge/internal/domain/models"
+	"github.com/Athla/vr-software-challenge/internal/infrastructure/treasury"
+)
+
+type mockRate struct {
+	rate   treasury.ExchangeRate
+	source models.RateSource
+}
+
+type MockRateRepository struct {
+	mu    sync.Mutex
+	rates map[string]mockRate
+	syncs []models.RateSync
+}
+
+func NewMockRateRepository() *MockRateRepository {
+	return &MockRateRepository{
+		rates: make(map[string]mockRate),
+	}
+}
+
+func rateKey(currency string, date time.Time) string {
+	return currency + "|" + date.Format("2006-01-02")
+}
+
+func (m *MockRateRepository) Save(ctx context.Context, source models.RateSource, rates []treasury.ExchangeRate) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.save(source, rates), nil
+}
+
+func (m *MockRateRepository) save(source models.RateSource, rates []treasury.ExchangeRate) int {
+	stored := 0
+	for _, rate := range rates {
+		key := rateKey(rate.Currency, rate.EffectiveDate)
+		if _, exists := m.rates[key]; exists {
+			continue
+		}
+		rate.EffectiveDate = truncateDate(rate.EffectiveDate)
+		m.rates[key] = mockRate{rate: rate, source: source}
+		stored++
+	}
+	return stored
+}
+
+func (m *MockRateRepository) InRange(ctx context.Context, currency string, from, to time.Time) ([]treasury.ExchangeRate, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	from, to = truncateDate(from), truncateDate(to)
+
+	var rates []treasury.ExchangeRate
+	for _, stored := range m.rates {
+		date := stored.rate.EffectiveDate
+		if stored.rate.Currency == currency && !date.Before(from) && !date.After(to) {
+			rates = append(rates, stored.rate)
+		}
+	}
+
+	sort.Slice(rates, func(i, j int) bool {
+		return rates[i].EffectiveDate.After(rates[j].EffectiveDate)
+	})
+
+	return rates, nil
+}
+
+func (m *MockRateRepository) LatestSyncedDate(ctx context.Context) (time.Time, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var latest time.Time
+	for _, stored := range m.rates {
+		if stored.source == models.RateSourceSync && stored.rate.EffectiveDate.After(latest) {
+			latest = stored.rate.EffectiveDate
+		}
+	}
+	return latest, nil
+}
+
+func (m *MockRateRepository) RecordSync(ctx context.Context, run *models.RateSync, rates []treasury.ExchangeRate) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	run.Stored = m.save(models.RateSourceSync, rates)
+	run.ID = int64(len(m.syncs) + 1)
+	run.FinishedAt = time.Now()
+	m.syncs = append(m.syncs, *run)
+
+	return nil
+}
+
+func (m *MockRateRepository) LastSync(ctx context.Context) (*models.RateSync, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(m.syncs) == 0 {
+		return nil, nil
+	}
+	run := m.syncs[len(m.syncs)-1]
+	return &run, nil
+}
+
+func (m *MockRateRepository) Coverage(ctx context.Context, from, to time.Time) ([]models.RateCoverage, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	from, to = truncateDate(from), truncateDate(to)
+	filtered := !from.IsZero() && !to.IsZero()
+
+	byCurrency := map[string]*models.RateCoverage{}
+	inRange := map[string]bool{}
+	for _, stored := range m.rates {
+		rate := stored.rate
+		c, ok := byCurrency[rate.Currency]
+		if !ok {
+			c = &models.RateCoverage{Currency: rate.Currency, FirstRecordDate: rate.EffectiveDate, LatestRecordDate: rate.EffectiveDate}
+			byCurrency[rate.Currency] = c
+		}
+		if rate.EffectiveDate.Before(c.FirstRecordDate) {
+			c.FirstRecordDate = rate.EffectiveDate
+		}
+		if rate.EffectiveDate.After(c.LatestRecordDate) {
+			c.LatestRecordDate = rate.EffectiveDate
+		}
+		if !rate.EffectiveDate.Before(from) && !rate.EffectiveDate.After(to) {
+			inRange[rate.Currency] = true
+		}
+	}
+
+	var coverage []models.RateCoverage
+	for currency, c := range byCurrency {
+		if !filtered || inRange[currency] {
+			coverage = append(coverage, *c)
+		}
+	}
+	sort.Slice(coverage, func(i, j int) bool {
+		return coverage[i].Currency < coverage[j].Currency
+	})
+
+	return coverage, nil
+}
+
+func (m *MockRateRepository) Descriptor(ctx context.Context, name string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, stored := range m.rates {
+		if strings.EqualFold(stored.rate.Currency, name) {
+			return stored.rate.Currency, nil
+		}
+	}
+	return "", nil
+}
+
+// Source returns how the rate of currency on date was obtained, if stored.
+func (m *MockRateRepository) Source(currency string, date time.Time) (models.RateSource, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, ok := m.rates[rateKey(currency, date)]
+	return stored.source, ok
+}
+
+// truncateDate drops the time of day, as a DATE column does.
+func truncateDate(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
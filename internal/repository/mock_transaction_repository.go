@@ -2,12 +2,16 @@ package repository
 
 import (
 	"context"
+	"slices"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/Athla/vr-software-challenge/internal/domain/errors"
 	"github.com/Athla/vr-software-challenge/internal/domain/models"
 	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
 )
 
 type MockTransactionRepository struct {
@@ -26,10 +30,24 @@ func (m *MockTransactionRepository) Create(ctx context.Context, tx *models.Trans
 	defer m.mu.Unlock()
 
 	tx.CreatedAt = time.Now()
+	tx.Version = 1
 	m.transactions[tx.ID] = tx
 	return nil
 }
 
+func (m *MockTransactionRepository) CreateBatch(ctx context.Context, txs []*models.Transaction) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	for _, tx := range txs {
+		tx.CreatedAt = now
+		tx.Version = 1
+		m.transactions[tx.ID] = tx
+	}
+	return nil
+}
+
 func (m *MockTransactionRepository) GetById(ctx context.Context, id uuid.UUID) (*models.Transaction, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -41,41 +59,216 @@ func (m *MockTransactionRepository) GetById(ctx context.Context, id uuid.UUID) (
 	return tx, nil
 }
 
-func (m *MockTransactionRepository) UpdateStatus(ctx context.Context, id uuid.UUID, status models.TransactionStatus) error {
+func (m *MockTransactionRepository) UpdateStatus(ctx context.Context, id uuid.UUID, from, to models.TransactionStatus) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	if !from.CanTransitionTo(to) {
+		return errors.ErrInvalidTransition
+	}
+	if to == models.StatusVoided {
+		return errors.ErrVoidReasonRequired
+	}
+	if to == models.StatusFailed {
+		return errors.ErrFailureReasonRequired
+	}
+
 	tx, exists := m.transactions[id]
 	if !exists {
 		return errors.ErrTransactionNotFound
 	}
 
-	tx.Status = status
-	if status == models.StatusCompleted {
+	if tx.Status != from {
+		return errors.ErrConcurrentModification
+	}
+
+	tx.Status = to
+	tx.Version++
+	if to == models.StatusCompleted {
 		now := time.Now()
 		tx.ProcessedAt = &now
+		tx.FailedStep = nil
+		tx.FailureReason = nil
+	}
+	return nil
+}
+
+func (m *MockTransactionRepository) Void(ctx context.Context, id uuid.UUID, from models.TransactionStatus, reason string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !from.CanTransitionTo(models.StatusVoided) {
+		return errors.ErrInvalidTransition
+	}
+
+	tx, exists := m.transactions[id]
+	if !exists {
+		return errors.ErrTransactionNotFound
+	}
+
+	if tx.Status != from {
+		return errors.ErrConcurrentModification
+	}
+
+	now := time.Now()
+	tx.Status = models.StatusVoided
+	tx.VoidReason = &reason
+	tx.VoidedAt = &now
+	tx.Version++
+	return nil
+}
+
+func (m *MockTransactionRepository) Fail(ctx context.Context, id uuid.UUID, step, reason string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if step == "" || reason == "" {
+		return errors.ErrFailureReasonRequired
+	}
+
+	tx, exists := m.transactions[id]
+	if !exists {
+		return errors.ErrTransactionNotFound
+	}
+
+	if tx.Status != models.StatusProcessing {
+		return errors.ErrConcurrentModification
+	}
+
+	tx.Status = models.StatusFailed
+	tx.FailedStep = &step
+	tx.FailureReason = &reason
+	tx.Version++
+	return nil
+}
+
+func (m *MockTransactionRepository) Update(ctx context.Context, tx *models.Transaction, expectedVersion int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, exists := m.transactions[tx.ID]
+	if !exists {
+		return errors.ErrTransactionNotFound
+	}
+	if stored.Version != expectedVersion {
+		return errors.ErrVersionMismatch
+	}
+	if !stored.IsEditable() {
+		return errors.ErrTransactionNotEditable
 	}
+
+	tx.Standardize()
+	stored.Description = tx.Description
+	stored.TransactionDate = tx.TransactionDate
+	stored.AmountUSD = tx.AmountUSD
+	stored.Version++
+	tx.Version = stored.Version
 	return nil
 }
 
-func (m *MockTransactionRepository) List(ctx context.Context, limit, offset int) ([]models.Transaction, error) {
+func (m *MockTransactionRepository) List(ctx context.Context, filter ListFilter) (*ListResult, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	var transactions []models.Transaction
+	field := filter.sortField()
+	less := func(a, b models.Transaction) bool {
+		if filter.Ascending {
+			return compareSortValue(field, a, b) < 0
+		}
+		return compareSortValue(field, a, b) > 0
+	}
+
+	var after *models.Transaction
+	if filter.Cursor != "" {
+		value, id, err := decodeCursor(filter)
+		if err != nil {
+			return nil, err
+		}
+		after = &models.Transaction{ID: id}
+		switch v := value.(type) {
+		case decimal.Decimal:
+			after.AmountUSD = v
+		case time.Time:
+			if field == SortByTransactionDate {
+				after.TransactionDate = v
+			} else {
+				after.CreatedAt = v
+			}
+		}
+	}
+
+	matching := []models.Transaction{}
 	for _, tx := range m.transactions {
-		transactions = append(transactions, *tx)
+		if matchesFilter(*tx, filter) {
+			matching = append(matching, *tx)
+		}
 	}
+	sort.Slice(matching, func(i, j int) bool {
+		return less(matching[i], matching[j])
+	})
 
-	// Simulate pagination
-	start := offset
-	end := offset + limit
-	if start > len(transactions) {
-		start = len(transactions)
+	result := &ListResult{Transactions: []models.Transaction{}}
+	if filter.IncludeTotal {
+		total := len(matching)
+		result.Total = &total
 	}
-	if end > len(transactions) {
-		end = len(transactions)
+
+	for _, tx := range matching {
+		if after != nil && !less(*after, tx) {
+			continue
+		}
+		if len(result.Transactions) == filter.Limit {
+			result.NextCursor = encodeCursor(filter, result.Transactions[filter.Limit-1])
+			break
+		}
+		result.Transactions = append(result.Transactions, tx)
 	}
 
-	return transactions[start:end], nil
+	return result, nil
+}
+
+func matchesFilter(tx models.Transaction, filter ListFilter) bool {
+	if len(filter.IDs) > 0 && !slices.Contains(filter.IDs, tx.ID) {
+		return false
+	}
+	if len(filter.Statuses) > 0 {
+		found := false
+		for _, status := range filter.Statuses {
+			if tx.Status == status {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	} else if !filter.IncludeVoided && tx.Status == models.StatusVoided {
+		return false
+	}
+	if filter.DateFrom != nil && tx.TransactionDate.Before(*filter.DateFrom) {
+		return false
+	}
+	if filter.DateTo != nil && tx.TransactionDate.After(*filter.DateTo) {
+		return false
+	}
+	if filter.CreatedFrom != nil && tx.CreatedAt.Before(*filter.CreatedFrom) {
+		return false
+	}
+	if filter.CreatedTo != nil && !tx.CreatedAt.Before(*filter.CreatedTo) {
+		return false
+	}
+	if filter.AmountMin != nil && tx.AmountUSD.LessThan(*filter.AmountMin) {
+		return false
+	}
+	if filter.AmountMax != nil && tx.AmountUSD.GreaterThan(*filter.AmountMax) {
+		return false
+	}
+	description := strings.ToLower(tx.Description)
+	if filter.DescriptionPrefix != "" && !strings.HasPrefix(description, strings.ToLower(filter.DescriptionPrefix)) {
+		return false
+	}
+	if filter.DescriptionContains != "" && !strings.Contains(description, strings.ToLower(filter.DescriptionContains)) {
+		return false
+	}
+	return true
 }
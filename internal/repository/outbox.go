@@ -0,0 +1,172 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/Athla/vr-software-challenge/internal/domain/models"
+	"github.com/Athla/vr-software-challenge/internal/infrastructure/database"
+	"github.com/Athla/vr-software-challenge/internal/infrastructure/messagery"
+	"github.com/charmbracelet/log"
+	"github.com/google/uuid"
+)
+
+// OutboxRepository defines the interface for the transactional outbox.
+type OutboxRepository interface {
+	// ProcessPending locks up to limit unsent messages, oldest first, and hands
+	// them to fn. The messages are marked sent only when fn succeeds; otherwise
+	// the failure is recorded and they are offered again on the next call.
+	ProcessPending(ctx context.Context, limit int, fn func(context.Context, []models.OutboxMessage) error) (int, error)
+}
+
+// postgresOutboxRepo implements the OutboxRepository interface for PostgreSQL.
+type postgresOutboxRepo struct {
+	db *sql.DB
+}
+
+// NewOutboxRepository creates a new instance of postgresOutboxRepo.
+func NewOutboxRepository(db *sql.DB) OutboxRepository {
+	return &postgresOutboxRepo{
+		db: db,
+	}
+}
+
+// outboxEntry is an event to be stored in the outbox.
+type outboxEntry struct {
+	aggregateID uuid.UUID
+	eventType   string
+	payload     any
+}
+
+// outboxInsertChunk bounds the rows of a single insert, keeping it well under
+// the PostgreSQL limit of 65535 parameters.
+const outboxInsertChunk = 1000
+
+// insertOutbox stores events in the outbox as part of the caller's transaction,
+// using chunked multi-row inserts. Each payload is stored already wrapped in
+// its event envelope, so a redelivered message keeps the same event ID.
+func insertOutbox(ctx context.Context, dbTx *sql.Tx, entries ...outboxEntry) error {
+	for start := 0; start < len(entries); start += outboxInsertChunk {
+		chunk := entries[start:min(start+outboxInsertChunk, len(entries))]
+
+		var values strings.Builder
+		args := make([]any, 0, len(chunk)*3)
+		for i, entry := range chunk {
+			event, err := messagery.NewEvent(entry.eventType, entry.aggregateID.String(), entry.payload)
+			if err != nil {
+				log.Errorf("Unable to build outbox event due: %v", err)
+				return err
+			}
+			data, err := json.Marshal(event)
+			if err != nil {
+				log.Errorf("Unable to marshal outbox payload due: %v", err)
+				return err
+			}
+
+			if i > 0 {
+				values.WriteString(", ")
+			}
+			fmt.Fprintf(&values, "($%d, $%d, $%d)", len(args)+1, len(args)+2, len(args)+3)
+			args = append(args, entry.aggregateID, entry.eventType, data)
+		}
+
+		query := `INSERT INTO transaction_outbox (aggregate_id, event_type, payload) VALUES ` + values.String()
+
+		if _, err := dbTx.ExecContext(ctx, query, args...); err != nil {
+			log.Errorf("Unable to insert outbox messages due: %v", err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ProcessPending hands the oldest unsent outbox messages to fn. Rows are locked
+// with SKIP LOCKED so several relays can run side by side without sending the
+// same message twice.
+func (r *postgresOutboxRepo) ProcessPending(
+	ctx context.Context,
+	limit int,
+	fn func(context.Context, []models.OutboxMessage) error,
+) (int, error) {
+	var processed int
+	var publishErr error
+
+	err := database.Transaction(ctx, r.db, func(dbTx *sql.Tx) error {
+		query := `
+			SELECT id, aggregate_id, event_type, payload, created_at, attempts
+			FROM transaction_outbox
+			WHERE sent_at IS NULL
+			ORDER BY id
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED`
+
+		rows, err := dbTx.QueryContext(ctx, query, limit)
+		if err != nil {
+			log.Errorf("Unable to query outbox due: %v", err)
+			return err
+		}
+		defer rows.Close()
+
+		var messages []models.OutboxMessage
+		var ids []int64
+		for rows.Next() {
+			var msg models.OutboxMessage
+			if err := rows.Scan(
+				&msg.ID,
+				&msg.AggregateID,
+				&msg.EventType,
+				&msg.Payload,
+				&msg.CreatedAt,
+				&msg.Attempts,
+			); err != nil {
+				log.Errorf("Unable to scan outbox message due: %v", err)
+				return err
+			}
+			messages = append(messages, msg)
+			ids = append(ids, msg.ID)
+		}
+		if err := rows.Err(); err != nil {
+			log.Errorf("Unable to iterate over outbox due: %v", err)
+			return err
+		}
+		rows.Close()
+
+		if len(messages) == 0 {
+			return nil
+		}
+
+		if fnErr := fn(ctx, messages); fnErr != nil {
+			if _, err := dbTx.ExecContext(ctx, `
+				UPDATE transaction_outbox
+				SET attempts = attempts + 1, last_error = $2
+				WHERE id = ANY($1)`, ids, fnErr.Error()); err != nil {
+				log.Errorf("Unable to record outbox failure due: %v", err)
+				return err
+			}
+			publishErr = fnErr
+			return nil
+		}
+
+		if _, err := dbTx.ExecContext(ctx, `
+			UPDATE transaction_outbox
+			SET sent_at = CURRENT_TIMESTAMP, attempts = attempts + 1, last_error = NULL
+			WHERE id = ANY($1)`, ids); err != nil {
+			log.Errorf("Unable to mark outbox messages as sent due: %v", err)
+			return err
+		}
+		processed = len(messages)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	if publishErr != nil {
+		return 0, publishErr
+	}
+
+	return processed, nil
+}
@@ -0,0 +1,249 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	go_errors "errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Athla/vr-software-challenge/internal/domain/models"
+	"github.com/Athla/vr-software-challenge/internal/infrastructure/database"
+	"github.com/Athla/vr-software-challenge/internal/infrastructure/treasury"
+	"github.com/charmbracelet/log"
+)
+
+// RateRepository stores Treasury exchange rates. A rate is stored once per
+// currency and record date and never overwritten, so a conversion made with
+// it can be reproduced even if Treasury later revises or removes the record.
+type RateRepository interface {
+	// Save stores the rates not stored yet and returns how many were stored.
+	Save(ctx context.Context, source models.RateSource, rates []treasury.ExchangeRate) (int, error)
+	// InRange returns the stored rates of currency recorded between from and
+	// to, inclusive, most recent first.
+	InRange(ctx context.Context, currency string, from, to time.Time) ([]treasury.ExchangeRate, error)
+	// LatestSyncedDate returns the most recent record date stored by the sync
+	// job, or the zero time when nothing was synced yet.
+	LatestSyncedDate(ctx context.Context) (time.Time, error)
+	// RecordSync saves the rates fetched by a sync run and the run itself,
+	// setting its ID and Stored count.
+	RecordSync(ctx context.Context, run *models.RateSync, rates []treasury.ExchangeRate) error
+	// LastSync returns the most recent sync run, or nil when there was none.
+	LastSync(ctx context.Context) (*models.RateSync, error)
+	// Coverage returns the first and latest record dates stored for each
+	// currency, ordered by currency. With from and to set, only currencies
+	// with a rate recorded between them, inclusive, are returned.
+	Coverage(ctx context.Context, from, to time.Time) ([]models.RateCoverage, error)
+	// Descriptor returns the stored spelling of a currency descriptor matched
+	// ignoring case, or "" when no rate is stored under it.
+	Descriptor(ctx context.Context, name string) (string, error)
+}
+
+// postgresRateRepo implements the RateRepository interface for PostgreSQL.
+type postgresRateRepo struct {
+	db *sql.DB
+}
+
+// NewRateRepository creates a new instance of postgresRateRepo.
+func NewRateRepository(db *sql.DB) RateRepository {
+	return &postgresRateRepo{
+		db: db,
+	}
+}
+
+// rateInsertChunk bounds the rows of a single insert, keeping it well under
+// the PostgreSQL limit of 65535 parameters.
+const rateInsertChunk = 1000
+
+func (r *postgresRateRepo) Save(ctx context.Context, source models.RateSource, rates []treasury.ExchangeRate) (int, error) {
+	var stored int
+	err := database.Transaction(ctx, r.db, func(dbTx *sql.Tx) error {
+		var err error
+		stored, err = insertRates(ctx, dbTx, source, rates)
+		return err
+	})
+	return stored, err
+}
+
+// insertRates stores the rates missing from exchange_rates in chunks and
+// returns how many rows were inserted.
+func insertRates(ctx context.Context, dbTx *sql.Tx, source models.RateSource, rates []treasury.ExchangeRate) (int, error) {
+	var stored int
+	for start := 0; start < len(rates); start += rateInsertChunk {
+		chunk := rates[start:min(start+rateInsertChunk, len(rates))]
+
+		var values strings.Builder
+		args := make([]any, 0, len(chunk)*4)
+		for i, rate := range chunk {
+			if i > 0 {
+				values.WriteString(", ")
+			}
+			fmt.Fprintf(&values, "($%d, $%d, $%d, $%d)", len(args)+1, len(args)+2, len(args)+3, len(args)+4)
+			args = append(args, rate.Currency, rate.EffectiveDate.Format("2006-01-02"), rate.Rate, source)
+		}
+
+		query := `
+			INSERT INTO exchange_rates (currency, record_date, exchange_rate, source)
+			VALUES ` + values.String() + `
+			ON CONFLICT (currency, record_date) DO NOTHING`
+
+		result, err := dbTx.ExecContext(ctx, query, args...)
+		if err != nil {
+			log.Errorf("Unable to insert exchange rates due: %v", err)
+			return 0, err
+		}
+		inserted, err := result.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+		stored += int(inserted)
+	}
+
+	return stored, nil
+}
+
+func (r *postgresRateRepo) InRange(ctx context.Context, currency string, from, to time.Time) ([]treasury.ExchangeRate, error) {
+	query := `
+		SELECT currency, record_date, exchange_rate
+		FROM exchange_rates
+		WHERE currency = $1 AND record_date BETWEEN $2 AND $3
+		ORDER BY record_date DESC`
+
+	rows, err := r.db.QueryContext(ctx, query, currency, from.Format("2006-01-02"), to.Format("2006-01-02"))
+	if err != nil {
+		log.Errorf("Unable to query exchange rates due: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rates []treasury.ExchangeRate
+	for rows.Next() {
+		var rate treasury.ExchangeRate
+		if err := rows.Scan(&rate.Currency, &rate.EffectiveDate, &rate.Rate); err != nil {
+			log.Errorf("Unable to scan exchange rate due: %v", err)
+			return nil, err
+		}
+		rates = append(rates, rate)
+	}
+
+	return rates, rows.Err()
+}
+
+func (r *postgresRateRepo) LatestSyncedDate(ctx context.Context) (time.Time, error) {
+	var latest sql.NullTime
+	err := r.db.QueryRowContext(ctx, `SELECT MAX(record_date) FROM exchange_rates WHERE source = $1`, models.RateSourceSync).Scan(&latest)
+	if err != nil {
+		log.Errorf("Unable to query latest synced exchange rate due: %v", err)
+		return time.Time{}, err
+	}
+	return latest.Time, nil
+}
+
+func (r *postgresRateRepo) RecordSync(ctx context.Context, run *models.RateSync, rates []treasury.ExchangeRate) error {
+	return database.Transaction(ctx, r.db, func(dbTx *sql.Tx) error {
+		stored, err := insertRates(ctx, dbTx, models.RateSourceSync, rates)
+		if err != nil {
+			return err
+		}
+		run.Stored = stored
+
+		query := `
+			INSERT INTO exchange_rate_syncs (started_at, since, fetched, stored)
+			VALUES ($1, $2, $3, $4)
+			RETURNING id, finished_at`
+
+		if err := dbTx.QueryRowContext(ctx, query,
+			run.StartedAt,
+			run.Since.Format("2006-01-02"),
+			run.Fetched,
+			run.Stored,
+		).Scan(&run.ID, &run.FinishedAt); err != nil {
+			log.Errorf("Unable to record exchange rate sync due: %v", err)
+			return err
+		}
+
+		return nil
+	})
+}
+
+func (r *postgresRateRepo) LastSync(ctx context.Context) (*models.RateSync, error) {
+	query := `
+		SELECT id, started_at, finished_at, since, fetched, stored
+		FROM exchange_rate_syncs
+		ORDER BY started_at DESC
+		LIMIT 1`
+
+	run := &models.RateSync{}
+	err := r.db.QueryRowContext(ctx, query).Scan(
+		&run.ID,
+		&run.StartedAt,
+		&run.FinishedAt,
+		&run.Since,
+		&run.Fetched,
+		&run.Stored,
+	)
+	if go_errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		log.Errorf("Unable to query last exchange rate sync due: %v", err)
+		return nil, err
+	}
+
+	return run, nil
+}
+
+func (r *postgresRateRepo) Coverage(ctx context.Context, from, to time.Time) ([]models.RateCoverage, error) {
+	query := `
+		SELECT currency, MIN(record_date), MAX(record_date)
+		FROM exchange_rates
+		GROUP BY currency`
+	var args []any
+	if !from.IsZero() && !to.IsZero() {
+		query += `
+		HAVING COUNT(*) FILTER (WHERE record_date BETWEEN $1 AND $2) > 0`
+		args = append(args, from.Format("2006-01-02"), to.Format("2006-01-02"))
+	}
+	query += `
+		ORDER BY currency`
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		log.Errorf("Unable to query exchange rate coverage due: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var coverage []models.RateCoverage
+	for rows.Next() {
+		var c models.RateCoverage
+		if err := rows.Scan(&c.Currency, &c.FirstRecordDate, &c.LatestRecordDate); err != nil {
+			log.Errorf("Unable to scan exchange rate coverage due: %v", err)
+			return nil, err
+		}
+		coverage = append(coverage, c)
+	}
+
+	return coverage, rows.Err()
+}
+
+func (r *postgresRateRepo) Descriptor(ctx context.Context, name string) (string, error) {
+	query := `
+		SELECT currency
+		FROM exchange_rates
+		WHERE lower(currency) = lower($1)
+		LIMIT 1`
+
+	var currency string
+	err := r.db.QueryRowContext(ctx, query, name).Scan(&currency)
+	if go_errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		log.Errorf("Unable to look up exchange rate descriptor due: %v", err)
+		return "", err
+	}
+
+	return currency, nil
+}
@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	go_errors "errors"
+	"fmt"
+
+	"github.com/Athla/vr-software-challenge/internal/infrastructure/database"
+	"github.com/Athla/vr-software-challenge/internal/infrastructure/messagery"
+	"github.com/charmbracelet/log"
+)
+
+// SchemaRepository is the Postgres-backed schema registry shared by every
+// producer and consumer of the events.
+type SchemaRepository interface {
+	messagery.SchemaRegistry
+}
+
+// postgresSchemaRepo implements the SchemaRepository interface for PostgreSQL.
+type postgresSchemaRepo struct {
+	db *sql.DB
+}
+
+// NewSchemaRepository creates a new instance of postgresSchemaRepo.
+func NewSchemaRepository(db *sql.DB) SchemaRepository {
+	return &postgresSchemaRepo{
+		db: db,
+	}
+}
+
+// Register serializes registrations of a subject with an advisory lock, so
+// two instances starting together cannot both claim the next version.
+func (r *postgresSchemaRepo) Register(ctx context.Context, schema *messagery.Schema) (int, error) {
+	if err := schema.Validate(); err != nil {
+		return 0, err
+	}
+
+	definition, err := json.Marshal(schema)
+	if err != nil {
+		return 0, err
+	}
+	fingerprint := schema.Fingerprint()
+
+	var id int
+	err = database.Transaction(ctx, r.db, func(dbTx *sql.Tx) error {
+		if _, err := dbTx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext($1))`, schema.Subject); err != nil {
+			log.Errorf("Unable to lock schema subject due: %v", err)
+			return err
+		}
+
+		rows, err := dbTx.QueryContext(ctx, `
+			SELECT id, fingerprint, definition
+			FROM message_schemas
+			WHERE subject = $1
+			ORDER BY version`, schema.Subject)
+		if err != nil {
+			log.Errorf("Unable to fetch schema versions due: %v", err)
+			return err
+		}
+		defer rows.Close()
+
+		var previous []*messagery.Schema
+		for rows.Next() {
+			var (
+				existingID          int
+				existingFingerprint string
+				existingDefinition  []byte
+			)
+			if err := rows.Scan(&existingID, &existingFingerprint, &existingDefinition); err != nil {
+				log.Errorf("Unable to scan schema version due: %v", err)
+				return err
+			}
+			if existingFingerprint == fingerprint {
+				id = existingID
+				return nil
+			}
+
+			var existing messagery.Schema
+			if err := json.Unmarshal(existingDefinition, &existing); err != nil {
+				return fmt.Errorf("decode schema %d: %w", existingID, err)
+			}
+			previous = append(previous, &existing)
+		}
+		if err := rows.Err(); err != nil {
+			log.Errorf("Unable to fetch schema versions due: %v", err)
+			return err
+		}
+
+		if err := messagery.CheckCompatibility(schema, previous...); err != nil {
+			return fmt.Errorf("register %s: %w", schema.Subject, err)
+		}
+
+		if err := dbTx.QueryRowContext(ctx, `
+			INSERT INTO message_schemas (subject, version, fingerprint, definition)
+			VALUES ($1, $2, $3, $4)
+			RETURNING id`, schema.Subject, len(previous)+1, fingerprint, definition).Scan(&id); err != nil {
+			log.Errorf("Unable to insert schema due: %v", err)
+			return err
+		}
+
+		log.Infof("Registered schema %s version %d as %d", schema.Subject, len(previous)+1, id)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+func (r *postgresSchemaRepo) Schema(ctx context.Context, id int) (*messagery.Schema, error) {
+	var definition []byte
+	err := r.db.QueryRowContext(ctx, `SELECT definition FROM message_schemas WHERE id = $1`, id).Scan(&definition)
+	if go_errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("schema %d: %w", id, messagery.ErrSchemaNotFound)
+	}
+	if err != nil {
+		log.Errorf("Unable to fetch schema due: %v", err)
+		return nil, err
+	}
+
+	var schema messagery.Schema
+	if err := json.Unmarshal(definition, &schema); err != nil {
+		return nil, fmt.Errorf("decode schema %d: %w", id, err)
+	}
+
+	return &schema, nil
+}
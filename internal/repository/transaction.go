@@ -4,9 +4,14 @@ import (
 	"context"
 	"database/sql"
 	go_errors "errors"
+	"fmt"
+	"strings"
+	"time"
 
 	"github.com/Athla/vr-software-challenge/internal/domain/errors"
 	"github.com/Athla/vr-software-challenge/internal/domain/models"
+	"github.com/Athla/vr-software-challenge/internal/infrastructure/database"
+	"github.com/Athla/vr-software-challenge/internal/infrastructure/messagery"
 	"github.com/charmbracelet/log"
 	"github.com/google/uuid"
 )
@@ -14,9 +19,13 @@ import (
 // TransactionRepository defines the interface for transaction repository.
 type TransactionRepository interface {
 	Create(ctx context.Context, tx *models.Transaction) error
+	CreateBatch(ctx context.Context, txs []*models.Transaction) error
 	GetById(ctx context.Context, id uuid.UUID) (*models.Transaction, error)
-	UpdateStatus(ctx context.Context, id uuid.UUID, status models.TransactionStatus) error
-	List(ctx context.Context, limit, offset int) ([]models.Transaction, error)
+	UpdateStatus(ctx context.Context, id uuid.UUID, from, to models.TransactionStatus) error
+	Void(ctx context.Context, id uuid.UUID, from models.TransactionStatus, reason string) error
+	Fail(ctx context.Context, id uuid.UUID, step, reason string) error
+	Update(ctx context.Context, tx *models.Transaction, expectedVersion int) error
+	List(ctx context.Context, filter ListFilter) (*ListResult, error)
 }
 
 // postgresTransactionRepo implements the TransactionRepository interface for PostgreSQL.
@@ -31,7 +40,8 @@ func NewTransactionRepository(db *sql.DB) TransactionRepository {
 	}
 }
 
-// Create inserts a new transaction into the database.
+// Create inserts a new transaction into the database together with the
+// outbox message announcing it, so both are committed or neither is.
 func (r *postgresTransactionRepo) Create(ctx context.Context, tx *models.Transaction) error {
 	tx.Standardize()
 	query := `
@@ -39,18 +49,111 @@ func (r *postgresTransactionRepo) Create(ctx context.Context, tx *models.Transac
 			id, description, transaction_date, amount_usd, status
 		) VALUES (
 		$1, $2, $3, $4, $5
-	) RETURNING created_at`
+	) RETURNING created_at, version`
 
-	if err := r.db.QueryRowContext(
-		ctx,
-		query,
-		tx.ID,
-		tx.Description,
-		tx.TransactionDate,
-		tx.AmountUSD,
-		tx.Status,
-	).Scan(&tx.CreatedAt); err != nil {
-		log.Errorf("Unable to create transaction due: %v", err)
+	return database.Transaction(ctx, r.db, func(dbTx *sql.Tx) error {
+		if err := dbTx.QueryRowContext(
+			ctx,
+			query,
+			tx.ID,
+			tx.Description,
+			tx.TransactionDate,
+			tx.AmountUSD,
+			tx.Status,
+		).Scan(&tx.CreatedAt, &tx.Version); err != nil {
+			log.Errorf("Unable to create transaction due: %v", err)
+			return err
+		}
+
+		return insertOutbox(ctx, dbTx, outboxEntry{
+			aggregateID: tx.ID,
+			eventType:   messagery.EventTransactionCreated,
+			payload:     messagery.NewTransactionCreated(tx),
+		})
+	})
+}
+
+// transactionInsertChunk bounds the rows of a single insert, keeping it well
+// under the PostgreSQL limit of 65535 parameters.
+const transactionInsertChunk = 1000
+
+// CreateBatch inserts several transactions and their outbox messages with
+// chunked multi-row inserts inside a single database transaction.
+func (r *postgresTransactionRepo) CreateBatch(ctx context.Context, txs []*models.Transaction) error {
+	if len(txs) == 0 {
+		return nil
+	}
+
+	for _, tx := range txs {
+		tx.Standardize()
+	}
+
+	return database.Transaction(ctx, r.db, func(dbTx *sql.Tx) error {
+		for start := 0; start < len(txs); start += transactionInsertChunk {
+			chunk := txs[start:min(start+transactionInsertChunk, len(txs))]
+			if err := insertTransactions(ctx, dbTx, chunk); err != nil {
+				return err
+			}
+		}
+
+		entries := make([]outboxEntry, 0, len(txs))
+		for _, tx := range txs {
+			entries = append(entries, outboxEntry{
+				aggregateID: tx.ID,
+				eventType:   messagery.EventTransactionCreated,
+				payload:     messagery.NewTransactionCreated(tx),
+			})
+		}
+
+		return insertOutbox(ctx, dbTx, entries...)
+	})
+}
+
+// insertTransactions stores txs with a single multi-row insert, setting their
+// creation time and version.
+func insertTransactions(ctx context.Context, dbTx *sql.Tx, txs []*models.Transaction) error {
+	var values strings.Builder
+	args := make([]any, 0, len(txs)*5)
+	byID := make(map[uuid.UUID]*models.Transaction, len(txs))
+	for i, tx := range txs {
+		byID[tx.ID] = tx
+
+		if i > 0 {
+			values.WriteString(", ")
+		}
+		n := len(args)
+		fmt.Fprintf(&values, "($%d, $%d, $%d, $%d, $%d)", n+1, n+2, n+3, n+4, n+5)
+		args = append(args, tx.ID, tx.Description, tx.TransactionDate, tx.AmountUSD, tx.Status)
+	}
+
+	query := `
+		INSERT INTO transactions (
+			id, description, transaction_date, amount_usd, status
+		) VALUES ` + values.String() + `
+		RETURNING id, created_at, version`
+
+	rows, err := dbTx.QueryContext(ctx, query, args...)
+	if err != nil {
+		log.Errorf("Unable to create transaction batch due: %v", err)
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id uuid.UUID
+		var createdAt time.Time
+		var version int
+		if err := rows.Scan(&id, &createdAt, &version); err != nil {
+			log.Errorf("Unable to scan created transaction due: %v", err)
+			return err
+		}
+		if tx, ok := byID[id]; ok {
+			tx.CreatedAt = createdAt
+			tx.Version = version
+		}
+	}
+	if err := rows.Err(); err != nil {
+		log.Errorf("Unable to create transaction batch due: %v", err)
 		return err
 	}
 
@@ -60,7 +163,8 @@ func (r *postgresTransactionRepo) Create(ctx context.Context, tx *models.Transac
 // GetById fetches a transaction by ID from the database.
 func (r *postgresTransactionRepo) GetById(ctx context.Context, id uuid.UUID) (*models.Transaction, error) {
 	query := `
-	SELECT id, description, transaction_date, amount_usd, created_at, processed_at, status
+	SELECT id, description, transaction_date, amount_usd, created_at, processed_at, status,
+	       void_reason, voided_at, version, failed_step, failure_reason
 	FROM transactions
 	WHERE id = $1
 	`
@@ -74,6 +178,11 @@ func (r *postgresTransactionRepo) GetById(ctx context.Context, id uuid.UUID) (*m
 		&tx.CreatedAt,
 		&tx.ProcessedAt,
 		&tx.Status,
+		&tx.VoidReason,
+		&tx.VoidedAt,
+		&tx.Version,
+		&tx.FailedStep,
+		&tx.FailureReason,
 	); err != nil {
 		if go_errors.Is(err, sql.ErrNoRows) {
 			log.Errorf("Transaction not found: %s", err)
@@ -87,49 +196,257 @@ func (r *postgresTransactionRepo) GetById(ctx context.Context, id uuid.UUID) (*m
 	return tx, nil
 }
 
-// UpdateStatus updates the status of a transaction in the database.
-func (r *postgresTransactionRepo) UpdateStatus(ctx context.Context, id uuid.UUID, status models.TransactionStatus) error {
+// UpdateStatus moves a transaction from one status to another. The update is a
+// compare-and-set on the current status: if the stored status is no longer
+// `from`, ErrConcurrentModification is returned and nothing is written. The
+// transaction.status_changed event is queued in the same database transaction.
+func (r *postgresTransactionRepo) UpdateStatus(ctx context.Context, id uuid.UUID, from, to models.TransactionStatus) error {
+	if !from.CanTransitionTo(to) {
+		return errors.ErrInvalidTransition
+	}
+	if to == models.StatusVoided {
+		return errors.ErrVoidReasonRequired
+	}
+	if to == models.StatusFailed {
+		return errors.ErrFailureReasonRequired
+	}
+
 	query := `
         UPDATE transactions
         SET status = $1::transaction_status,
             processed_at = CASE
                 WHEN $1::transaction_status = 'COMPLETED' THEN CURRENT_TIMESTAMP
                 ELSE processed_at
+            END,
+            failed_step = CASE
+                WHEN $1::transaction_status = 'COMPLETED' THEN NULL
+                ELSE failed_step
+            END,
+            failure_reason = CASE
+                WHEN $1::transaction_status = 'COMPLETED' THEN NULL
+                ELSE failure_reason
             END
-        WHERE id = $2
-        RETURNING id
+        WHERE id = $2 AND status = $3::transaction_status
+        RETURNING CURRENT_TIMESTAMP
     `
 
-	var returnedID uuid.UUID
-	err := r.db.QueryRowContext(ctx, query, string(status), id).Scan(&returnedID)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return errors.ErrTransactionNotFound
+	return database.Transaction(ctx, r.db, func(dbTx *sql.Tx) error {
+		var changedAt time.Time
+		err := dbTx.QueryRowContext(ctx, query, string(to), id, string(from)).Scan(&changedAt)
+		if go_errors.Is(err, sql.ErrNoRows) {
+			return updateConflict(ctx, dbTx, id)
+		}
+		if err != nil {
+			log.Errorf("Unable to update transaction status due: %v", err)
+			return err
 		}
-		log.Errorf("Unable to update transaction status due: %v", err)
+
+		return insertOutbox(ctx, dbTx, outboxEntry{
+			aggregateID: id,
+			eventType:   messagery.EventTransactionStatusChanged,
+			payload: messagery.TransactionStatusChanged{
+				ID:        id,
+				From:      from,
+				To:        to,
+				ChangedAt: changedAt.UTC(),
+			},
+		})
+	})
+}
+
+// Void moves a transaction to VOIDED, recording the reason, and queues the
+// compensating event in the outbox within the same database transaction. Like
+// UpdateStatus it is a compare-and-set on the current status.
+func (r *postgresTransactionRepo) Void(ctx context.Context, id uuid.UUID, from models.TransactionStatus, reason string) error {
+	if !from.CanTransitionTo(models.StatusVoided) {
+		return errors.ErrInvalidTransition
+	}
+
+	query := `
+        UPDATE transactions
+        SET status = 'VOIDED', void_reason = $1, voided_at = CURRENT_TIMESTAMP
+        WHERE id = $2 AND status = $3::transaction_status
+        RETURNING voided_at
+    `
+
+	return database.Transaction(ctx, r.db, func(dbTx *sql.Tx) error {
+		var voidedAt time.Time
+		err := dbTx.QueryRowContext(ctx, query, reason, id, string(from)).Scan(&voidedAt)
+		if go_errors.Is(err, sql.ErrNoRows) {
+			return updateConflict(ctx, dbTx, id)
+		}
+		if err != nil {
+			log.Errorf("Unable to void transaction due: %v", err)
+			return err
+		}
+
+		return insertOutbox(ctx, dbTx, outboxEntry{
+			aggregateID: id,
+			eventType:   messagery.EventTransactionVoided,
+			payload: messagery.TransactionVoided{
+				ID:             id,
+				PreviousStatus: from,
+				Reason:         reason,
+				VoidedAt:       voidedAt.UTC(),
+			},
+		})
+	})
+}
+
+// Fail moves a processing transaction to FAILED, recording the pipeline step
+// that failed and why, and queues the transaction.status_changed event in the
+// same database transaction. Like UpdateStatus it is a compare-and-set on the
+// current status.
+func (r *postgresTransactionRepo) Fail(ctx context.Context, id uuid.UUID, step, reason string) error {
+	if step == "" || reason == "" {
+		return errors.ErrFailureReasonRequired
+	}
+
+	query := `
+        UPDATE transactions
+        SET status = 'FAILED', failed_step = $1, failure_reason = $2
+        WHERE id = $3 AND status = 'PROCESSING'
+        RETURNING CURRENT_TIMESTAMP
+    `
+
+	return database.Transaction(ctx, r.db, func(dbTx *sql.Tx) error {
+		var changedAt time.Time
+		err := dbTx.QueryRowContext(ctx, query, step, reason, id).Scan(&changedAt)
+		if go_errors.Is(err, sql.ErrNoRows) {
+			return updateConflict(ctx, dbTx, id)
+		}
+		if err != nil {
+			log.Errorf("Unable to fail transaction due: %v", err)
+			return err
+		}
+
+		return insertOutbox(ctx, dbTx, outboxEntry{
+			aggregateID: id,
+			eventType:   messagery.EventTransactionStatusChanged,
+			payload: messagery.TransactionStatusChanged{
+				ID:            id,
+				From:          models.StatusProcessing,
+				To:            models.StatusFailed,
+				ChangedAt:     changedAt.UTC(),
+				FailedStep:    step,
+				FailureReason: reason,
+			},
+		})
+	})
+}
+
+// Update writes the editable fields of a pending transaction. The write only
+// happens while the stored version still equals expectedVersion, so a client
+// editing from a stale read gets ErrVersionMismatch instead of overwriting a
+// newer change. On success tx carries the new version.
+func (r *postgresTransactionRepo) Update(ctx context.Context, tx *models.Transaction, expectedVersion int) error {
+	tx.Standardize()
+	query := `
+        UPDATE transactions
+        SET description = $1, transaction_date = $2, amount_usd = $3
+        WHERE id = $4 AND version = $5 AND status = 'PENDING'
+        RETURNING version
+    `
+
+	err := r.db.QueryRowContext(
+		ctx,
+		query,
+		tx.Description,
+		tx.TransactionDate,
+		tx.AmountUSD,
+		tx.ID,
+		expectedVersion,
+	).Scan(&tx.Version)
+	if err == nil {
+		return nil
+	}
+	if !go_errors.Is(err, sql.ErrNoRows) {
+		log.Errorf("Unable to update transaction due: %v", err)
 		return err
 	}
 
-	return nil
+	var status models.TransactionStatus
+	var version int
+	err = r.db.QueryRowContext(ctx, `SELECT status, version FROM transactions WHERE id = $1`, tx.ID).Scan(&status, &version)
+	switch {
+	case go_errors.Is(err, sql.ErrNoRows):
+		return errors.ErrTransactionNotFound
+	case err != nil:
+		log.Errorf("Unable to fetch transaction version due: %v", err)
+		return err
+	case version != expectedVersion:
+		return errors.ErrVersionMismatch
+	default:
+		return errors.ErrTransactionNotEditable
+	}
+}
+
+// queryRower is satisfied by both *sql.DB and *sql.Tx.
+type queryRower interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
 }
 
-// List fetches a list of transactions from the database with pagination.
-func (r *postgresTransactionRepo) List(ctx context.Context, limit, offset int) ([]models.Transaction, error) {
+// updateConflict explains why a compare-and-set update matched no row: the
+// transaction either does not exist or was changed by someone else.
+func updateConflict(ctx context.Context, q queryRower, id uuid.UUID) error {
+	var exists bool
+	if err := q.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM transactions WHERE id = $1)`, id).Scan(&exists); err != nil {
+		log.Errorf("Unable to check transaction existence due: %v", err)
+		return err
+	}
+	if !exists {
+		return errors.ErrTransactionNotFound
+	}
+
+	return errors.ErrConcurrentModification
+}
+
+// List fetches a page of transactions matching the filter. Pages are keyset
+// paginated on (sort column, id), so rows inserted while a client is paging do
+// not shift later pages.
+func (r *postgresTransactionRepo) List(ctx context.Context, filter ListFilter) (*ListResult, error) {
+	conditions, args := listConditions(filter)
+	field := filter.sortField()
+
+	pageConditions := conditions
+	pageArgs := args
+	if filter.Cursor != "" {
+		value, id, err := decodeCursor(filter)
+		if err != nil {
+			return nil, err
+		}
+
+		op := "<"
+		if filter.Ascending {
+			op = ">"
+		}
+		pageArgs = append(append([]any{}, args...), value, id)
+		pageConditions = append(append([]string{}, conditions...),
+			fmt.Sprintf("(%s, id) %s ($%d, $%d)", field, op, len(pageArgs)-1, len(pageArgs)))
+	}
+
+	direction := "DESC"
+	if filter.Ascending {
+		direction = "ASC"
+	}
+
+	pageArgs = append(pageArgs, filter.Limit+1)
 	query := `
         SELECT id, description, transaction_date, amount_usd,
-               created_at, processed_at, status
-        FROM transactions
-        ORDER BY created_at DESC
-        LIMIT $1 OFFSET $2`
+               created_at, processed_at, status, void_reason, voided_at, version,
+               failed_step, failure_reason
+        FROM transactions` + whereClause(pageConditions) + fmt.Sprintf(`
+        ORDER BY %s %s, id %s
+        LIMIT $%d`, field, direction, direction, len(pageArgs))
 
-	rows, err := r.db.QueryContext(ctx, query, limit, offset)
+	rows, err := r.db.QueryContext(ctx, query, pageArgs...)
 	if err != nil {
 		log.Errorf("Unable to query transactions due: %s", err)
 		return nil, err
 	}
 	defer rows.Close()
 
-	var transactions []models.Transaction
+	transactions := []models.Transaction{}
 	for rows.Next() {
 		var tx models.Transaction
 		err := rows.Scan(
@@ -140,6 +457,11 @@ func (r *postgresTransactionRepo) List(ctx context.Context, limit, offset int) (
 			&tx.CreatedAt,
 			&tx.ProcessedAt,
 			&tx.Status,
+			&tx.VoidReason,
+			&tx.VoidedAt,
+			&tx.Version,
+			&tx.FailedStep,
+			&tx.FailureReason,
 		)
 		if err != nil {
 			log.Errorf("Unable to scan transaction due: %s", err)
@@ -153,5 +475,86 @@ func (r *postgresTransactionRepo) List(ctx context.Context, limit, offset int) (
 		return nil, err
 	}
 
-	return transactions, nil
+	result := &ListResult{Transactions: transactions}
+	if len(transactions) > filter.Limit {
+		result.Transactions = transactions[:filter.Limit]
+		result.NextCursor = encodeCursor(filter, result.Transactions[filter.Limit-1])
+	}
+
+	if filter.IncludeTotal {
+		var total int
+		if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM transactions`+whereClause(conditions), args...).Scan(&total); err != nil {
+			log.Errorf("Unable to count transactions due: %s", err)
+			return nil, err
+		}
+		result.Total = &total
+	}
+
+	return result, nil
+}
+
+// listConditions translates the filter into SQL conditions and their arguments.
+func listConditions(filter ListFilter) ([]string, []any) {
+	var conditions []string
+	var args []any
+
+	if len(filter.IDs) > 0 {
+		ids := make([]string, len(filter.IDs))
+		for i, id := range filter.IDs {
+			ids[i] = id.String()
+		}
+		args = append(args, ids)
+		conditions = append(conditions, fmt.Sprintf("id = ANY($%d::uuid[])", len(args)))
+	}
+	if len(filter.Statuses) > 0 {
+		statuses := make([]string, len(filter.Statuses))
+		for i, status := range filter.Statuses {
+			statuses[i] = string(status)
+		}
+		args = append(args, statuses)
+		conditions = append(conditions, fmt.Sprintf("status::text = ANY($%d)", len(args)))
+	} else if !filter.IncludeVoided {
+		conditions = append(conditions, "status::text <> 'VOIDED'")
+	}
+	if filter.DateFrom != nil {
+		args = append(args, *filter.DateFrom)
+		conditions = append(conditions, fmt.Sprintf("transaction_date >= $%d", len(args)))
+	}
+	if filter.DateTo != nil {
+		args = append(args, *filter.DateTo)
+		conditions = append(conditions, fmt.Sprintf("transaction_date <= $%d", len(args)))
+	}
+	if filter.CreatedFrom != nil {
+		args = append(args, *filter.CreatedFrom)
+		conditions = append(conditions, fmt.Sprintf("created_at >= $%d", len(args)))
+	}
+	if filter.CreatedTo != nil {
+		args = append(args, *filter.CreatedTo)
+		conditions = append(conditions, fmt.Sprintf("created_at < $%d", len(args)))
+	}
+	if filter.AmountMin != nil {
+		args = append(args, *filter.AmountMin)
+		conditions = append(conditions, fmt.Sprintf("amount_usd >= $%d", len(args)))
+	}
+	if filter.AmountMax != nil {
+		args = append(args, *filter.AmountMax)
+		conditions = append(conditions, fmt.Sprintf("amount_usd <= $%d", len(args)))
+	}
+	if filter.DescriptionPrefix != "" {
+		args = append(args, escapeLike(filter.DescriptionPrefix)+"%")
+		conditions = append(conditions, fmt.Sprintf("description ILIKE $%d", len(args)))
+	}
+	if filter.DescriptionContains != "" {
+		args = append(args, "%"+escapeLike(filter.DescriptionContains)+"%")
+		conditions = append(conditions, fmt.Sprintf("description ILIKE $%d", len(args)))
+	}
+
+	return conditions, args
+}
+
+func whereClause(conditions []string) string {
+	if len(conditions) == 0 {
+		return ""
+	}
+	return "\n        WHERE " + strings.Join(conditions, " AND ")
 }
@@ -0,0 +1,163 @@
+package repository
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Athla/vr-software-challenge/internal/domain/errors"
+	"github.com/Athla/vr-software-challenge/internal/domain/models"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+type SortField string
+
+const (
+	SortByCreatedAt       SortField = "created_at"
+	SortByTransactionDate SortField = "transaction_date"
+	SortByAmount          SortField = "amount_usd"
+)
+
+// IsValid reports whether transactions can be ordered by the field.
+func (f SortField) IsValid() bool {
+	switch f {
+	case SortByCreatedAt, SortByTransactionDate, SortByAmount:
+		return true
+	}
+	return false
+}
+
+// ListFilter narrows and orders a transaction listing. Zero values disable a
+// condition; results are ordered by created_at descending unless SortBy is set.
+// Voided transactions are left out unless IncludeVoided is set or VOIDED is one
+// of the requested Statuses.
+type ListFilter struct {
+	IDs           []uuid.UUID
+	Statuses      []models.TransactionStatus
+	IncludeVoided bool
+	DateFrom      *time.Time
+	DateTo        *time.Time
+	// CreatedFrom is inclusive and CreatedTo exclusive.
+	CreatedFrom         *time.Time
+	CreatedTo           *time.Time
+	AmountMin           *decimal.Decimal
+	AmountMax           *decimal.Decimal
+	DescriptionPrefix   string
+	DescriptionContains string
+	SortBy              SortField
+	Ascending           bool
+	Limit               int
+	// Cursor is the opaque NextCursor of a previous page.
+	Cursor       string
+	IncludeTotal bool
+}
+
+// ListResult is a page of transactions.
+type ListResult struct {
+	Transactions []models.Transaction
+	// NextCursor is empty when there are no more pages.
+	NextCursor string
+	// Total is the number of transactions matching the filter, set only when
+	// IncludeTotal was requested.
+	Total *int
+}
+
+// listCursor is the position after the last row of a page. It records the
+// ordering it was produced with so it cannot be replayed against another one.
+type listCursor struct {
+	SortBy    SortField `json:"s"`
+	Ascending bool      `json:"a"`
+	Value     string    `json:"v"`
+	ID        uuid.UUID `json:"i"`
+}
+
+func (f ListFilter) sortField() SortField {
+	if f.SortBy == "" {
+		return SortByCreatedAt
+	}
+	return f.SortBy
+}
+
+func encodeCursor(filter ListFilter, last models.Transaction) string {
+	c := listCursor{
+		SortBy:    filter.sortField(),
+		Ascending: filter.Ascending,
+		Value:     sortValue(filter.sortField(), last),
+		ID:        last.ID,
+	}
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor returns the cursor position as the typed value of the sort
+// column and the tie-breaking ID.
+func decodeCursor(filter ListFilter) (any, uuid.UUID, error) {
+	data, err := base64.RawURLEncoding.DecodeString(filter.Cursor)
+	if err != nil {
+		return nil, uuid.Nil, errors.ErrInvalidCursor
+	}
+
+	var c listCursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, uuid.Nil, errors.ErrInvalidCursor
+	}
+	if c.SortBy != filter.sortField() || c.Ascending != filter.Ascending {
+		return nil, uuid.Nil, fmt.Errorf("%w: cursor was issued for a different ordering", errors.ErrInvalidCursor)
+	}
+
+	value, err := parseSortValue(c.SortBy, c.Value)
+	if err != nil {
+		return nil, uuid.Nil, errors.ErrInvalidCursor
+	}
+
+	return value, c.ID, nil
+}
+
+func sortValue(field SortField, tx models.Transaction) string {
+	switch field {
+	case SortByTransactionDate:
+		return tx.TransactionDate.Format("2006-01-02")
+	case SortByAmount:
+		return tx.AmountUSD.String()
+	default:
+		return tx.CreatedAt.UTC().Format(time.RFC3339Nano)
+	}
+}
+
+func parseSortValue(field SortField, value string) (any, error) {
+	switch field {
+	case SortByTransactionDate:
+		return time.Parse("2006-01-02", value)
+	case SortByAmount:
+		return decimal.NewFromString(value)
+	case SortByCreatedAt:
+		return time.Parse(time.RFC3339Nano, value)
+	default:
+		return nil, errors.ErrInvalidCursor
+	}
+}
+
+// compareSortValue orders two transactions by the sort field, then by ID.
+func compareSortValue(field SortField, a, b models.Transaction) int {
+	var cmp int
+	switch field {
+	case SortByTransactionDate:
+		cmp = a.TransactionDate.Compare(b.TransactionDate)
+	case SortByAmount:
+		cmp = a.AmountUSD.Cmp(b.AmountUSD)
+	default:
+		cmp = a.CreatedAt.Compare(b.CreatedAt)
+	}
+	if cmp != 0 {
+		return cmp
+	}
+	return strings.Compare(a.ID.String(), b.ID.String())
+}
+
+// escapeLike escapes the LIKE wildcards in a user supplied pattern.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -2,17 +2,57 @@ package service
 
 import (
 	"context"
+	go_errors "errors"
+	"slices"
+	"strings"
+	"time"
 
+	"github.com/Athla/vr-software-challenge/internal/domain/currencies"
 	"github.com/Athla/vr-software-challenge/internal/domain/errors"
 	"github.com/Athla/vr-software-challenge/internal/domain/models"
+	"github.com/Athla/vr-software-challenge/internal/infrastructure/messagery"
 	"github.com/Athla/vr-software-challenge/internal/infrastructure/treasury"
 	"github.com/Athla/vr-software-challenge/internal/repository"
+	"github.com/charmbracelet/log"
 	"github.com/google/uuid"
 )
 
 type CurrencyService struct {
 	treasuryClient treasury.Clienter
 	txRepo         repository.TransactionRepository
+	rates          repository.RateRepository
+	events         messagery.Producerer
+	publishTimeout time.Duration
+}
+
+// defaultPublishTimeout bounds how long a conversion event may wait for the
+// broker's delivery report once the request has been answered.
+const defaultPublishTimeout = 5 * time.Second
+
+type CurrencyServiceOption func(*CurrencyService)
+
+// WithEventPublisher makes the service publish a transaction.converted event
+// for every successful conversion.
+func WithEventPublisher(producer messagery.Producerer) CurrencyServiceOption {
+	return func(s *CurrencyService) {
+		s.events = producer
+	}
+}
+
+// WithPublishTimeout bounds how long a transaction.converted event may wait
+// for delivery before it is dropped.
+func WithPublishTimeout(timeout time.Duration) CurrencyServiceOption {
+	return func(s *CurrencyService) {
+		s.publishTimeout = timeout
+	}
+}
+
+// WithRateRepository makes the service read the stored exchange rates first,
+// calling Treasury only for the gaps and storing what it fetched.
+func WithRateRepository(rates repository.RateRepository) CurrencyServiceOption {
+	return func(s *CurrencyService) {
+		s.rates = rates
+	}
 }
 
 type CurrencyServicer interface {
@@ -22,11 +62,17 @@ type CurrencyServicer interface {
 func NewCurrencyService(
 	treasuryClient treasury.Clienter,
 	txRepo repository.TransactionRepository,
+	opts ...CurrencyServiceOption,
 ) *CurrencyService {
-	return &CurrencyService{
+	s := &CurrencyService{
 		treasuryClient: treasuryClient,
 		txRepo:         txRepo,
+		publishTimeout: defaultPublishTimeout,
+	}
+	for _, opt := range opts {
+		opt(s)
 	}
+	return s
 }
 
 func (s *CurrencyService) ConvertTransaction(
@@ -38,14 +84,19 @@ func (s *CurrencyService) ConvertTransaction(
 		return nil, ctx.Err()
 	}
 
+	currency, err := s.resolveCurrency(ctx, targetCurrency)
+	if err != nil {
+		return nil, err
+	}
+
 	tx, err := s.txRepo.GetById(ctx, transactionID)
 	if err != nil {
 		return nil, err
 	}
 
 	sixMonthsAgo := tx.TransactionDate.AddDate(0, -6, 0)
-	rates, err := s.treasuryClient.GetExchangeRatesInRange(ctx, targetCurrency, sixMonthsAgo, tx.TransactionDate)
-	if err != nil {
+	rates, err := s.currencyRates(ctx, currency, sixMonthsAgo, tx.TransactionDate)
+	if err != nil && !go_errors.Is(err, errors.ErrNoValidExchangeRate) {
 		return nil, err
 	}
 
@@ -56,14 +107,190 @@ func (s *CurrencyService) ConvertTransaction(
 	// Use the most recent rate
 	rate := rates[0]
 
-	conversion, err := models.NewCurrencyConversion(tx, targetCurrency, rate.Rate, rate.EffectiveDate)
+	conversion, err := models.NewCurrencyConversion(tx, currency.Name(), rate.Rate, rate.EffectiveDate, int32(currency.MinorUnits))
 	if err != nil {
 		return nil, err
 	}
 
+	conversion.RateSource = string(rateSource(rate))
+
 	if err := conversion.Validate(); err != nil {
 		return nil, err
 	}
 
+	s.publishConverted(ctx, conversion)
+
 	return conversion, nil
 }
+
+// currencyRates returns the rates of currency recorded between from and to
+// under any of its Treasury descriptors, most recent first.
+func (s *CurrencyService) currencyRates(ctx context.Context, currency currencies.Currency, from, to time.Time) ([]treasury.ExchangeRate, error) {
+	descriptors := currency.DescriptorsBetween(from, to)
+	if len(descriptors) == 1 {
+		return s.exchangeRates(ctx, descriptors[0], from, to)
+	}
+
+	var rates []treasury.ExchangeRate
+	for _, descriptor := range descriptors {
+		found, err := s.exchangeRates(ctx, descriptor, from, to)
+		if err != nil && !go_errors.Is(err, errors.ErrNoValidExchangeRate) {
+			return nil, err
+		}
+		rates = append(rates, found...)
+	}
+	slices.SortFunc(rates, func(a, b treasury.ExchangeRate) int {
+		return b.EffectiveDate.Compare(a.EffectiveDate)
+	})
+
+	return rates, nil
+}
+
+// resolveCurrency finds the target currency in the catalog or, failing that,
+// among the descriptors rates are stored under. Anything else is rejected with
+// ErrInvalidCurrency before Treasury is called.
+func (s *CurrencyService) resolveCurrency(ctx context.Context, target string) (currencies.Currency, error) {
+	if currency, ok := currencies.Lookup(target); ok {
+		return currency, nil
+	}
+
+	name := strings.TrimSpace(target)
+	if s.rates == nil || name == "" {
+		return currencies.Currency{}, errors.ErrInvalidCurrency
+	}
+
+	descriptor, err := s.rates.Descriptor(ctx, name)
+	if err != nil {
+		return currencies.Currency{}, err
+	}
+	if descriptor == "" {
+		return currencies.Currency{}, errors.ErrInvalidCurrency
+	}
+
+	return currencies.Uncatalogued(descriptor), nil
+}
+
+// exchangeRates returns the rates of currency recorded between from and to,
+// most recent first. Stored rates are used when the last sync ran after to,
+// so none can be missing; otherwise the gap is filled from Treasury and the
+// fetched rates are stored. Stored rates win over fetched ones for the same
+// record date, so a conversion gives the same result once its rate is stored.
+func (s *CurrencyService) exchangeRates(ctx context.Context, currency string, from, to time.Time) ([]treasury.ExchangeRate, error) {
+	if s.rates == nil {
+		return s.treasuryClient.GetExchangeRatesInRange(ctx, currency, from, to)
+	}
+
+	stored, err := s.rates.InRange(ctx, currency, from, to)
+	if err != nil {
+		return nil, err
+	}
+	if len(stored) > 0 && s.syncedAfter(ctx, to) {
+		return withSource(stored, treasury.SourceCache), nil
+	}
+
+	live, err := s.treasuryClient.GetExchangeRatesInRange(ctx, currency, from, to)
+	if err != nil {
+		if len(stored) > 0 {
+			log.Warnf("Unable to fetch %s exchange rates, using stored ones due: %v", currency, err)
+			return withSource(stored, treasury.SourceStale), nil
+		}
+		return nil, err
+	}
+	if len(live) == 0 {
+		return withSource(stored, treasury.SourceCache), nil
+	}
+	source := rateSource(live[0])
+
+	if _, err := s.rates.Save(ctx, models.RateSourceLive, live); err != nil {
+		log.Warnf("Unable to store fetched exchange rates due: %v", err)
+		return withSource(mergeRates(stored, live), source), nil
+	}
+
+	rates, err := s.rates.InRange(ctx, currency, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return withSource(rates, source), nil
+}
+
+// rateSource is where a rate came from; clients other than
+// treasury.CachingClient leave it unset and always call the API.
+func rateSource(rate treasury.ExchangeRate) treasury.RateSource {
+	if rate.Source == "" {
+		return treasury.SourceLive
+	}
+	return rate.Source
+}
+
+// withSource marks every rate with source.
+func withSource(rates []treasury.ExchangeRate, source treasury.RateSource) []treasury.ExchangeRate {
+	for i := range rates {
+		rates[i].Source = source
+	}
+	return rates
+}
+
+// syncedAfter reports whether the last rate sync started after date.
+func (s *CurrencyService) syncedAfter(ctx context.Context, date time.Time) bool {
+	run, err := s.rates.LastSync(ctx)
+	if err != nil {
+		log.Warnf("Unable to read the last exchange rate sync due: %v", err)
+		return false
+	}
+	return run != nil && run.StartedAt.After(date)
+}
+
+// mergeRates adds the fetched rates whose record date is not stored, keeping
+// the most recent first.
+func mergeRates(stored, fetched []treasury.ExchangeRate) []treasury.ExchangeRate {
+	dates := make(map[string]bool, len(stored))
+	for _, rate := range stored {
+		dates[rate.EffectiveDate.Format(time.DateOnly)] = true
+	}
+
+	merged := slices.Clone(stored)
+	for _, rate := range fetched {
+		if !dates[rate.EffectiveDate.Format(time.DateOnly)] {
+			merged = append(merged, rate)
+		}
+	}
+	slices.SortFunc(merged, func(a, b treasury.ExchangeRate) int {
+		return b.EffectiveDate.Compare(a.EffectiveDate)
+	})
+
+	return merged
+}
+
+// publishConverted announces a conversion. Conversions are reads, so the
+// event is published in the background, detached from the request and bounded
+// by the publish timeout, and a failed publish is only logged: a slow or
+// unavailable broker never holds up the response.
+func (s *CurrencyService) publishConverted(ctx context.Context, conversion *models.CurrencyConversion) {
+	if s.events == nil {
+		return
+	}
+
+	event, err := messagery.NewEvent(
+		messagery.EventTransactionConverted,
+		conversion.TransactionID.String(),
+		messagery.NewTransactionConverted(conversion),
+	)
+	if err != nil {
+		log.Warnf("Unable to build conversion event due: %v", err)
+		return
+	}
+
+	msg, err := event.Message()
+	if err != nil {
+		log.Warnf("Unable to encode conversion event due: %v", err)
+		return
+	}
+
+	publishCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), s.publishTimeout)
+	go func() {
+		defer cancel()
+		if err := s.events.Publish(publishCtx, msg); err != nil {
+			log.Warnf("Unable to publish conversion event due: %v", err)
+		}
+	}()
+}
@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Athla/vr-software-challenge/internal/domain/currencies"
+	"github.com/Athla/vr-software-challenge/internal/domain/models"
+	"github.com/Athla/vr-software-challenge/internal/repository"
+)
+
+const (
+	DefaultCatalogTTL = time.Hour
+	// maxCatalogEntries bounds the cached listings, one per as_of date.
+	maxCatalogEntries = 1000
+)
+
+type CurrencyLister interface {
+	ListCurrencies(ctx context.Context, asOf *time.Time) ([]models.SupportedCurrency, error)
+}
+
+// CurrencyCatalog lists the currencies with stored Treasury rates, which are
+// the ones conversions can target. Listings are cached per as_of date.
+type CurrencyCatalog struct {
+	rates repository.RateRepository
+	ttl   time.Duration
+	now   func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]catalogEntry
+}
+
+type catalogEntry struct {
+	currencies []models.SupportedCurrency
+	fetchedAt  time.Time
+}
+
+type CurrencyCatalogOption func(*CurrencyCatalog)
+
+// WithCatalogTTL sets how long a listing is served without querying the rate
+// repository again.
+func WithCatalogTTL(ttl time.Duration) CurrencyCatalogOption {
+	return func(c *CurrencyCatalog) {
+		c.ttl = ttl
+	}
+}
+
+func NewCurrencyCatalog(rates repository.RateRepository, opts ...CurrencyCatalogOption) *CurrencyCatalog {
+	c := &CurrencyCatalog{
+		rates:   rates,
+		ttl:     DefaultCatalogTTL,
+		now:     time.Now,
+		entries: map[string]catalogEntry{},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// ListCurrencies returns every currency with stored rates, ordered by
+// descriptor. With asOf set, only the currencies with a rate in the six months
+// up to that date are listed, as those are the ones a transaction made on it
+// could be converted to.
+func (c *CurrencyCatalog) ListCurrencies(ctx context.Context, asOf *time.Time) ([]models.SupportedCurrency, error) {
+	var from, to time.Time
+	key := ""
+	if asOf != nil {
+		to = asOf.UTC().Truncate(24 * time.Hour)
+		from = to.AddDate(0, -6, 0)
+		key = to.Format("2006-01-02")
+	}
+
+	if listed, ok := c.get(key); ok {
+		return listed, nil
+	}
+
+	coverage, err := c.rates.Coverage(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	listed := make([]models.SupportedCurrency, 0, len(coverage))
+	for _, cov := range coverage {
+		listed = append(listed, supportedCurrency(cov))
+	}
+
+	c.set(key, listed)
+
+	return listed, nil
+}
+
+// supportedCurrency names the currency of a stored descriptor the way
+// conversions resolve it, so every listed currency can be converted to.
+// Descriptors missing from the catalog have no ISO code.
+func supportedCurrency(cov models.RateCoverage) models.SupportedCurrency {
+	currency, ok := currencies.Lookup(cov.Currency)
+	if !ok {
+		currency = currencies.Uncatalogued(cov.Currency)
+	}
+
+	return models.SupportedCurrency{
+		Code:             currency.Code,
+		Descriptor:       cov.Currency,
+		Country:          currency.Country,
+		FirstRecordDate:  cov.FirstRecordDate,
+		LatestRecordDate: cov.LatestRecordDate,
+	}
+}
+
+func (c *CurrencyCatalog) get(key string) ([]models.SupportedCurrency, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok || c.now().Sub(entry.fetchedAt) >= c.ttl {
+		return nil, false
+	}
+	return entry.currencies, true
+}
+
+func (c *CurrencyCatalog) set(key string, listed []models.SupportedCurrency) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	if _, exists := c.entries[key]; !exists && len(c.entries) >= maxCatalogEntries {
+		for k, entry := range c.entries {
+			if now.Sub(entry.fetchedAt) >= c.ttl {
+				delete(c.entries, k)
+			}
+		}
+		if len(c.entries) >= maxCatalogEntries {
+			clear(c.entries)
+		}
+	}
+	c.entries[key] = catalogEntry{currencies: listed, fetchedAt: now}
+}
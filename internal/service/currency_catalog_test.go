@@ -0,0 +1,126 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Athla/vr-software-challenge/internal/domain/models"
+	"github.com/Athla/vr-software-challenge/internal/infrastructure/treasury"
+	"github.com/Athla/vr-software-challenge/internal/repository"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func TestCurrencyCatalog_ListCurrencies(t *testing.T) {
+	ctx := context.Background()
+	rates := repository.NewMockRateRepository()
+	_, err := rates.Save(ctx, models.RateSourceSync, []treasury.ExchangeRate{
+		{Currency: "Euro Zone-Euro", Rate: decimal.NewFromFloat(0.91), EffectiveDate: date(2020, time.March, 31)},
+		{Currency: "Euro Zone-Euro", Rate: decimal.NewFromFloat(0.92), EffectiveDate: date(2024, time.June, 30)},
+		{Currency: "Peru-Nuevo Sol", Rate: decimal.NewFromFloat(3.4), EffectiveDate: date(2015, time.December, 31)},
+		{Currency: "Bhutan-Ngultrum", Rate: decimal.NewFromFloat(83.1), EffectiveDate: date(2024, time.January, 31)},
+	})
+	require.NoError(t, err)
+
+	catalog := NewCurrencyCatalog(rates)
+
+	listed, err := catalog.ListCurrencies(ctx, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []models.SupportedCurrency{
+		{Descriptor: "Bhutan-Ngultrum", Country: "Bhutan", FirstRecordDate: date(2024, time.January, 31), LatestRecordDate: date(2024, time.January, 31)},
+		{Code: "EUR", Descriptor: "Euro Zone-Euro", Country: "Euro Zone", FirstRecordDate: date(2020, time.March, 31), LatestRecordDate: date(2024, time.June, 30)},
+		{Code: "PEN", Descriptor: "Peru-Nuevo Sol", Country: "Peru", FirstRecordDate: date(2015, time.December, 31), LatestRecordDate: date(2015, time.December, 31)},
+	}, listed)
+
+	// Only the currencies with a rate in the six months up to as_of are
+	// listed, with their full coverage.
+	asOf := date(2024, time.September, 15)
+	listed, err = catalog.ListCurrencies(ctx, &asOf)
+	require.NoError(t, err)
+	require.Len(t, listed, 1)
+	assert.Equal(t, "EUR", listed[0].Code)
+	assert.Equal(t, date(2020, time.March, 31), listed[0].FirstRecordDate)
+
+	asOf = date(2016, time.May, 1)
+	listed, err = catalog.ListCurrencies(ctx, &asOf)
+	require.NoError(t, err)
+	require.Len(t, listed, 1)
+	assert.Equal(t, "PEN", listed[0].Code)
+}
+
+func TestCurrencyCatalog_Cache(t *testing.T) {
+	ctx := context.Background()
+	rates := repository.NewMockRateRepository()
+	catalog := NewCurrencyCatalog(rates, WithCatalogTTL(time.Hour))
+	now := time.Now()
+	catalog.now = func() time.Time { return now }
+
+	listed, err := catalog.ListCurrencies(ctx, nil)
+	require.NoError(t, err)
+	assert.Empty(t, listed)
+
+	_, err = rates.Save(ctx, models.RateSourceSync, []treasury.ExchangeRate{
+		{Currency: "Japan-Yen", Rate: decimal.NewFromFloat(150.2), EffectiveDate: date(2024, time.June, 30)},
+	})
+	require.NoError(t, err)
+
+	// The listing is served from the cache until the TTL passes.
+	listed, err = catalog.ListCurrencies(ctx, nil)
+	require.NoError(t, err)
+	assert.Empty(t, listed)
+
+	now = now.Add(time.Hour)
+	listed, err = catalog.ListCurrencies(ctx, nil)
+	require.NoError(t, err)
+	require.Len(t, listed, 1)
+	assert.Equal(t, "JPY", listed[0].Code)
+}
+
+func TestCurrencyCatalog_ListedCurrenciesConvert(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	rates := repository.NewMockRateRepository()
+	err := rates.RecordSync(ctx, &models.RateSync{StartedAt: now, Since: firstRecordDate}, []treasury.ExchangeRate{
+		{Currency: "Euro Zone-Euro", Rate: decimal.NewFromFloat(0.92), EffectiveDate: now.AddDate(0, -1, 0)},
+		{Currency: "Peru-Sol", Rate: decimal.NewFromFloat(3.7), EffectiveDate: now.AddDate(0, -1, 0)},
+		{Currency: "Bhutan-Ngultrum", Rate: decimal.NewFromFloat(83.1), EffectiveDate: now.AddDate(0, -1, 0)},
+	})
+	require.NoError(t, err)
+
+	txRepo := repository.NewMockTransactionRepository()
+	tx := &models.Transaction{
+		ID:              uuid.New(),
+		Description:     "Valid Transaction",
+		TransactionDate: now,
+		AmountUSD:       decimal.NewFromFloat(100.00),
+		Status:          models.StatusCompleted,
+	}
+	txRepo.Create(ctx, tx)
+
+	service := NewCurrencyService(treasury.NewMockClient(), txRepo, WithRateRepository(rates))
+	listed, err := NewCurrencyCatalog(rates).ListCurrencies(ctx, &now)
+	require.NoError(t, err)
+	require.Len(t, listed, 3)
+
+	for _, currency := range listed {
+		target := currency.Code
+		if target == "" {
+			target = currency.Descriptor
+		}
+		conversion, err := service.ConvertTransaction(ctx, tx.ID, target)
+		require.NoError(t, err, target)
+		assert.Equal(t, target, conversion.TargetCurrency)
+
+		// The descriptor a currency is listed under converts too.
+		_, err = service.ConvertTransaction(ctx, tx.ID, currency.Descriptor)
+		assert.NoError(t, err, currency.Descriptor)
+	}
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/Athla/vr-software-challenge/internal/domain/errors"
 	"github.com/Athla/vr-software-challenge/internal/domain/models"
+	"github.com/Athla/vr-software-challenge/internal/infrastructure/messagery"
 	"github.com/Athla/vr-software-challenge/internal/infrastructure/treasury"
 	"github.com/Athla/vr-software-challenge/internal/repository"
 	"github.com/google/uuid"
@@ -121,7 +122,7 @@ func TestCurrencyService_ConvertTransaction_Rounding(t *testing.T) {
 	mockRepo := repository.NewMockTransactionRepository()
 
 	rate := decimal.NewFromFloat(0.8333)
-	mockTreasury.AddMockRate("EUR", rate, now.Add(-24*time.Hour))
+	mockTreasury.AddMockRate("Euro Zone-Euro", rate, now.Add(-24*time.Hour))
 
 	tx := &models.Transaction{
 		ID:              uuid.New(),
@@ -143,3 +144,287 @@ func TestCurrencyService_ConvertTransaction_Rounding(t *testing.T) {
 	assert.NoError(t, err)
 	assert.NotNil(t, conversion)
 }
+
+func TestCurrencyService_ConvertTransaction_PublishesEvent(t *testing.T) {
+	now := time.Now()
+	mockRepo := repository.NewMockTransactionRepository()
+	tx := &models.Transaction{
+		ID:              uuid.New(),
+		Description:     "Valid Transaction",
+		TransactionDate: now,
+		AmountUSD:       decimal.NewFromFloat(100.00),
+		Status:          models.StatusCompleted,
+	}
+	mockRepo.Create(context.Background(), tx)
+
+	broker := messagery.NewMemoryBroker(1)
+	producer, _ := broker.NewProducer("transactions")
+	service := NewCurrencyService(treasury.NewMockClient(), mockRepo, WithEventPublisher(producer))
+
+	conversion, err := service.ConvertTransaction(context.Background(), tx.ID, "EUR")
+	assert.NoError(t, err)
+
+	assert.Eventually(t, func() bool {
+		return len(broker.Messages("transactions")) == 1
+	}, time.Second, 10*time.Millisecond)
+	messages := broker.Messages("transactions")
+	event, err := messagery.DecodeEvent(&messages[0])
+	assert.NoError(t, err)
+	assert.Equal(t, messagery.EventTransactionConverted, event.Type)
+	assert.Equal(t, tx.ID.String(), event.Subject)
+
+	var data messagery.TransactionConverted
+	assert.NoError(t, event.DecodeData(&data))
+	assert.Equal(t, "EUR", data.TargetCurrency)
+	assert.True(t, conversion.ConvertedAmount.Equal(data.ConvertedAmount))
+}
+
+// unacknowledgedProducer never receives a delivery report: every publish
+// blocks until its context is done.
+type unacknowledgedProducer struct {
+	messagery.Producerer
+	done chan error
+}
+
+func (p *unacknowledgedProducer) Publish(ctx context.Context, msg *messagery.Message) error {
+	<-ctx.Done()
+	p.done <- ctx.Err()
+	return ctx.Err()
+}
+
+func TestCurrencyService_ConvertTransaction_UnacknowledgedPublish(t *testing.T) {
+	mockRepo := repository.NewMockTransactionRepository()
+	tx := &models.Transaction{
+		ID:              uuid.New(),
+		Description:     "Valid Transaction",
+		TransactionDate: time.Now(),
+		AmountUSD:       decimal.NewFromFloat(100.00),
+		Status:          models.StatusCompleted,
+	}
+	mockRepo.Create(context.Background(), tx)
+
+	producer := &unacknowledgedProducer{
+		Producerer: messagery.NewMockProducer(),
+		done:       make(chan error, 1),
+	}
+	service := NewCurrencyService(
+		treasury.NewMockClient(),
+		mockRepo,
+		WithEventPublisher(producer),
+		WithPublishTimeout(50*time.Millisecond),
+	)
+
+	// The request has no deadline, yet the conversion is served at once.
+	converted := make(chan error, 1)
+	go func() {
+		_, err := service.ConvertTransaction(context.Background(), tx.ID, "EUR")
+		converted <- err
+	}()
+	select {
+	case err := <-converted:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("conversion waited for the delivery report")
+	}
+
+	// The background publish gives up once the publish timeout expires.
+	select {
+	case err := <-producer.done:
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	case <-time.After(time.Second):
+		t.Fatal("publish was not bounded by the publish timeout")
+	}
+}
+
+// countingClient counts the live rate lookups, failing them with err when set.
+type countingClient struct {
+	treasury.Clienter
+	calls int
+	err   error
+}
+
+func (c *countingClient) GetExchangeRatesInRange(ctx context.Context, currency string, startDate, endDate time.Time) ([]treasury.ExchangeRate, error) {
+	c.calls++
+	if c.err != nil {
+		return nil, c.err
+	}
+	return c.Clienter.GetExchangeRatesInRange(ctx, currency, startDate, endDate)
+}
+
+func TestCurrencyService_ConvertTransaction_StoredRates(t *testing.T) {
+	ctx := context.Background()
+	txDate := time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC)
+	recordDate := txDate.AddDate(0, 0, -10)
+
+	mockTreasury := treasury.NewMockClient()
+	mockTreasury.AddMockRate("Canada-Dollar", decimal.NewFromFloat(1.35), recordDate)
+	client := &countingClient{Clienter: mockTreasury}
+
+	mockRepo := repository.NewMockTransactionRepository()
+	tx := &models.Transaction{
+		ID:              uuid.New(),
+		Description:     "Stored Rate",
+		TransactionDate: txDate,
+		AmountUSD:       decimal.NewFromFloat(100.00),
+		Status:          models.StatusCompleted,
+	}
+	mockRepo.Create(ctx, tx)
+
+	rates := repository.NewMockRateRepository()
+	service := NewCurrencyService(client, mockRepo, WithRateRepository(rates))
+
+	// Nothing is stored yet: the rate is fetched live and stored.
+	conversion, err := service.ConvertTransaction(ctx, tx.ID, "Canada-Dollar")
+	assert.NoError(t, err)
+	assert.Equal(t, "1.35", conversion.ExchangeRate.String())
+	assert.Equal(t, "live", conversion.RateSource)
+	assert.Equal(t, 1, client.calls)
+	source, stored := rates.Source("Canada-Dollar", recordDate)
+	assert.True(t, stored)
+	assert.Equal(t, models.RateSourceLive, source)
+
+	// Treasury revises the record: the stored rate still wins.
+	mockTreasury.AddMockRate("Canada-Dollar", decimal.NewFromFloat(1.40), recordDate)
+	conversion, err = service.ConvertTransaction(ctx, tx.ID, "Canada-Dollar")
+	assert.NoError(t, err)
+	assert.Equal(t, "1.35", conversion.ExchangeRate.String())
+	assert.Equal(t, 2, client.calls, "rates are fetched until a sync covers the transaction date")
+
+	// Treasury is down: the stored rate is served stale.
+	client.err = errors.ErrTreasuryAPIError
+	conversion, err = service.ConvertTransaction(ctx, tx.ID, "Canada-Dollar")
+	assert.NoError(t, err)
+	assert.Equal(t, "1.35", conversion.ExchangeRate.String())
+	assert.Equal(t, "stale", conversion.RateSource)
+	client.err = nil
+
+	// Once a sync ran after the transaction date, Treasury is not called.
+	assert.NoError(t, rates.RecordSync(ctx, &models.RateSync{StartedAt: txDate.AddDate(0, 0, 1)}, nil))
+	conversion, err = service.ConvertTransaction(ctx, tx.ID, "Canada-Dollar")
+	assert.NoError(t, err)
+	assert.Equal(t, "1.35", conversion.ExchangeRate.String())
+	assert.Equal(t, "cache", conversion.RateSource)
+	assert.Equal(t, 3, client.calls)
+}
+
+func TestCurrencyService_ConvertTransaction_CurrencyForms(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := repository.NewMockTransactionRepository()
+	tx := &models.Transaction{
+		ID:              uuid.New(),
+		Description:     "Valid Transaction",
+		TransactionDate: time.Now(),
+		AmountUSD:       decimal.NewFromFloat(100.00),
+		Status:          models.StatusCompleted,
+	}
+	mockRepo.Create(ctx, tx)
+
+	client := &countingClient{Clienter: treasury.NewMockClient()}
+	service := NewCurrencyService(client, mockRepo)
+
+	for _, currency := range []string{"EUR", "eur", "Euro Zone-Euro"} {
+		conversion, err := service.ConvertTransaction(ctx, tx.ID, currency)
+		assert.NoError(t, err, currency)
+		assert.Equal(t, "EUR", conversion.TargetCurrency, currency)
+	}
+	assert.Equal(t, 3, client.calls)
+
+	// Codes missing from the catalog are not descriptors either, so they are
+	// rejected without calling Treasury.
+	_, err := service.ConvertTransaction(ctx, tx.ID, "XYZ")
+	assert.ErrorIs(t, err, errors.ErrInvalidCurrency)
+	assert.Equal(t, 3, client.calls)
+}
+
+func TestCurrencyService_ConvertTransaction_RenamedCurrency(t *testing.T) {
+	ctx := context.Background()
+	txDate := time.Date(2016, 2, 15, 0, 0, 0, 0, time.UTC)
+
+	mockTreasury := treasury.NewMockClient()
+	mockTreasury.AddMockRate("Peru-Nuevo Sol", decimal.NewFromFloat(3.41), time.Date(2015, 12, 31, 0, 0, 0, 0, time.UTC))
+	mockTreasury.AddMockRate("Peru-Sol", decimal.NewFromFloat(3.50), time.Date(2016, 1, 31, 0, 0, 0, 0, time.UTC))
+
+	mockRepo := repository.NewMockTransactionRepository()
+	tx := &models.Transaction{
+		ID:              uuid.New(),
+		Description:     "Lima Office",
+		TransactionDate: txDate,
+		AmountUSD:       decimal.NewFromFloat(100.00),
+		Status:          models.StatusCompleted,
+	}
+	mockRepo.Create(ctx, tx)
+
+	service := NewCurrencyService(mockTreasury, mockRepo)
+
+	// The window spans the rename: both descriptors are searched and the
+	// most recent rate wins.
+	conversion, err := service.ConvertTransaction(ctx, tx.ID, "PEN")
+	assert.NoError(t, err)
+	assert.Equal(t, "PEN", conversion.TargetCurrency)
+	assert.Equal(t, "3.5", conversion.ExchangeRate.String())
+	assert.Equal(t, "2016-01-31", conversion.ExchangeDate.Format(time.DateOnly))
+}
+
+// lookupOnlyRates fails the test when the full rate coverage is aggregated.
+type lookupOnlyRates struct {
+	*repository.MockRateRepository
+	t *testing.T
+}
+
+func (r lookupOnlyRates) Coverage(ctx context.Context, from, to time.Time) ([]models.RateCoverage, error) {
+	r.t.Error("conversions look descriptors up one at a time")
+	return r.MockRateRepository.Coverage(ctx, from, to)
+}
+
+func TestCurrencyService_ConvertTransaction_UncataloguedDescriptor(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	mockTreasury := treasury.NewMockClient()
+	mockTreasury.AddMockRate("Guinea-Franc", decimal.NewFromFloat(8600), now.AddDate(-2, 0, 0))
+	client := &countingClient{Clienter: mockTreasury}
+
+	mockRepo := repository.NewMockTransactionRepository()
+	tx := &models.Transaction{
+		ID:              uuid.New(),
+		Description:     "Thimphu Office",
+		TransactionDate: now,
+		AmountUSD:       decimal.NewFromFloat(100.00),
+		Status:          models.StatusCompleted,
+	}
+	mockRepo.Create(ctx, tx)
+
+	rates := repository.NewMockRateRepository()
+	_, err := rates.Save(ctx, models.RateSourceSync, []treasury.ExchangeRate{
+		{Currency: "Bhutan-Ngultrum", Rate: decimal.NewFromFloat(83.1), EffectiveDate: now.AddDate(0, -1, 0).Truncate(24 * time.Hour)},
+		{Currency: "Guinea-Franc", Rate: decimal.NewFromFloat(8600), EffectiveDate: now.AddDate(-2, 0, 0).Truncate(24 * time.Hour)},
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, rates.RecordSync(ctx, &models.RateSync{StartedAt: now.Add(time.Hour)}, nil))
+
+	service := NewCurrencyService(client, mockRepo, WithRateRepository(lookupOnlyRates{MockRateRepository: rates, t: t}))
+
+	// A stored descriptor missing from the catalog is converted to, under its
+	// stored spelling and with two minor units.
+	conversion, err := service.ConvertTransaction(ctx, tx.ID, "bhutan-ngultrum")
+	assert.NoError(t, err)
+	assert.Equal(t, "Bhutan-Ngultrum", conversion.TargetCurrency)
+	assert.Equal(t, "8310", conversion.ConvertedAmount.String())
+
+	// A stored descriptor without a recent rate has no valid rate.
+	_, err = service.ConvertTransaction(ctx, tx.ID, "Guinea-Franc")
+	assert.ErrorIs(t, err, errors.ErrNoValidExchangeRate)
+	assert.Equal(t, 1, client.calls, "the gap is fetched from Treasury")
+
+	// Anything else is rejected without calling Treasury.
+	for _, input := range []string{"foo-bar", "Atlantis-Dollar", "XYZ"} {
+		_, err = service.ConvertTransaction(ctx, tx.ID, input)
+		assert.ErrorIs(t, err, errors.ErrInvalidCurrency, input)
+	}
+	assert.Equal(t, 1, client.calls)
+
+	// Without stored rates, only catalogued currencies are known.
+	_, err = NewCurrencyService(client, mockRepo).ConvertTransaction(ctx, tx.ID, "Bhutan-Ngultrum")
+	assert.ErrorIs(t, err, errors.ErrInvalidCurrency)
+	assert.Equal(t, 1, client.calls)
+}
@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/Athla/vr-software-challenge/internal/domain/models"
+	"github.com/Athla/vr-software-challenge/internal/infrastructure/treasury"
+	"github.com/Athla/vr-software-challenge/internal/repository"
+	"github.com/charmbracelet/log"
+)
+
+// firstRecordDate is the earliest record date of the Treasury rates of
+// exchange dataset, where the first sync starts.
+var firstRecordDate = time.Date(2001, time.March, 31, 0, 0, 0, 0, time.UTC)
+
+// RateSyncer copies new Treasury exchange rates into the rate repository.
+type RateSyncer struct {
+	treasuryClient treasury.Clienter
+	rates          repository.RateRepository
+}
+
+func NewRateSyncer(treasuryClient treasury.Clienter, rates repository.RateRepository) *RateSyncer {
+	return &RateSyncer{
+		treasuryClient: treasuryClient,
+		rates:          rates,
+	}
+}
+
+// Sync fetches the records from the latest synced record date on, so records
+// published late for that date are picked up too, and stores the new ones.
+func (s *RateSyncer) Sync(ctx context.Context) (*models.RateSync, error) {
+	run := &models.RateSync{StartedAt: time.Now().UTC()}
+
+	since, err := s.rates.LatestSyncedDate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if since.IsZero() {
+		since = firstRecordDate
+	}
+	run.Since = since
+
+	rates, err := s.treasuryClient.GetExchangeRatesSince(ctx, since)
+	if err != nil {
+		log.Errorf("Unable to fetch exchange rates since %s due: %v", since.Format(time.DateOnly), err)
+		return nil, err
+	}
+	run.Fetched = len(rates)
+
+	if err := s.rates.RecordSync(ctx, run, rates); err != nil {
+		return nil, err
+	}
+
+	return run, nil
+}
+
+// Start syncs right away and then every interval until ctx is done. A failed
+// sync is logged and tried again at the next tick.
+func (s *RateSyncer) Start(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if run, err := s.Sync(ctx); err != nil {
+			log.Errorf("Exchange rate sync failed: %v", err)
+		} else {
+			log.Infof("Synced exchange rates since %s: %d fetched, %d new", run.Since.Format(time.DateOnly), run.Fetched, run.Stored)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
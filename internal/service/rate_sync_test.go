@@ -0,0 +1,41 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Athla/vr-software-challenge/internal/infrastructure/treasury"
+	"github.com/Athla/vr-software-challenge/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateSyncer_Sync(t *testing.T) {
+	ctx := context.Background()
+	rates := repository.NewMockRateRepository()
+	syncer := NewRateSyncer(treasury.NewMockClient(), rates)
+
+	run, err := syncer.Sync(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, firstRecordDate, run.Since)
+	assert.Equal(t, 4, run.Fetched)
+	assert.Equal(t, 4, run.Stored)
+
+	// The next run starts from the latest synced record date and only
+	// stores what is new.
+	yesterday := time.Now().AddDate(0, 0, -1)
+	run, err = syncer.Sync(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, yesterday.Format(time.DateOnly), run.Since.Format(time.DateOnly))
+	assert.Equal(t, 3, run.Fetched)
+	assert.Equal(t, 0, run.Stored)
+
+	last, err := rates.LastSync(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, run.ID, last.ID)
+
+	stored, err := rates.InRange(ctx, "Euro Zone-Euro", yesterday.AddDate(0, -2, 0), yesterday)
+	require.NoError(t, err)
+	assert.Len(t, stored, 2)
+}
@@ -0,0 +1,17 @@
+-- migrations/002_transaction_outbox.sql
+-- Outbox of events written in the same database transaction as the change that
+-- produced them, relayed to Kafka asynchronously.
+CREATE TABLE transaction_outbox (
+    id BIGSERIAL PRIMARY KEY,
+    aggregate_id UUID NOT NULL,
+    event_type VARCHAR(50) NOT NULL,
+    payload JSONB NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    sent_at TIMESTAMP WITH TIME ZONE,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT
+);
+
+-- Only unsent rows are ever scanned by the relay
+CREATE INDEX idx_outbox_unsent ON transaction_outbox(id) WHERE sent_at IS NULL;
+CREATE INDEX idx_outbox_aggregate_id ON transaction_outbox(aggregate_id);
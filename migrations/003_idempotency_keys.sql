@@ -0,0 +1,14 @@
+-- migrations/003_idempotency_keys.sql
+-- Responses stored per Idempotency-Key so retried requests can be replayed
+CREATE TABLE idempotency_keys (
+    key VARCHAR(255) PRIMARY KEY,
+    fingerprint VARCHAR(64) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'IN_PROGRESS',
+    response_code INTEGER,
+    response_body BYTEA,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    CONSTRAINT idempotency_status_valid CHECK (status IN ('IN_PROGRESS', 'COMPLETED'))
+);
+
+CREATE INDEX idx_idempotency_expires_at ON idempotency_keys(expires_at);
@@ -0,0 +1,8 @@
+-- migrations/004_transaction_list_indexes.sql
+-- Composite indexes backing keyset pagination on (sort column, id)
+CREATE INDEX idx_transactions_created_at_id ON transactions(created_at, id);
+CREATE INDEX idx_transactions_date_id ON transactions(transaction_date, id);
+CREATE INDEX idx_transactions_amount_id ON transactions(amount_usd, id);
+
+-- Superseded by idx_transactions_created_at_id
+DROP INDEX IF EXISTS idx_created_at;
@@ -0,0 +1,33 @@
+-- migrations/005_void_transactions.sql
+-- Voided transactions: a terminal status recorded together with its reason
+ALTER TYPE transaction_status ADD VALUE IF NOT EXISTS 'VOIDED';
+
+ALTER TABLE transactions
+    ADD COLUMN void_reason TEXT,
+    ADD COLUMN voided_at TIMESTAMP WITH TIME ZONE;
+
+ALTER TABLE transaction_audit_logs ADD COLUMN reason TEXT;
+
+ALTER TABLE transaction_audit_logs DROP CONSTRAINT audit_action_valid;
+ALTER TABLE transaction_audit_logs
+    ADD CONSTRAINT audit_action_valid CHECK (action IN ('CREATE', 'UPDATE', 'STATUS_CHANGE', 'VOID'));
+
+-- Voids are logged as their own action carrying the reason
+CREATE OR REPLACE FUNCTION fn_log_transaction_changes()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        INSERT INTO transaction_audit_logs (transaction_id, action, new_status)
+        VALUES (NEW.id, 'CREATE', NEW.status);
+    ELSIF TG_OP = 'UPDATE' AND OLD.status != NEW.status THEN
+        IF NEW.status::text = 'VOIDED' THEN
+            INSERT INTO transaction_audit_logs (transaction_id, action, old_status, new_status, reason)
+            VALUES (NEW.id, 'VOID', OLD.status, NEW.status, NEW.void_reason);
+        ELSE
+            INSERT INTO transaction_audit_logs (transaction_id, action, old_status, new_status)
+            VALUES (NEW.id, 'STATUS_CHANGE', OLD.status, NEW.status);
+        END IF;
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
@@ -0,0 +1,62 @@
+-- migrations/006_transaction_versions.sql
+-- Row versions for optimistic locking and audited edits of pending transactions
+ALTER TABLE transactions ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+
+ALTER TABLE transaction_audit_logs ADD COLUMN changes JSONB;
+
+-- Every update, whatever issued it, moves the transaction to a new version
+CREATE OR REPLACE FUNCTION fn_bump_transaction_version()
+RETURNS TRIGGER AS $$
+BEGIN
+    NEW.version := OLD.version + 1;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trg_transaction_version
+BEFORE UPDATE ON transactions
+FOR EACH ROW
+EXECUTE FUNCTION fn_bump_transaction_version();
+
+-- Edits of description, date or amount are logged as UPDATE with before/after values
+CREATE OR REPLACE FUNCTION fn_log_transaction_changes()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        INSERT INTO transaction_audit_logs (transaction_id, action, new_status)
+        VALUES (NEW.id, 'CREATE', NEW.status);
+        RETURN NEW;
+    END IF;
+
+    IF OLD.status != NEW.status THEN
+        IF NEW.status::text = 'VOIDED' THEN
+            INSERT INTO transaction_audit_logs (transaction_id, action, old_status, new_status, reason)
+            VALUES (NEW.id, 'VOID', OLD.status, NEW.status, NEW.void_reason);
+        ELSE
+            INSERT INTO transaction_audit_logs (transaction_id, action, old_status, new_status)
+            VALUES (NEW.id, 'STATUS_CHANGE', OLD.status, NEW.status);
+        END IF;
+    END IF;
+
+    IF (OLD.description, OLD.transaction_date, OLD.amount_usd)
+        IS DISTINCT FROM (NEW.description, NEW.transaction_date, NEW.amount_usd) THEN
+        INSERT INTO transaction_audit_logs (transaction_id, action, old_status, new_status, changes)
+        VALUES (NEW.id, 'UPDATE', OLD.status, NEW.status, jsonb_build_object(
+            'before', jsonb_build_object(
+                'description', OLD.description,
+                'transaction_date', OLD.transaction_date,
+                'amount_usd', OLD.amount_usd,
+                'version', OLD.version
+            ),
+            'after', jsonb_build_object(
+                'description', NEW.description,
+                'transaction_date', NEW.transaction_date,
+                'amount_usd', NEW.amount_usd,
+                'version', NEW.version
+            )
+        ));
+    END IF;
+
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
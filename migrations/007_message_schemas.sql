@@ -0,0 +1,13 @@
+-- migrations/007_message_schemas.sql
+-- Schema registry of event data: every version of a subject gets a global ID,
+-- which messages carry in their schema_id header
+CREATE TABLE message_schemas (
+    id SERIAL PRIMARY KEY,
+    subject VARCHAR(100) NOT NULL,
+    version INTEGER NOT NULL,
+    fingerprint VARCHAR(64) NOT NULL,
+    definition JSONB NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT message_schemas_subject_version UNIQUE (subject, version),
+    CONSTRAINT message_schemas_subject_fingerprint UNIQUE (subject, fingerprint)
+);
@@ -0,0 +1,51 @@
+-- migrations/008_transaction_failures.sql
+-- Why processing failed: the pipeline step that rejected the transaction and its reason
+ALTER TABLE transactions
+    ADD COLUMN failed_step VARCHAR(50),
+    ADD COLUMN failure_reason TEXT;
+
+-- Moves to FAILED are logged with the step and reason
+CREATE OR REPLACE FUNCTION fn_log_transaction_changes()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        INSERT INTO transaction_audit_logs (transaction_id, action, new_status)
+        VALUES (NEW.id, 'CREATE', NEW.status);
+        RETURN NEW;
+    END IF;
+
+    IF OLD.status != NEW.status THEN
+        IF NEW.status::text = 'VOIDED' THEN
+            INSERT INTO transaction_audit_logs (transaction_id, action, old_status, new_status, reason)
+            VALUES (NEW.id, 'VOID', OLD.status, NEW.status, NEW.void_reason);
+        ELSIF NEW.status::text = 'FAILED' THEN
+            INSERT INTO transaction_audit_logs (transaction_id, action, old_status, new_status, reason)
+            VALUES (NEW.id, 'STATUS_CHANGE', OLD.status, NEW.status, NEW.failed_step || ': ' || NEW.failure_reason);
+        ELSE
+            INSERT INTO transaction_audit_logs (transaction_id, action, old_status, new_status)
+            VALUES (NEW.id, 'STATUS_CHANGE', OLD.status, NEW.status);
+        END IF;
+    END IF;
+
+    IF (OLD.description, OLD.transaction_date, OLD.amount_usd)
+        IS DISTINCT FROM (NEW.description, NEW.transaction_date, NEW.amount_usd) THEN
+        INSERT INTO transaction_audit_logs (transaction_id, action, old_status, new_status, changes)
+        VALUES (NEW.id, 'UPDATE', OLD.status, NEW.status, jsonb_build_object(
+            'before', jsonb_build_object(
+                'description', OLD.description,
+                'transaction_date', OLD.transaction_date,
+                'amount_usd', OLD.amount_usd,
+                'version', OLD.version
+            ),
+            'after', jsonb_build_object(
+                'description', NEW.description,
+                'transaction_date', NEW.transaction_date,
+                'amount_usd', NEW.amount_usd,
+                'version', NEW.version
+            )
+        ));
+    END IF;
+
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
@@ -0,0 +1,30 @@
+-- migrations/009_exchange_rates.sql
+-- Treasury exchange rates, stored once per currency and record date so a
+-- conversion can be reproduced even if Treasury later revises or removes the
+-- record. source tells whether the rate came from the sync job or was fetched
+-- live to fill a gap.
+CREATE TABLE exchange_rates (
+    currency VARCHAR(100) NOT NULL,
+    record_date DATE NOT NULL,
+    exchange_rate DECIMAL(20,6) NOT NULL,
+    source VARCHAR(10) NOT NULL,
+    fetched_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (currency, record_date),
+    CONSTRAINT exchange_rate_positive CHECK (exchange_rate > 0),
+    CONSTRAINT exchange_rate_source_valid CHECK (source IN ('sync', 'live'))
+);
+
+CREATE INDEX idx_exchange_rates_synced_record_date ON exchange_rates(record_date) WHERE source = 'sync';
+
+-- One row per successful sync run, for auditing and to know how recent the
+-- stored rates are.
+CREATE TABLE exchange_rate_syncs (
+    id BIGSERIAL PRIMARY KEY,
+    started_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    finished_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    since DATE NOT NULL,
+    fetched INTEGER NOT NULL,
+    stored INTEGER NOT NULL
+);
+
+CREATE INDEX idx_exchange_rate_syncs_started_at ON exchange_rate_syncs(started_at);
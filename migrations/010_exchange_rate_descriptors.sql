@@ -0,0 +1,4 @@
+-- migrations/010_exchange_rate_descriptors.sql
+-- Conversions to a currency missing from the catalog look its descriptor up
+-- in any case, so the lookup must not scan the whole table.
+CREATE INDEX idx_exchange_rates_currency_lower ON exchange_rates(lower(currency));
@@ -16,7 +16,6 @@ import (
 	"github.com/Athla/vr-software-challenge/internal/api/handlers"
 	"github.com/Athla/vr-software-challenge/internal/domain/models"
 	"github.com/Athla/vr-software-challenge/internal/infrastructure/database"
-	"github.com/Athla/vr-software-challenge/internal/infrastructure/messagery"
 	"github.com/Athla/vr-software-challenge/internal/infrastructure/treasury"
 	"github.com/Athla/vr-software-challenge/internal/repository"
 	"github.com/Athla/vr-software-challenge/internal/service"
@@ -28,12 +27,11 @@ import (
 
 var (
 	dbHandler *sql.DB
-	producer  messagery.Producerer
 	cfg       *config.Config
 )
 
 func cleanup(t *testing.T) {
-	_, err := dbHandler.Exec("TRUNCATE TABLE transactions, transaction_audit_logs CASCADE")
+	_, err := dbHandler.Exec("TRUNCATE TABLE transactions, transaction_audit_logs, transaction_outbox CASCADE")
 	assert.NoError(t, err, "Failed to cleanup test data")
 }
 
@@ -45,8 +43,7 @@ func setupRouter() *gin.Engine {
 	currencyService := service.NewCurrencyService(treasuryClient, repository.NewTransactionRepository(dbHandler))
 
 	transactionHandler := handlers.TransactionHandler{
-		Repo:     repository.NewTransactionRepository(dbHandler),
-		Producer: producer,
+		Repo: repository.NewTransactionRepository(dbHandler),
 	}
 
 	currencyHandler := handlers.CurrencyHandler{
@@ -79,16 +76,9 @@ func TestMain(m *testing.M) {
 		os.Exit(1)
 	}
 
-	producer, err = messagery.NewProducer(cfg.Kafka.Brokers, cfg.Kafka.Topic)
-	if err != nil {
-		fmt.Printf("Unable to create Kafka producer: %v\n", err)
-		os.Exit(1)
-	}
-
 	code := m.Run()
 
 	dbHandler.Close()
-	producer.Close()
 
 	os.Exit(code)
 }
@@ -130,6 +120,12 @@ func TestCreateTransactionIntegration(t *testing.T) {
 	assert.Equal(t, models.StatusPending, tx.Status)
 	assert.NotNil(t, tx.CreatedAt)
 	assert.Nil(t, tx.ProcessedAt)
+
+	// Verify the creation event was queued in the outbox
+	var eventType string
+	err = dbHandler.QueryRow("SELECT event_type FROM transaction_outbox WHERE aggregate_id = $1", resp.ID).Scan(&eventType)
+	assert.NoError(t, err)
+	assert.Equal(t, "transaction.created", eventType)
 }
 
 func TestGetTransactionByIDIntegration(t *testing.T) {
@@ -195,16 +191,31 @@ func TestListTransactionsIntegration(t *testing.T) {
 		time.Sleep(100 * time.Millisecond) // Ensure different created_at times
 	}
 
-	req, _ := http.NewRequest("GET", "/api/v1/transactions?limit=3&offset=0", nil)
+	req, _ := http.NewRequest("GET", "/api/v1/transactions?limit=3", nil)
 	rr := httptest.NewRecorder()
 	router.ServeHTTP(rr, req)
 
 	assert.Equal(t, http.StatusOK, rr.Code)
 
-	var transactions []models.Transaction
-	err := json.Unmarshal(rr.Body.Bytes(), &transactions)
+	var page handlers.TransactionListResponse
+	err := json.Unmarshal(rr.Body.Bytes(), &page)
 	assert.NoError(t, err)
+	transactions := page.Data
 	assert.Len(t, transactions, 3, "Should return exactly 3 transactions")
+	assert.NotNil(t, page.NextCursor)
+
+	// The next page continues where the first one ended
+	req, _ = http.NewRequest("GET", "/api/v1/transactions?limit=3&cursor="+*page.NextCursor, nil)
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var next handlers.TransactionListResponse
+	err = json.Unmarshal(rr.Body.Bytes(), &next)
+	assert.NoError(t, err)
+	assert.Len(t, next.Data, 2)
+	assert.Nil(t, next.NextCursor)
 
 	for i := 0; i < len(transactions)-1; i++ {
 		assert.True(t, transactions[i].CreatedAt.After(transactions[i+1].CreatedAt),
@@ -233,16 +244,18 @@ func TestUpdateTransactionStatusIntegration(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, models.StatusPending, initialTx.Status)
 
-	// Update status
-	reqBody := map[string]string{"status": "COMPLETED"}
-	body, _ := json.Marshal(reqBody)
-	req, _ := http.NewRequest("PATCH", "/api/v1/transactions/"+tx.ID.String()+"/status", bytes.NewBuffer(body))
-	req.Header.Set("Content-Type", "application/json")
+	// Update status through the lifecycle
+	for _, status := range []string{"PROCESSING", "COMPLETED"} {
+		reqBody := map[string]string{"status": status}
+		body, _ := json.Marshal(reqBody)
+		req, _ := http.NewRequest("PATCH", "/api/v1/transactions/"+tx.ID.String()+"/status", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
 
-	rr := httptest.NewRecorder()
-	router.ServeHTTP(rr, req)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
 
-	assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, http.StatusOK, rr.Code)
+	}
 
 	// Add a small delay to allow for processing
 	time.Sleep(1 * time.Second)